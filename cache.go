@@ -0,0 +1,22 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// snapshotFile returns the file in the user cache directory where the snapshot of the given directory is stored
+func snapshotFile(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(absDir))
+	return filepath.Join(cacheDir, "codesum", hex.EncodeToString(sum[:8])+".json"), nil
+}
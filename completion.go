@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// completionShells are the shells that "codesum completion" can generate a script for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// runCompletion prints a shell completion script for the given shell
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: codesum completion %s", strings.Join(completionShells, "|"))
+	}
+	flags := completionFlags()
+	w := bufio.NewWriter(os.Stdout)
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(w, flags)
+	case "zsh":
+		writeZshCompletion(w, flags)
+	case "fish":
+		writeFishCompletion(w, flags)
+	default:
+		return fmt.Errorf("unknown shell %q, must be one of: %s", args[0], strings.Join(completionShells, ", "))
+	}
+	return w.Flush()
+}
+
+// subcommandCompletions are the words to complete for the arguments of each subcommand, and if filenames should be completed too
+var subcommandCompletions = map[string]struct {
+	words []string
+	files bool
+}{
+	"completion": {words: completionShells},
+	"diff":       {words: []string{"--json"}, files: true},
+	"doctor":     {words: []string{"-json"}, files: true},
+	"init":       {words: []string{"--force"}},
+	"mcp":        {words: []string{"--root", "--max-size"}, files: true},
+	"serve":      {words: []string{"--addr", "--root", "--ttl"}, files: true},
+}
+
+// completionFlag is a flag of the default command, as seen by the completion scripts
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	hint   valueHint
+}
+
+// completionFlags returns the flags of the default command, sorted by name
+func completionFlags() []completionFlag {
+	fs := flag.NewFlagSet("codesum", flag.ContinueOnError)
+	var c cliFlags
+	c.define(fs)
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:   f.Name,
+			usage:  f.Usage,
+			isBool: ok && boolFlag.IsBoolFlag(),
+			hint:   flagValueHints[f.Name],
+		})
+	})
+	return flags
+}
+
+// subcommandNames returns the names of the subcommands, sorted
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dashed returns the flag as it is typed on the command line. Single letter flags get one dash.
+func (f completionFlag) dashed() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+func writeBashCompletion(w io.Writer, flags []completionFlag) {
+	var all, files, valued []string
+	choices := make(map[string][]string)
+	for _, f := range flags {
+		all = append(all, f.dashed())
+		switch {
+		case f.isBool:
+		case f.hint.file:
+			files = append(files, "-"+f.name, "--"+f.name)
+		case len(f.hint.choices) > 0:
+			choices["-"+f.name+"|--"+f.name] = f.hint.choices
+		default:
+			valued = append(valued, "-"+f.name, "--"+f.name)
+		}
+	}
+	fmt.Fprintln(w, "# bash completion for codesum")
+	fmt.Fprintln(w, "_codesum() {")
+	fmt.Fprintln(w, "    local cur prev")
+	fmt.Fprintln(w, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Fprintln(w, "    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+	fmt.Fprintln(w, "    case ${COMP_WORDS[1]} in")
+	for _, name := range subcommandNames() {
+		sub := subcommandCompletions[name]
+		fmt.Fprintf(w, "        %s)\n", name)
+		if sub.files {
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(sub.words, " "))
+		} else {
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(sub.words, " "))
+		}
+		fmt.Fprintln(w, "            return")
+		fmt.Fprintln(w, "            ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    case \"$prev\" in")
+	fmt.Fprintf(w, "        %s)\n", strings.Join(files, "|"))
+	fmt.Fprintln(w, "            COMPREPLY=($(compgen -f -- \"$cur\"))")
+	fmt.Fprintln(w, "            return")
+	fmt.Fprintln(w, "            ;;")
+	patterns := make([]string, 0, len(choices))
+	for pattern := range choices {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		fmt.Fprintf(w, "        %s)\n", pattern)
+		fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(choices[pattern], " "))
+		fmt.Fprintln(w, "            return")
+		fmt.Fprintln(w, "            ;;")
+	}
+	fmt.Fprintf(w, "        %s)\n", strings.Join(valued, "|"))
+	fmt.Fprintln(w, "            return")
+	fmt.Fprintln(w, "            ;;")
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(subcommandNames(), " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(all, " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _codesum codesum")
+}
+
+func writeZshCompletion(w io.Writer, flags []completionFlag) {
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+	fmt.Fprintln(w, "#compdef codesum")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_codesum() {")
+	fmt.Fprintln(w, "    case ${words[2]} in")
+	for _, name := range subcommandNames() {
+		sub := subcommandCompletions[name]
+		fmt.Fprintf(w, "        %s)\n", name)
+		fmt.Fprintf(w, "            compadd -- %s\n", strings.Join(sub.words, " "))
+		if sub.files {
+			fmt.Fprintln(w, "            _files")
+		}
+		fmt.Fprintln(w, "            return")
+		fmt.Fprintln(w, "            ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    _arguments \\")
+	for _, f := range flags {
+		spec := f.dashed() + "[" + escape.Replace(f.usage) + "]"
+		switch {
+		case f.isBool:
+		case f.hint.file:
+			spec += ":file:_files"
+		case len(f.hint.choices) > 0:
+			spec += ":" + f.name + ":(" + strings.Join(f.hint.choices, " ") + ")"
+		default:
+			spec += ":" + f.name + ":"
+		}
+		fmt.Fprintf(w, "        '%s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "        '1:command:(%s)'\n", strings.Join(subcommandNames(), " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_codesum \"$@\"")
+}
+
+func writeFishCompletion(w io.Writer, flags []completionFlag) {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	}
+	fmt.Fprintln(w, "# fish completion for codesum")
+	fmt.Fprintln(w, "complete -c codesum -f")
+	fmt.Fprintf(w, "complete -c codesum -n '__fish_use_subcommand' -a %s\n", quote(strings.Join(subcommandNames(), " ")))
+	for _, name := range subcommandNames() {
+		sub := subcommandCompletions[name]
+		files := ""
+		if sub.files {
+			files = " -F"
+		}
+		fmt.Fprintf(w, "complete -c codesum -n '__fish_seen_subcommand_from %s'%s -a %s\n", name, files, quote(strings.Join(sub.words, " ")))
+	}
+	for _, f := range flags {
+		option := "-l " + f.name
+		if len(f.name) == 1 {
+			option = "-s " + f.name
+		}
+		switch {
+		case f.isBool:
+		case f.hint.file:
+			option += " -r -F"
+		case len(f.hint.choices) > 0:
+			option += " -x -a " + quote(strings.Join(f.hint.choices, " "))
+		default:
+			option += " -x"
+		}
+		fmt.Fprintf(w, "complete -c codesum -n '__fish_use_subcommand' %s -d %s\n", option, quote(f.usage))
+	}
+}
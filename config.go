@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const projectConfigFile = ".codesum.toml"
+
+// shortAliases are the short flags that share a variable with a longer flag
+var shortAliases = map[string]string{
+	"j":  "json",
+	"l":  "list",
+	"v":  "version",
+	"V":  "verbose",
+	"VV": "debug",
+}
+
+// metaFlags are flags that only make sense on the command line
+var metaFlags = map[string]bool{
+	"config":       true,
+	"explain":      true,
+	"list":         true,
+	"list-long":    true,
+	"print-config": true,
+	"print-schema": true,
+	"version":      true,
+}
+
+// flagSources records where the value of each flag that is not at its default value came from
+var flagSources = make(map[string]string)
+
+const sourceCommandLine = "command line"
+
+// recordCommandLineFlags marks all flags that were given on the command line
+func recordCommandLineFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		flagSources[canonicalFlagName(f.Name)] = sourceCommandLine
+	})
+}
+
+// canonicalFlagName returns the long name of a flag
+func canonicalFlagName(name string) string {
+	if long, ok := shortAliases[name]; ok {
+		return long
+	}
+	return name
+}
+
+// setFlagFrom sets a flag from a lower priority source than the command line. A flag that can be repeated, like
+// author, is set to the given values, replacing those of the sources with a lower priority.
+func setFlagFrom(name string, values []string, source string) error {
+	name = canonicalFlagName(name)
+	if flagSources[name] == sourceCommandLine {
+		return nil
+	}
+	if list, ok := flag.Lookup(name).Value.(*listFlag); ok {
+		*list = nil
+	}
+	for _, value := range values {
+		if err := flag.Set(name, value); err != nil {
+			return err
+		}
+	}
+	flagSources[name] = source
+	return nil
+}
+
+// userConfigFile returns the path to ~/.config/codesum/config.toml, respecting $XDG_CONFIG_HOME
+func userConfigFile() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "codesum", "config.toml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "codesum", "config.toml")
+}
+
+// loadConfigFiles applies the user configuration file and then the project configuration file,
+// or only the given file if configFile is not empty.
+// Unknown keys are returned as warnings, so that configuration files survive version skew.
+func loadConfigFiles(configFile string) ([]string, error) {
+	if configFile != "" {
+		return applyConfigFile(configFile)
+	}
+	var warnings []string
+	for _, filename := range []string{userConfigFile(), projectConfigFile} {
+		if filename == "" {
+			continue
+		}
+		fileWarnings, err := applyConfigFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return warnings, err
+		}
+		warnings = append(warnings, fileWarnings...)
+	}
+	return warnings, nil
+}
+
+// applyConfigFile sets the flags given in a TOML file, unless they were given on the command line
+func applyConfigFile(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+	var warnings []string
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := strings.ReplaceAll(key, "_", "-")
+		if flag.Lookup(name) == nil || metaFlags[canonicalFlagName(name)] {
+			warnings = append(warnings, fmt.Sprintf("unknown key %q in %s", key, filename))
+			continue
+		}
+		_, repeatable := flag.Lookup(name).Value.(*listFlag)
+		flagValues, err := configValues(values[key], repeatable)
+		if err != nil {
+			return warnings, fmt.Errorf("%s: %s: %w", filename, key, err)
+		}
+		if err := setFlagFrom(name, flagValues, filename); err != nil {
+			return warnings, fmt.Errorf("%s: %s: %w", filename, key, err)
+		}
+	}
+	return warnings, nil
+}
+
+// configValues converts a TOML value to flag values. Arrays become one value per element for the flags that can be
+// repeated, and a comma-separated list for the other flags.
+func configValues(value any, repeatable bool) ([]string, error) {
+	elements, ok := value.([]any)
+	if !ok || !repeatable {
+		s, err := configValueString(value)
+		return []string{s}, err
+	}
+	values := make([]string, 0, len(elements))
+	for _, element := range elements {
+		s, err := configValueString(element)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// configValueString converts a TOML value to a flag value. Arrays become comma-separated lists.
+func configValueString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		elements := make([]string, 0, len(v))
+		for _, element := range v {
+			s, err := configValueString(element)
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, s)
+		}
+		return strings.Join(elements, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
+
+// envName returns the environment variable that corresponds to a flag, like CODESUM_MAX_DEPTH for max-depth
+func envName(flagName string) string {
+	return "CODESUM_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvironment sets flags from CODESUM_* environment variables, unless they were given on the command line.
+// Boolean flags accept 1/0, true/false, yes/no and on/off.
+func applyEnvironment() error {
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, isAlias := shortAliases[f.Name]; isAlias || metaFlags[f.Name] || err != nil {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+			switch strings.ToLower(strings.TrimSpace(value)) {
+			case "1", "true", "yes", "on":
+				value = "true"
+			case "0", "false", "no", "off", "":
+				value = "false"
+			default:
+				err = fmt.Errorf("%s: invalid boolean value %q", name, value)
+				return
+			}
+		}
+		if setErr := setFlagFrom(f.Name, []string{value}, "environment variable "+name); setErr != nil {
+			err = fmt.Errorf("%s: %w", name, setErr)
+		}
+	})
+	return err
+}
+
+// printConfig writes the effective configuration as TOML, with the source of each value as a comment
+func printConfig(w io.Writer) error {
+	var sb strings.Builder
+	for _, setting := range effectiveConfig() {
+		fmt.Fprintf(&sb, "%s = %s # %s\n", setting.Name, setting.Value, setting.Source)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// configSetting is the effective value of a flag, and where it came from
+type configSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// effectiveConfig returns the value and source of each flag, where string values are quoted like in TOML
+func effectiveConfig() []configSetting {
+	var settings []configSetting
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, isAlias := shortAliases[f.Name]; isAlias || metaFlags[f.Name] {
+			return
+		}
+		source, ok := flagSources[f.Name]
+		if !ok {
+			source = "default"
+		}
+		value := f.Value.String()
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if _, isString := getter.Get().(string); isString {
+				value = strconv.Quote(value)
+			}
+		}
+		settings = append(settings, configSetting{Name: f.Name, Value: value, Source: source})
+	})
+	return settings
+}
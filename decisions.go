@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// decision is a file or directory that Collect skipped or found, as logged with its path
+type decision struct {
+	path    string
+	message string
+	attrs   []slog.Attr // the attributes other than the path
+}
+
+// skipped checks if the decision left the file or directory out of the output
+func (d decision) skipped() bool {
+	for _, prefix := range []string{"skipping ", "omitting ", "dropping "} {
+		if strings.HasPrefix(d.message, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// reason describes the decision without the path, like "file, since it is too large",
+// with the ignore or exclude pattern that matched, if any
+func (d decision) reason() string {
+	reason := d.message
+	for _, prefix := range []string{"skipping ", "omitting ", "dropping "} {
+		reason = strings.TrimPrefix(reason, prefix)
+	}
+	for _, a := range d.attrs {
+		if a.Key == "ignore" || a.Key == "exclude" {
+			reason += fmt.Sprintf(" (%s %s)", a.Key, a.Value)
+		}
+	}
+	return reason
+}
+
+// attr returns the value of the attribute with the given key, or an empty string
+func (d decision) attr(key string) string {
+	for _, a := range d.attrs {
+		if a.Key == key {
+			return a.Value.String()
+		}
+	}
+	return ""
+}
+
+// decisionRecorder is a log handler that records the messages that Collect logs about each path, at any level,
+// and passes the records on to the next handler
+type decisionRecorder struct {
+	next      slog.Handler
+	mut       *sync.Mutex
+	decisions *[]decision
+}
+
+// newDecisionRecorder returns a recorder that passes the records on to the handler of logger
+func newDecisionRecorder(logger *slog.Logger) *decisionRecorder {
+	return &decisionRecorder{next: logger.Handler(), mut: &sync.Mutex{}, decisions: &[]decision{}}
+}
+
+// recorded returns the decisions in the order they were logged
+func (h *decisionRecorder) recorded() []decision {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return append([]decision{}, *h.decisions...)
+}
+
+func (h *decisionRecorder) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *decisionRecorder) Handle(ctx context.Context, r slog.Record) error {
+	d := decision{message: r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "path" {
+			d.path = a.Value.String()
+		} else {
+			d.attrs = append(d.attrs, a)
+		}
+		return true
+	})
+	if d.path != "" {
+		h.mut.Lock()
+		*h.decisions = append(*h.decisions, d)
+		h.mut.Unlock()
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *decisionRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *decisionRecorder) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// runDiff compares two JSON summaries. It exits with 0 if they are the same, 1 if they differ and 2 on errors.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("codesum diff", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Output the differences in JSON format")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: codesum diff [--json] OLD.json NEW.json")
+		fs.PrintDefaults()
+	}
+
+	// Allow flags both before and after the filenames
+	var filenames []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return err
+			}
+			return &exitError{code: 2}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		filenames = append(filenames, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(filenames) != 2 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	oldProject, err := readSummary(filenames[0])
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	newProject, err := readSummary(filenames[1])
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+
+	d := codesum.DiffProjects(oldProject, newProject)
+	write := codesum.WriteDiff
+	if *jsonOutput {
+		write = codesum.WriteDiffJSON
+	}
+	if err := write(os.Stdout, d); err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	if !d.Empty() {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+// readSummary reads a JSON summary, warning if it has a newer schema version than this version of codesum knows
+func readSummary(filename string) (codesum.ProjectInfo, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return codesum.ProjectInfo{}, err
+	}
+	defer f.Close()
+	project, err := codesum.ReadProjectInfo(f)
+	if err != nil {
+		return codesum.ProjectInfo{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	if project.SchemaVersion > codesum.SchemaVersion {
+		fmt.Fprintf(os.Stderr, "Warning: %s has schema version %d, which is newer than %d, so some fields may not be compared\n", filename, project.SchemaVersion, codesum.SchemaVersion)
+	}
+	return project, nil
+}
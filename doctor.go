@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// Reasons for skipping files that are worth pointing out when there are many of them
+const (
+	reasonUnrecognized = "file, since the extension is not recognized"
+	manyUnrecognized   = 1000
+)
+
+// doctorReport is what codesum doctor found out about how the project is collected
+type doctorReport struct {
+	Directory     string                            `json:"directory"`
+	IgnoreSources []codesum.IgnoreSource            `json:"ignore_sources"`
+	Git           doctorGit                         `json:"git"`
+	Name          string                            `json:"name"`
+	NameSource    string                            `json:"name_source"`
+	Type          string                            `json:"type"`
+	BuildSystem   string                            `json:"build_system,omitempty"`
+	Files         int                               `json:"files"`
+	Languages     map[string]codesum.LanguageTotals `json:"languages"`
+	Skipped       map[string]int                    `json:"skipped"`
+	Unreadable    []codesum.FileError               `json:"unreadable"`
+	Configuration []configSetting                   `json:"configuration"`
+	Findings      []string                          `json:"findings"`
+}
+
+// doctorGit describes the git repository that the directory is in, if any
+type doctorGit struct {
+	Repository bool   `json:"repository"`
+	Root       string `json:"root,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Remote     string `json:"remote,omitempty"` // the URL of the origin remote, as read from .git/config
+}
+
+// runDoctor reports which ignore files, git details, name detection and configuration codesum uses for a
+// directory, and why files are skipped, to find out why the output looks wrong. It takes the same flags as
+// the default command, so that the configuration is the same, and -json outputs the report as JSON.
+func runDoctor(args []string) error {
+	var c cliFlags
+	flag.CommandLine.Init("codesum doctor", flag.ContinueOnError)
+	c.define(flag.CommandLine)
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return err
+	}
+	c.root = "."
+	switch {
+	case flag.NArg() > 1:
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flag.Args()[1:], " "))
+	case flag.NArg() == 1:
+		if c.root = flag.Arg(0); !isDirectory(c.root) {
+			return fmt.Errorf("%q is not a directory", c.root)
+		}
+	}
+
+	recordCommandLineFlags()
+	findings := []string{}
+	configWarnings, err := loadConfigFiles(c.configFile)
+	if err == nil {
+		err = applyEnvironment()
+	}
+	if err != nil {
+		return err
+	}
+	findings = append(findings, configWarnings...)
+	report := doctorReport{Directory: c.root, Configuration: effectiveConfig()}
+
+	// Only the metadata is needed, so the flags that need the contents, or git, are turned off
+	c.noContents, c.outline, c.summaries, c.gitMetadata, c.changelog = true, false, false, false, 0
+	recorder := newDecisionRecorder(newLogger(os.Stderr, c.logLevel()))
+	opts := c.options(slog.New(recorder))
+
+	ctx := context.Background()
+	ignorer, err := codesum.NewIgnorer(os.DirFS(c.root), opts...)
+	if err != nil {
+		return err
+	}
+	report.IgnoreSources = ignorer.Sources()
+	project, err := codesum.Collect(ctx, c.root, opts...)
+	if err != nil {
+		return fmt.Errorf("could not walk directory and collect files: %w", err)
+	}
+	report.Name, report.NameSource = project.Name, project.NameSource
+	report.Type, report.BuildSystem = project.Type, project.BuildSystem
+	report.Files, report.Languages = project.Totals.Files, project.Totals.Languages
+	report.Skipped = make(map[string]int)
+	for _, d := range recorder.recorded() {
+		if d.skipped() {
+			report.Skipped[d.reason()]++
+		}
+	}
+	report.Unreadable = append([]codesum.FileError{}, project.Errors...)
+	report.Git = inspectGit(ctx, c.root, project.Repository)
+	report.Findings = append(findings, report.suspiciousFindings()...)
+
+	if c.jsonOutput {
+		return writeOutput(c.outputFile, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		})
+	}
+	return writeOutput(c.outputFile, func(w io.Writer) error {
+		report.write(w)
+		return nil
+	})
+}
+
+// inspectGit finds the repository root and the current branch, with the repository URL that Collect found
+func inspectGit(ctx context.Context, dir, repository string) doctorGit {
+	root, err := codesum.RepositoryRoot(ctx, dir)
+	if err != nil {
+		return doctorGit{}
+	}
+	info := doctorGit{Repository: true, Root: root}
+	if out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+		info.Branch = strings.TrimSpace(string(out))
+	}
+	if repository != "Unknown" {
+		info.Remote = repository
+	}
+	return info
+}
+
+// suspiciousFindings returns the problems that are likely to make the output look wrong
+func (r *doctorReport) suspiciousFindings() []string {
+	var findings []string
+	if unrecognized := r.Skipped[reasonUnrecognized]; r.Files == 0 && unrecognized > 0 {
+		findings = append(findings, fmt.Sprintf("no files were collected, but %d files have extensions that are not recognized", unrecognized))
+	} else if unrecognized >= manyUnrecognized && unrecognized > 10*r.Files {
+		findings = append(findings, fmt.Sprintf("%d files have extensions that are not recognized, against %d collected files", unrecognized, r.Files))
+	}
+	if len(r.Unreadable) > 0 {
+		first := r.Unreadable[0]
+		findings = append(findings, fmt.Sprintf("%d files or directories could not be read, like %s: %s", len(r.Unreadable), first.Path, first.Error))
+	}
+	found, common := false, false
+	for _, source := range r.IgnoreSources {
+		if source.File == codesum.CommonIgnoresSource {
+			common = true
+		} else if source.Found {
+			found = true
+		}
+	}
+	if !found && common {
+		findings = append(findings, "none of the ignore files were found, so only the common ignores apply")
+	} else if !found {
+		findings = append(findings, "none of the ignore files were found, and the common ignores are turned off, so no directories are skipped")
+	}
+	if r.Git.Repository && r.Git.Remote == "" {
+		if abs, err := filepath.Abs(r.Directory); err == nil && abs != r.Git.Root {
+			findings = append(findings, fmt.Sprintf("the repository is reported as Unknown, since it is only read from .git/config in the directory itself, and the repository root is %s", r.Git.Root))
+		} else {
+			findings = append(findings, "the repository is reported as Unknown, since .git/config has no origin remote")
+		}
+	}
+	return findings
+}
+
+// write writes the report as readable text
+func (r *doctorReport) write(w io.Writer) {
+	fmt.Fprintf(w, "Directory: %s\n", r.Directory)
+
+	fmt.Fprintln(w, "\nIgnore files:")
+	for _, source := range r.IgnoreSources {
+		if !source.Found {
+			fmt.Fprintf(w, "  %s: not found\n", source.File)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %d patterns\n", source.File, source.Patterns)
+	}
+
+	fmt.Fprintln(w, "\nGit:")
+	if !r.Git.Repository {
+		fmt.Fprintln(w, "  not in a git repository")
+	} else {
+		fmt.Fprintf(w, "  repository root: %s\n", r.Git.Root)
+		if r.Git.Branch != "" {
+			fmt.Fprintf(w, "  branch: %s\n", r.Git.Branch)
+		}
+		if r.Git.Remote != "" {
+			fmt.Fprintf(w, "  remote: origin %s\n", r.Git.Remote)
+		} else {
+			fmt.Fprintln(w, "  remote: none")
+		}
+	}
+
+	fmt.Fprintln(w, "\nProject:")
+	fmt.Fprintf(w, "  name: %s (from %s)\n", r.Name, r.NameSource)
+	fmt.Fprintf(w, "  type: %s\n", r.Type)
+	if r.BuildSystem != "" {
+		fmt.Fprintf(w, "  build system: %s\n", r.BuildSystem)
+	}
+
+	fmt.Fprintf(w, "\nFiles: %d collected\n", r.Files)
+	for _, lang := range sortedKeys(r.Languages) {
+		fmt.Fprintf(w, "  %s: %d\n", lang, r.Languages[lang].Files)
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSkipped:")
+		for _, reason := range sortedKeys(r.Skipped) {
+			fmt.Fprintf(w, "  %s: %d\n", reason, r.Skipped[reason])
+		}
+	}
+
+	fmt.Fprintln(w, "\nConfiguration:")
+	for _, setting := range r.Configuration {
+		if setting.Source != "default" {
+			fmt.Fprintf(w, "  %s = %s # %s\n", setting.Name, setting.Value, setting.Source)
+		}
+	}
+	fmt.Fprintln(w, "  (all other settings are at their defaults, see -print-config)")
+
+	fmt.Fprintln(w, "\nFindings:")
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, finding := range r.Findings {
+		fmt.Fprintf(w, "  %s\n", finding)
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
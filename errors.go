@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The exit codes of the default command. Every error that run returns maps onto one of these,
+// where errors that are not an *exitError are fatal.
+const (
+	exitSuccess     = 0
+	exitFatal       = 1 // the walk failed, the output could not be written or the flags are invalid
+	exitNoFiles     = 2 // no files matched
+	exitOverLimit   = 3 // the output was larger than -fail-over, -fail-over-tokens or -max-output-bytes
+	exitInterrupted = 4 // interrupted by Ctrl-C or SIGTERM
+)
+
+var (
+	errNoFiles     = &exitError{code: exitNoFiles, err: errors.New("no files matched")}
+	errInterrupted = &exitError{code: exitInterrupted, err: errors.New("interrupted")}
+)
+
+// exitError makes main exit with the given code. The error is printed, unless it is nil.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit code %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
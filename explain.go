@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// explainFlag is the value of -explain, which is either a list of paths, from -explain=PATH, or all skipped files, from -explain
+type explainFlag struct {
+	all   bool
+	paths []string
+}
+
+func (e *explainFlag) String() string {
+	if e.all {
+		return "true"
+	}
+	return strings.Join(e.paths, ",")
+}
+
+func (e *explainFlag) Set(value string) error {
+	switch value {
+	case "true":
+		e.all = true
+	case "false":
+		e.all, e.paths = false, nil
+	default:
+		e.paths = append(e.paths, filepath.ToSlash(filepath.Clean(value)))
+	}
+	return nil
+}
+
+// IsBoolFlag makes -explain work without a value, while -explain=PATH gives a path
+func (e *explainFlag) IsBoolFlag() bool {
+	return true
+}
+
+// enabled checks if -explain was given
+func (e *explainFlag) enabled() bool {
+	return e.all || len(e.paths) > 0
+}
+
+// explainer traces why files were included in the output, or left out, from the decisions that Collect logged
+type explainer struct {
+	decisions []decision
+	files     []codesum.FileInfo
+}
+
+// explain writes one line per path, or per skipped file and directory when all is true
+func (x *explainer) explain(w io.Writer, e *explainFlag) {
+	if e.all {
+		for _, d := range x.decisions {
+			if d.skipped() {
+				fmt.Fprintf(w, "explain: %s: %s\n", d.path, x.describe(d))
+			}
+		}
+	}
+	for _, p := range e.paths {
+		fmt.Fprintf(w, "explain: %s: %s\n", p, x.trace(p))
+	}
+}
+
+// trace describes the decision for the given slash separated path, relative to the root
+func (x *explainer) trace(p string) string {
+	found := false
+	for _, d := range x.decisions {
+		switch {
+		case d.path == p && d.skipped():
+			return x.describe(d)
+		case d.path == p && d.message == "found file":
+			found = true
+		case strings.HasPrefix(p, d.path+"/") && d.skipped():
+			return fmt.Sprintf("in the directory %s, which was %s", d.path, x.describe(d))
+		}
+	}
+	if !found {
+		return "not found in the directory"
+	}
+	for i, file := range x.files {
+		// The paths in the output may be relative to another directory, or absolute
+		if file.Path == p || strings.HasSuffix(file.Path, "/"+p) {
+			return fmt.Sprintf("included, as file %d of %d", i+1, len(x.files))
+		}
+	}
+	return "found, but left out of the output, since it could not be read"
+}
+
+// describe explains a decision to skip a file or directory, including the ignore file that a pattern came from
+func (x *explainer) describe(d decision) string {
+	if pattern := d.attr("ignore"); pattern != "" {
+		origin := d.attr("ignore-file")
+		if origin == "" {
+			return fmt.Sprintf("skipped, since it matches the ignore pattern %q", pattern)
+		}
+		return fmt.Sprintf("skipped, since it matches the ignore pattern %q from %s", pattern, origin)
+	}
+	if pattern := d.attr("exclude"); pattern != "" {
+		return fmt.Sprintf("skipped, since it matches the exclude pattern %q", pattern)
+	}
+	description := "skipped"
+	if _, reason, ok := strings.Cut(d.message, ", "); ok {
+		description += ", " + reason
+	} else if strings.HasPrefix(d.message, "omitting file ") {
+		description = "omitted, since it is " + strings.TrimPrefix(d.message, "omitting file ")
+	} else if d.message == "dropping one of the largest files" {
+		description = "dropped, since it is one of the largest files"
+	} else {
+		description += ", as a " + strings.TrimPrefix(d.message, "skipping ")
+	}
+	var details []string
+	for _, a := range d.attrs {
+		details = append(details, fmt.Sprintf("%s=%s", a.Key, a.Value))
+	}
+	if len(details) > 0 {
+		description += " (" + strings.Join(details, ", ") + ")"
+	}
+	return description
+}
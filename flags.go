@@ -0,0 +1,472 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// cliFlags are the flags of the default command
+type cliFlags struct {
+	jsonOutput     bool
+	list           bool
+	listLong       bool
+	gistOutput     bool
+	format         string
+	versionFlag    bool
+	printSchema    bool
+	presetName     string
+	templateFile   string
+	outputFile     string
+	errorReport    string
+	configFile     string
+	alsoJSON       string
+	metricsOut     string
+	printConf      bool
+	quiet          bool
+	verbose        bool
+	debug          bool
+	failOver       int64
+	failOverTokens int64
+	maxOutputBytes int64
+	watchMode      bool
+	watchInterval  time.Duration
+
+	localTime        bool
+	legacyTimestamps bool
+	maxPerLanguage   int
+	maxDepth         int
+	languages        string
+	maxFileSize      int64
+	concurrency      int
+	gitMetadata      bool
+	dirBudget        int64
+	noContents       bool
+	outline          bool
+	summaries        bool
+	submodules       bool
+	hashes           bool
+	changedSince     bool
+	changelog        int
+	trimEdges        bool
+	runID            string
+	strict           bool
+	changelogFiles   bool
+	relativeTo       string
+	exclude          string
+	embedBinary      string
+	imports          bool
+	signatures       bool
+	dominantOnly     bool
+	topLanguages     int
+	dirReadmes       bool
+	baseline         string
+	roles            string
+	roleDirs         string
+	typeThreshold    float64
+	include          string
+	extensions       string
+	sortOrder        string
+	reverse          bool
+	maxFiles         int
+	dropLargest      float64
+	absolutePaths    bool
+
+	renderOpts codesum.RenderOptions
+
+	repo             string
+	dir              string
+	ref              string
+	keepClone        string
+	separateProjects bool
+	explain          explainFlag
+	untested         bool
+	name             string
+	manifestFile     string
+	recent           int
+	distribution     bool
+	groupBy          string
+	assets           bool
+	goEmbed          bool
+	nearDupes        bool
+	stripLicenses    bool
+	failOnCase       bool
+	authors          listFlag
+	authorMode       string
+	authorCommits    int
+	extOnly          bool
+	noDefaultIgnores bool
+	maxSize          byteSize
+
+	// root is the directory that is summarized, and repositoryURL is the git URL it was cloned from, if any.
+	// archive is the archive that is summarized instead, and roots are the directories when several are given.
+	// They are set by run, from the arguments.
+	root          string
+	repositoryURL string
+	archive       string
+	roots         []string
+
+	// decisions records why files were skipped, for -explain
+	decisions *decisionRecorder
+}
+
+// valueHint describes how the value of a flag can be completed by a shell
+type valueHint struct {
+	file    bool     // complete filenames
+	choices []string // complete one of these words
+}
+
+// flagValueHints are used when generating shell completion scripts.
+// Flags that take a value but are not listed here get no value completion.
+var flagValueHints = map[string]valueHint{
+	"o":             {file: true},
+	"also-json":     {file: true},
+	"error-report":  {file: true},
+	"metrics-out":   {file: true},
+	"manifest-file": {file: true},
+	"baseline":      {file: true},
+	"config":        {file: true},
+	"template":      {file: true},
+	"relative-to":   {file: true},
+	"keep-clone":    {file: true},
+	"dir":           {file: true},
+	"preset":        {choices: presetNames()},
+	"run-id":        {choices: runIDModeNames()},
+	"time-format":   {choices: codesum.TimeFormats},
+	"sort":          {choices: sortOrderNames()},
+	"author-mode":   {choices: authorModeNames()},
+	"format":        {choices: outputFormats},
+	"group-by":      {choices: groupByValues},
+}
+
+// groupByValues are the values of -group-by
+var groupByValues = []string{"language", "dir"}
+
+// outputFormats are the values of -format
+var outputFormats = []string{"markdown", "json", "gist", "rst"}
+
+// outputWriters are the renderers for each output format, including the template that is used for -template and -preset
+var outputWriters = map[string]func(io.Writer, codesum.ProjectInfo, codesum.RenderOptions) error{
+	"markdown": codesum.WriteMarkdown,
+	"json":     codesum.WriteJSON,
+	"gist":     codesum.WriteGist,
+	"rst":      codesum.WriteRST,
+	"template": codesum.WriteTemplate,
+}
+
+// outputFormat returns the format to write, where -json and -gist-json are short for -format=json and -format=gist
+func (c *cliFlags) outputFormat() string {
+	switch {
+	case c.jsonOutput:
+		return "json"
+	case c.gistOutput:
+		return "gist"
+	case c.format != "":
+		return c.format
+	case c.renderOpts.Template != "":
+		return "template"
+	}
+	return "markdown"
+}
+
+// directories returns the directories that are summarized
+func (c *cliFlags) directories() []string {
+	if c.roots != nil {
+		return c.roots
+	}
+	return []string{c.root}
+}
+
+// runIDModeNames returns the names of the run ID modes
+func runIDModeNames() []string {
+	names := make([]string, len(codesum.RunIDModes))
+	for i, mode := range codesum.RunIDModes {
+		names[i] = string(mode)
+	}
+	return names
+}
+
+// authorModeNames returns the names of the author modes
+func authorModeNames() []string {
+	names := make([]string, len(codesum.AuthorModes))
+	for i, mode := range codesum.AuthorModes {
+		names[i] = string(mode)
+	}
+	return names
+}
+
+// listFlag is the value of a flag that can be given several times, where each value is added to the list
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// byteSize is the value of a flag that is a number of bytes, with an optional suffix, like 500k, 2M or 1GB, where
+// 1k is 1024 bytes
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	s := strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "b"), "i")
+	multiplier := int64(1)
+	if i := strings.LastIndexAny(s, "kmg"); i >= 0 && i == len(s)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("kmg", s[i]) + 1))
+		s = s[:i]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q, which must be a number of bytes, like 500k or 2M", value)
+	}
+	*b = byteSize(n * multiplier)
+	return nil
+}
+
+// sortOrderNames returns the names of the sort orders
+func sortOrderNames() []string {
+	names := make([]string, len(codesum.SortOrders))
+	for i, order := range codesum.SortOrders {
+		names[i] = string(order)
+	}
+	return names
+}
+
+// define registers the flags with the given flag set
+func (c *cliFlags) define(fs *flag.FlagSet) {
+	fs.BoolVar(&c.jsonOutput, "j", false, "Output in JSON format")
+	fs.BoolVar(&c.jsonOutput, "json", false, "Output in JSON format")
+	fs.BoolVar(&c.list, "l", false, "Only list the paths of the files that would be included, one per line, without reading them")
+	fs.BoolVar(&c.list, "list", false, "Only list the paths of the files that would be included, one per line, without reading them")
+	fs.BoolVar(&c.listLong, "list-long", false, "Like -list, with the language, the number of lines and the size in bytes before each path")
+	fs.BoolVar(&c.gistOutput, "gist-json", false, "Output a JSON payload for creating a GitHub Gist")
+	fs.StringVar(&c.format, "format", "", "Output in the given format: "+strings.Join(outputFormats, ", ")+" (the default is markdown, or the template if one is given)")
+	fs.BoolVar(&c.versionFlag, "v", false, "Prints the version of the program")
+	fs.BoolVar(&c.versionFlag, "version", false, "Prints the version of the program")
+	fs.BoolVar(&c.printSchema, "print-schema", false, "Print a JSON Schema of the JSON output and exit")
+	fs.StringVar(&c.configFile, "config", "", "Read the configuration from the given file instead of "+projectConfigFile+" and ~/.config/codesum/config.toml")
+	fs.BoolVar(&c.printConf, "print-config", false, "Print the effective configuration and where each value came from")
+	fs.StringVar(&c.outputFile, "o", "", "Write the output to the given file instead of to stdout")
+	fs.StringVar(&c.alsoJSON, "also-json", "", "Also write the output in JSON format to the given file, from the same scan")
+	fs.StringVar(&c.errorReport, "error-report", "", "Write a JSON array of the files that were skipped because of errors to the given file")
+	fs.StringVar(&c.metricsOut, "metrics-out", "", "Append a JSON line with the totals and the output size of this run to the given file")
+	fs.BoolVar(&c.localTime, "local-time", false, "Use local time instead of UTC for JSON timestamps")
+	fs.BoolVar(&c.legacyTimestamps, "legacy-timestamps", false, "Also output last_modified_legacy in the old timestamp format (deprecated)")
+	fs.StringVar(&c.presetName, "preset", "", "Use a named preset: "+strings.Join(presetNames(), ", ")+", or help to describe them")
+	fs.StringVar(&c.sortOrder, "sort", "", "Sort the files in the given order instead of by path: "+strings.Join(sortOrderNames(), ", "))
+	fs.BoolVar(&c.reverse, "reverse", false, "Reverse the order of the files, after sorting them")
+	fs.IntVar(&c.maxFiles, "max-files", 0, "Include at most N files, after sorting (0 for no limit)")
+	fs.IntVar(&c.maxPerLanguage, "max-per-lang", 0, "Include at most N files per language (0 for no limit)")
+	fs.IntVar(&c.maxDepth, "max-depth", codesum.DefaultMaxDepth, "Fail if the directory tree is deeper than N levels (0 for no limit)")
+	fs.StringVar(&c.exclude, "exclude", "", "Skip the files that match any of the given comma-separated patterns, like docs/*.md")
+	fs.StringVar(&c.embedBinary, "embed-binary", "", "Include the binary files that match any of the given comma-separated patterns as base64, like testdata/*.bin")
+	fs.StringVar(&c.include, "include", "", "Only include the files that match any of the given comma-separated patterns, like cmd/*/*.go")
+	fs.StringVar(&c.extensions, "ext", "", "Only include files with the given comma-separated extensions, like go,py, or collect extensions as languages, like .rb=Ruby")
+	fs.StringVar(&c.languages, "lang", "", "Only include files of the given comma-separated languages, like go,python")
+	fs.BoolVar(&c.dominantOnly, "dominant-only", false, "Only include the files of the main language of the project, which is ignored if -lang is given")
+	fs.StringVar(&c.roles, "role", "", "Only include the files with the given comma-separated roles, from their directories, like entrypoint for cmd/")
+	fs.StringVar(&c.roleDirs, "role-dirs", "", "Give the files below the given directory names a role, as comma-separated pairs like tools=script, where an empty role removes one")
+	fs.Float64Var(&c.typeThreshold, "type-threshold", codesum.DefaultTypeThreshold, "The share of the lines, in percent, that the main language needs, or else it is Mixed (0 for the language with the most files)")
+	fs.IntVar(&c.topLanguages, "top-langs", 0, "Only include the files of the N languages with the most files (0 for all languages)")
+	fs.Float64Var(&c.dropLargest, "drop-largest-percent", 0, "Drop the largest P percent of the files by size, as a relative alternative to -max-filesize")
+	fs.Int64Var(&c.maxFileSize, "max-filesize", 0, "Skip files that are larger than N bytes (0 for no limit)")
+	fs.IntVar(&c.concurrency, "concurrency", runtime.NumCPU(), "Read up to N files in parallel")
+	fs.BoolVar(&c.gitMetadata, "git", false, "Add the last git commit of each file")
+	fs.BoolVar(&c.noContents, "no-contents", false, "Leave out the file contents, only output metadata (requires -json or a template)")
+	fs.BoolVar(&c.submodules, "include-submodules", false, "Include the files of initialized git submodules")
+	fs.BoolVar(&c.hashes, "hash", false, "Add the SHA-256 hash of each file")
+	fs.StringVar(&c.baseline, "baseline", "", "Only output the files that differ from those in the given directory, like an older copy, with diffs of the modified files")
+	fs.BoolVar(&c.changedSince, "changed-since-last", false, "Only output the files that changed since the last run with this flag in this directory")
+	fs.IntVar(&c.changelog, "changelog", 0, "Add the last N commit subjects as a changelog section, if in a git repository")
+	fs.BoolVar(&c.changelogFiles, "changelog-files", false, "List the files that each commit in the changelog touched")
+	fs.BoolVar(&c.strict, "strict", false, "Fail if a directory or file can not be read, instead of skipping it with a warning")
+	fs.StringVar(&c.runID, "run-id", "", "Add a run ID, as one of: "+strings.Join(runIDModeNames(), ", "))
+	fs.StringVar(&c.dir, "dir", ".", "Summarize the given directory instead of the current one, the same as giving the directory as an argument")
+	fs.StringVar(&c.repo, "repo", "", "Summarize a shallow clone of the git repository at the given URL, the same as giving the URL as an argument")
+	fs.StringVar(&c.ref, "ref", "", "Clone the given branch, tag or full commit hash, when summarizing a git URL")
+	fs.StringVar(&c.keepClone, "keep-clone", "", "Clone into the given directory and keep it, when summarizing a git URL")
+	fs.BoolVar(&c.assets, "assets", false, "List the files that are not source code, like images and model files, without their contents")
+	fs.Var(&c.authors, "author", "Only include the files where the last commit is by the given author, a part of the name or email address (can be repeated)")
+	fs.StringVar(&c.authorMode, "author-mode", string(codesum.AuthorLast), "Which commits of each file -author looks at: "+strings.Join(authorModeNames(), ", "))
+	fs.IntVar(&c.authorCommits, "author-commits", codesum.DefaultAuthorCommits, "The number of recent commits of each file that -author-mode any-recent looks at")
+	fs.BoolVar(&c.extOnly, "ext-only", false, "Only collect the extensions that are given with a language to -ext, instead of the built-in ones too")
+	fs.Var(&c.maxSize, "max-size", "Leave out the contents of the files that are larger than the given size, like 500k or 2M, and mark them as truncated (0 for no limit)")
+	fs.BoolVar(&c.noDefaultIgnores, "no-default-ignores", false, "Do not skip the common directories, like vendor, test and node_modules, unless an ignore file lists them")
+	fs.BoolVar(&c.failOnCase, "fail-on-case-collision", false, "Fail if paths only differ in case, like Util.go and util.go, which collide on macOS and Windows")
+	fs.BoolVar(&c.stripLicenses, "strip-license-headers", false, "Leave out the license headers at the start of the files, when they look like a license or several files start with them")
+	fs.BoolVar(&c.nearDupes, "near-dupes", false, "List the pairs of files in the same language that are at least 80% similar, ignoring comments and whitespace")
+	fs.BoolVar(&c.goEmbed, "go-embed", false, "Include the files that the //go:embed directives of the Go files refer to, and list the binary ones")
+	fs.BoolVar(&c.distribution, "distribution", false, "Add a histogram of the line counts, and the median and 90th percentile of the line counts per language")
+	fs.IntVar(&c.recent, "recent", 0, "List the N most recently modified files in a section of their own")
+	fs.StringVar(&c.name, "name", "", "Use the given project name instead of detecting it from go.mod or the directory name")
+	fs.StringVar(&c.manifestFile, "manifest-file", "", "Read the project name, version and dependencies from the given go.mod, package.json, Cargo.toml or pyproject.toml, relative to the directory")
+	fs.BoolVar(&c.untested, "untested", false, "Only output the Go files that have no test file, like foo_test.go for foo.go, or a test file for the whole package")
+	fs.Var(&c.explain, "explain", "Print why the file given as -explain=PATH was included or skipped, or why each file was skipped if no path is given, to stderr (can be repeated)")
+	fs.BoolVar(&c.separateProjects, "separate-projects", false, "Output one section per directory when several are given, instead of merging them into one project")
+	fs.StringVar(&c.relativeTo, "relative-to", "", "Report the paths relative to the given directory (the default is the repository root, or the current directory outside of git)")
+	fs.BoolVar(&c.absolutePaths, "absolute-paths", false, "Report absolute paths")
+	fs.BoolVar(&c.trimEdges, "trim-edges", false, "Remove leading and trailing blank lines from the contents of each file")
+	fs.BoolVar(&c.outline, "outline", false, "List the top-level declarations of Go and Python files")
+	fs.BoolVar(&c.summaries, "summaries", false, "Show the first sentence of the leading doc comment of Go, Python and Rust files next to their headings")
+	fs.BoolVar(&c.imports, "imports", false, "Classify the imports of Go files as standard library, internal or external, and list the external modules")
+	fs.BoolVar(&c.signatures, "signatures", false, "Show only the declarations of C and C++ files, like function prototypes and structs, instead of the whole files")
+	fs.BoolVar(&c.renderOpts.OutlineOnly, "outline-only", false, "List the top-level declarations of Go and Python files instead of the file contents")
+	fs.Int64Var(&c.dirBudget, "dir-budget", 0, "Include at most N bytes of files per top-level directory (0 for no limit)")
+	fs.BoolVar(&c.renderOpts.GroupByLanguage, "group-by-language", false, "List the files under a heading per language, with interface definitions like .proto files first")
+	fs.StringVar(&c.groupBy, "group-by", "", "List the files under a heading per language or per directory, as one of: "+strings.Join(groupByValues, ", "))
+	fs.IntVar(&c.renderOpts.GroupDepth, "group-depth", 1, "The number of path elements that decide the directory for -group-by dir")
+	fs.BoolVar(&c.dirReadmes, "dir-readmes", false, "Introduce the section of each directory for -group-by dir with its README.md, and add the README files to the JSON output")
+	fs.BoolVar(&c.renderOpts.Tree, "tree", false, "Add the directory tree of the files, with the number of files and lines of each directory")
+	fs.BoolVar(&c.renderOpts.Tight, "tight", false, "Leave out the blank lines between the sections of the Markdown output, to save tokens")
+	fs.BoolVar(&c.renderOpts.ContentsAsLines, "contents-as-lines", false, "Output the contents of each file as an array of lines in the JSON output, instead of as one string")
+	fs.BoolVar(&c.renderOpts.MetricsLine, "metrics-line", false, "Show the line count, size, modification time and estimated tokens of each file on one line below its heading")
+	fs.BoolVar(&c.renderOpts.ReadingTime, "reading-time", false, "Show an estimate of how long it takes to read each file, and the whole project, in the Markdown output")
+	fs.StringVar(&c.renderOpts.TimeFormat, "time-format", "", "Show the generation time and the modification time of each file in the Markdown output, as one of: "+strings.Join(codesum.TimeFormats, ", ")+" or a Go time layout")
+	fs.BoolVar(&c.renderOpts.UTC, "utc", false, "Show the times for -time-format in UTC instead of in the local time zone")
+	fs.StringVar(&c.templateFile, "template", "", "Render the output with the given Go text/template file")
+	fs.Int64Var(&c.failOver, "fail-over", 0, "Fail without writing the output if it is larger than N bytes (0 for no limit)")
+	fs.Int64Var(&c.maxOutputBytes, "max-output-bytes", defaultMaxOutputBytes, "Stop writing the output before it gets larger than N bytes, leaving no output file behind (0 for no limit)")
+	fs.Int64Var(&c.failOverTokens, "fail-over-tokens", 0, "Fail without writing the output if it is estimated to be more than N tokens (0 for no limit)")
+	fs.BoolVar(&c.watchMode, "watch", false, "Write the output to the -o file again whenever a file in the directory changes")
+	fs.DurationVar(&c.watchInterval, "watch-interval", 500*time.Millisecond, "Wait until no files have changed for this long before writing the output again, in -watch mode")
+	fs.BoolVar(&c.quiet, "quiet", false, "Only print fatal errors to stderr, no warnings")
+	fs.BoolVar(&c.verbose, "V", false, "Print why files are skipped and how long each step took to stderr")
+	fs.BoolVar(&c.verbose, "verbose", false, "Print why files are skipped and how long each step took to stderr")
+	fs.BoolVar(&c.debug, "VV", false, "Print debug output to stderr, in addition to the -verbose output")
+	fs.BoolVar(&c.debug, "debug", false, "Print debug output to stderr, in addition to the -verbose output")
+}
+
+// options returns the collection options. Each collection flag maps to exactly one option.
+func (c *cliFlags) options(logger *slog.Logger) []codesum.Option {
+	return []codesum.Option{
+		codesum.WithLogger(logger),
+		codesum.WithLocalTime(c.localTime),
+		codesum.WithLegacyTimestamps(c.legacyTimestamps),
+		codesum.WithSort(codesum.SortOrder(c.sortOrder)),
+		codesum.WithReverse(c.reverse),
+		codesum.WithMaxFiles(c.maxFiles),
+		codesum.WithMaxPerLanguage(c.maxPerLanguage),
+		codesum.WithMaxDepth(c.maxDepth),
+		codesum.WithLanguages(splitList(c.languages)...),
+		codesum.WithMaxFileSize(c.maxFileSize),
+		codesum.WithDropLargestPercent(c.dropLargest),
+		codesum.WithConcurrency(c.concurrency),
+		codesum.WithGitMetadata(c.gitMetadata),
+		codesum.WithDirBudget(c.dirBudget),
+		codesum.WithoutContents(c.noContents),
+		codesum.WithListOnly(c.list || c.listLong, c.listLong),
+		codesum.WithOutline(c.outline),
+		codesum.WithSummaries(c.summaries),
+		codesum.WithSignatures(c.signatures),
+		codesum.WithSubmodules(c.submodules),
+		codesum.WithHashes(c.hashes || c.changedSince),
+		codesum.WithChangelog(c.changelog, c.changelogFiles),
+		codesum.WithTrimEdges(c.trimEdges),
+		codesum.WithRunID(codesum.RunIDMode(c.runID)),
+		codesum.WithStrict(c.strict),
+		codesum.WithRelativeTo(c.relativeTo),
+		codesum.WithExclude(splitList(c.exclude)...),
+		codesum.WithEmbedBinary(splitList(c.embedBinary)...),
+		codesum.WithInclude(splitList(c.include)...),
+		extensionsOption(c.extensions),
+		codesum.WithOnlyExtensionLanguages(c.extOnly),
+		codesum.WithCommonIgnores(!c.noDefaultIgnores),
+		codesum.WithMaxContentSize(int64(c.maxSize)),
+		codesum.WithAbsolutePaths(c.absolutePaths),
+		codesum.WithUntested(c.untested),
+		codesum.WithName(c.name),
+		codesum.WithManifestFile(filepath.ToSlash(c.manifestFile)),
+		codesum.WithRecent(c.recent),
+		codesum.WithDistribution(c.distribution),
+		codesum.WithAssets(c.assets),
+		codesum.WithGoEmbed(c.goEmbed),
+		nearDuplicatesOption(c.nearDupes),
+		codesum.WithStripLicenseHeaders(c.stripLicenses),
+		codesum.WithFailOnCaseCollision(c.failOnCase),
+		codesum.WithAuthors(c.authors...),
+		codesum.WithAuthorMode(codesum.AuthorMode(c.authorMode), c.authorCommits),
+		codesum.WithImports(c.imports),
+		codesum.WithDominantOnly(c.dominantOnly),
+		codesum.WithTopLanguages(c.topLanguages),
+		codesum.WithDirReadmes(c.dirReadmes),
+		codesum.WithRoles(splitList(c.roles)...),
+		roleDirsOption(c.roleDirs),
+		codesum.WithTypeThreshold(c.typeThreshold),
+	}
+}
+
+// nearDuplicatesOption returns codesum.WithNearDuplicates with the default threshold, if enabled
+func nearDuplicatesOption(enabled bool) codesum.Option {
+	if !enabled {
+		return codesum.WithNearDuplicates(0)
+	}
+	return codesum.WithNearDuplicates(codesum.DefaultNearDuplicateThreshold)
+}
+
+// roleDirsOption parses a comma-separated list of directory names and roles, like "cmd=entrypoint,tools=script",
+// for codesum.WithRoleDirs
+func roleDirsOption(list string) codesum.Option {
+	dirs := make(map[string]string)
+	for _, entry := range splitList(list) {
+		dir, role, ok := strings.Cut(entry, "=")
+		if !ok {
+			return func(*codesum.Options) error {
+				return fmt.Errorf("invalid -role-dirs entry %q, which must be like cmd=entrypoint", entry)
+			}
+		}
+		dirs[strings.TrimSpace(dir)] = strings.TrimSpace(role)
+	}
+	return codesum.WithRoleDirs(dirs)
+}
+
+// extensionsOption parses a comma-separated list of extensions, like "go,py", for codesum.WithExtensions, where the
+// extensions with a language, like ".rb=Ruby", are for codesum.WithExtensionLanguages too. Those are only included
+// with the other extensions when some are given, so that a language can be added without limiting the files.
+func extensionsOption(list string) codesum.Option {
+	var extensions, withLanguage []string
+	languages := make(map[string]string)
+	for _, entry := range splitList(list) {
+		if ext, language, ok := strings.Cut(entry, "="); ok {
+			ext = strings.TrimSpace(ext)
+			languages[ext] = strings.TrimSpace(language)
+			withLanguage = append(withLanguage, ext)
+			continue
+		}
+		extensions = append(extensions, entry)
+	}
+	if len(extensions) > 0 {
+		extensions = append(extensions, withLanguage...)
+	}
+	return func(o *codesum.Options) error {
+		if err := codesum.WithExtensions(extensions...)(o); err != nil {
+			return err
+		}
+		return codesum.WithExtensionLanguages(languages)(o)
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty elements
+func splitList(s string) []string {
+	var list []string
+	for _, element := range strings.Split(s, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
@@ -1,3 +1,12 @@
 module github.com/xyproto/codesum
 
 go 1.22.2
+
+require golang.org/x/sync v0.10.0
+
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/fsnotify/fsnotify v1.7.0
+)
+
+require golang.org/x/sys v0.4.0 // indirect
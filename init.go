@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+const projectIgnoreFile = ".codesumignore"
+
+// generatedDirs are directory names that usually hold dependencies or build output instead of source code.
+// vendor and node_modules are not listed, since they are always skipped.
+var generatedDirs = map[string]string{
+	"dist":             "build output",
+	"build":            "build output",
+	"out":              "build output",
+	"target":           "Cargo or Maven build output",
+	"coverage":         "coverage reports",
+	".venv":            "a Python virtual environment",
+	"venv":             "a Python virtual environment",
+	"__pycache__":      "Python bytecode",
+	".next":            "Next.js build output",
+	"bower_components": "Bower dependencies",
+	"third_party":      "third-party code",
+}
+
+// ciMarkers are the files or directories that show which CI system is used
+var ciMarkers = []struct {
+	path string
+	name string
+}{
+	{".github/workflows", "GitHub Actions"},
+	{".gitlab-ci.yml", "GitLab CI"},
+	{".circleci", "CircleCI"},
+	{".travis.yml", "Travis CI"},
+	{"Jenkinsfile", "Jenkins"},
+	{"azure-pipelines.yml", "Azure Pipelines"},
+	{".woodpecker.yml", "Woodpecker CI"},
+}
+
+// A top-level directory is worth pointing out if it has at least largeDirShare of the collected bytes and
+// is at least largeDirSize bytes, so that small projects get no suggestions
+const (
+	largeDirShare = 0.4
+	largeDirSize  = 256 << 10
+)
+
+// largeFileSize is the file size that makes -max-filesize worth suggesting
+const largeFileSize = 1 << 20
+
+// dirUsage is the number of collected files and bytes in one directory
+type dirUsage struct {
+	path   string
+	reason string
+	files  int
+	bytes  int64
+}
+
+// initFindings is what runInit found out about the project
+type initFindings struct {
+	languages   []string
+	totals      codesum.Totals
+	buildSystem string
+	generated   []dirUsage
+	large       []dirUsage
+	largestFile codesum.FileInfo
+	ci          []string
+}
+
+// runInit writes a .codesumignore and a .codesum.toml file that are tailored to the project in the current directory
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("codesum init", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Overwrite "+projectIgnoreFile+" and "+projectConfigFile+" if they exist")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if !*force {
+		for _, filename := range []string{projectIgnoreFile, projectConfigFile} {
+			if _, err := os.Stat(filename); err == nil {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+			}
+		}
+	}
+
+	findings, err := inspectProject(context.Background(), ".", newLogger(os.Stderr, slog.LevelWarn))
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(projectIgnoreFile, findings.writeIgnoreFile); err != nil {
+		return err
+	}
+	if err := writeOutput(projectConfigFile, findings.writeConfigFile); err != nil {
+		return err
+	}
+	findings.writeSummary(os.Stdout)
+	return nil
+}
+
+// inspectProject walks the given directory without reading the file contents, and looks for CI configuration
+func inspectProject(ctx context.Context, root string, logger *slog.Logger) (initFindings, error) {
+	// A .codesumignore file that is about to be replaced should not hide anything
+	project, err := codesum.Collect(ctx, root,
+		codesum.WithoutContents(true),
+		codesum.WithIgnoreFiles(".ignore", ".gitignore"),
+		codesum.WithLogger(logger))
+	if err != nil {
+		return initFindings{}, err
+	}
+
+	findings := initFindings{totals: project.Totals, buildSystem: project.BuildSystem}
+	for lang := range project.Totals.Languages {
+		findings.languages = append(findings.languages, lang)
+	}
+	sort.Slice(findings.languages, func(i, j int) bool {
+		a, b := project.Totals.Languages[findings.languages[i]], project.Totals.Languages[findings.languages[j]]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return findings.languages[i] < findings.languages[j]
+	})
+
+	// Files in generated directories are about to be ignored, so they do not count towards the other suggestions
+	generated := make(map[string]*dirUsage)
+	topLevel := make(map[string]*dirUsage)
+	var keptBytes int64
+	for _, file := range project.Files {
+		elements := strings.Split(file.Path, "/")
+		if dir, reason, ok := generatedDir(elements); ok {
+			if generated[dir] == nil {
+				generated[dir] = &dirUsage{path: dir, reason: reason}
+			}
+			generated[dir].files++
+			generated[dir].bytes += file.Size
+			continue
+		}
+		keptBytes += file.Size
+		if file.Size > findings.largestFile.Size {
+			findings.largestFile = file
+		}
+		if len(elements) > 1 {
+			if topLevel[elements[0]] == nil {
+				topLevel[elements[0]] = &dirUsage{path: elements[0]}
+			}
+			topLevel[elements[0]].files++
+			topLevel[elements[0]].bytes += file.Size
+		}
+	}
+	for _, usage := range generated {
+		findings.generated = append(findings.generated, *usage)
+	}
+	sort.Slice(findings.generated, func(i, j int) bool { return findings.generated[i].path < findings.generated[j].path })
+	for _, usage := range topLevel {
+		if len(topLevel) < 2 || keptBytes == 0 {
+			break
+		}
+		if share := float64(usage.bytes) / float64(keptBytes); share >= largeDirShare && usage.bytes >= largeDirSize {
+			usage.reason = fmt.Sprintf("%.0f%% of the bytes", share*100)
+			findings.large = append(findings.large, *usage)
+		}
+	}
+	sort.Slice(findings.large, func(i, j int) bool { return findings.large[i].path < findings.large[j].path })
+
+	fsys := os.DirFS(root)
+	for _, marker := range ciMarkers {
+		if _, err := fs.Stat(fsys, marker.path); err == nil {
+			findings.ci = append(findings.ci, marker.name)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("could not check for CI configuration", "path", marker.path, "error", err)
+		}
+	}
+	return findings, nil
+}
+
+// generatedDir returns the first directory in the given path elements that looks like it holds dependencies or build output
+func generatedDir(elements []string) (string, string, bool) {
+	for i, name := range elements[:len(elements)-1] {
+		if reason, ok := generatedDirs[name]; ok {
+			return strings.Join(elements[:i+1], "/"), reason, true
+		}
+	}
+	return "", "", false
+}
+
+// writeIgnoreFile writes a commented .codesumignore file
+func (f initFindings) writeIgnoreFile(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# Ignore patterns for codesum, generated by \"codesum init\".\n")
+	sb.WriteString("# Each line is a glob that is matched against directory names, or a directory path relative to this directory.\n")
+	sb.WriteString("# Comments must be on their own line.\n")
+	sb.WriteString("# vendor, test, tmp, backup and node_modules are always skipped.\n")
+	if len(f.generated) > 0 {
+		sb.WriteString("\n# Dependencies and build output\n")
+		for _, dir := range f.generated {
+			fmt.Fprintf(&sb, "# %s, %d files\n%s\n", dir.reason, dir.files, dir.path)
+		}
+	}
+	if len(f.large) > 0 {
+		sb.WriteString("\n# Large directories, uncomment to leave them out\n")
+		for _, dir := range f.large {
+			fmt.Fprintf(&sb, "# %s has %s, in %d files\n# %s\n", dir.path, dir.reason, dir.files, dir.path)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeConfigFile writes a commented .codesum.toml file
+func (f initFindings) writeConfigFile(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# Configuration for codesum, generated by \"codesum init\".\n")
+	sb.WriteString("# Each key is the name of a flag, see \"codesum -h\". Flags that are given on the command line take precedence.\n")
+	if len(f.languages) > 0 {
+		quoted := make([]string, len(f.languages))
+		counts := make([]string, len(f.languages))
+		for i, lang := range f.languages {
+			quoted[i] = fmt.Sprintf("%q", lang)
+			counts[i] = fmt.Sprintf("%s (%d files)", lang, f.totals.Languages[lang].Files)
+		}
+		fmt.Fprintf(&sb, "\n# Found %s. Uncomment to only include these languages, even if files in other languages are added later.\n", strings.Join(counts, ", "))
+		fmt.Fprintf(&sb, "# lang = [%s]\n", strings.Join(quoted, ", "))
+	}
+	if f.largestFile.Size > largeFileSize {
+		fmt.Fprintf(&sb, "\n# %s is %d bytes. Skip files over 1 MiB, which are often generated.\n", f.largestFile.Path, f.largestFile.Size)
+		fmt.Fprintf(&sb, "max-filesize = %d\n", largeFileSize)
+	}
+	if len(f.ci) > 0 {
+		fmt.Fprintf(&sb, "\n# Found %s. Uncomment to make codesum fail in CI when the summary grows too large.\n", strings.Join(f.ci, ", "))
+		sb.WriteString("# fail-over-tokens = 200000\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeSummary describes what was generated and why
+func (f initFindings) writeSummary(w io.Writer) {
+	fmt.Fprintf(w, "Wrote %s and %s, after finding %d files in %d languages", projectIgnoreFile, projectConfigFile, f.totals.Files, len(f.languages))
+	if f.buildSystem != "" {
+		fmt.Fprintf(w, ", built with %s", f.buildSystem)
+	}
+	fmt.Fprintln(w, ".")
+	for _, dir := range f.generated {
+		fmt.Fprintf(w, "  %s: ignoring %s, which looks like %s\n", projectIgnoreFile, dir.path, dir.reason)
+	}
+	for _, dir := range f.large {
+		fmt.Fprintf(w, "  %s: suggesting to ignore %s, which has %s\n", projectIgnoreFile, dir.path, dir.reason)
+	}
+	if len(f.languages) > 0 {
+		fmt.Fprintf(w, "  %s: suggesting lang = %s, which can be uncommented\n", projectConfigFile, strings.Join(f.languages, ","))
+	}
+	if f.largestFile.Size > largeFileSize {
+		fmt.Fprintf(w, "  %s: setting max-filesize, since %s is %d bytes\n", projectConfigFile, f.largestFile.Path, f.largestFile.Size)
+	}
+	if len(f.ci) > 0 {
+		fmt.Fprintf(w, "  %s: suggesting fail-over-tokens for %s\n", projectConfigFile, strings.Join(f.ci, ", "))
+	}
+}
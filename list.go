@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// writeList writes the paths of the files of the projects, one per line, for -list. With long, the language, the number
+// of lines and the size in bytes come before each path, in aligned columns.
+func writeList(w io.Writer, projects []codesum.ProjectInfo, long bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, project := range projects {
+		for _, file := range project.Files {
+			if !long {
+				fmt.Fprintln(tw, file.Path)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", file.Language, file.LineCount, file.Size, file.Path)
+		}
+	}
+	return tw.Flush()
+}
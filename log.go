@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// levelPrefixes are written in front of each log message
+var levelPrefixes = map[slog.Level]string{
+	slog.LevelDebug: "Debug: ",
+	slog.LevelInfo:  "Info: ",
+	slog.LevelWarn:  "Warning: ",
+	slog.LevelError: "Error: ",
+}
+
+// logHandler writes one line per log record, as a prefix, the message and then key=value pairs
+type logHandler struct {
+	mut   *sync.Mutex
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+// newLogger returns a logger that writes messages at the given level and above to w
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&logHandler{mut: &sync.Mutex{}, w: w, level: level})
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(levelPrefixes[r.Level])
+	sb.WriteString(r.Message)
+	writeAttr := func(a slog.Attr) bool {
+		value := a.Value.Resolve().String()
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&sb, " %s=%s", a.Key, value)
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	sb.WriteByte('\n')
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	_, err := io.WriteString(h.w, sb.String())
+	return err
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup is not used by codesum, so groups are flattened
+func (h *logHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// logLevel returns the lowest level to log, given the -quiet, -verbose and -debug flags
+func (c *cliFlags) logLevel() slog.Level {
+	switch {
+	case c.quiet:
+		return slog.LevelError + 1 // Fatal errors are printed by main
+	case c.debug:
+		return slog.LevelDebug
+	case c.verbose:
+		return slog.LevelInfo
+	}
+	return slog.LevelWarn
+}
@@ -1,301 +1,557 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
-)
-
-const versionString = "codesum 1.1.0"
+	"syscall"
+	"time"
 
-var (
-	jsonOutput  bool
-	versionFlag bool
+	"github.com/xyproto/codesum/pkg/codesum"
 )
 
-func init() {
-	flag.BoolVar(&jsonOutput, "j", false, "Output in JSON format")
-	flag.BoolVar(&jsonOutput, "json", false, "Output in JSON format")
-	flag.BoolVar(&versionFlag, "v", false, "Prints the version of the program")
-	flag.BoolVar(&versionFlag, "version", false, "Prints the version of the program")
-	flag.Parse()
+// subcommands are run when their name is the first argument. Without a subcommand, run is used.
+var subcommands map[string]func(args []string) error
 
-	if versionFlag {
-		fmt.Println(versionString)
-		os.Exit(0)
+func init() {
+	subcommands = map[string]func(args []string) error{
+		"completion": runCompletion,
+		"diff":       runDiff,
+		"doctor":     runDoctor,
+		"init":       runInit,
+		"mcp":        runMCP,
+		"serve":      runServe,
 	}
 }
 
-type FileInfo struct {
-	Path         string `json:"path"`
-	Language     string `json:"language"`
-	LineCount    int    `json:"line_count,omitempty"`
-	LastModified string `json:"last_modified,omitempty"`
-	Contents     string `json:"contents,omitempty"`
-}
+// run is the default command, which summarizes the current directory, a clone of the given git URL or the given archive
+func run(args []string) error {
+	var c cliFlags
+	// Parse errors are returned, so that they are mapped onto the exit codes like any other error
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	c.define(flag.CommandLine)
+	flag.CommandLine.Usage = usage
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return err
+	}
 
-type ProjectInfo struct {
-	Name       string     `json:"name"`
-	Repository string     `json:"repository"`
-	Files      []FileInfo `json:"files"`
-	Type       string     `json:"type"`
-}
+	if c.versionFlag {
+		return writeVersion(os.Stdout, readBuildInfo(), c.jsonOutput)
+	}
+	if c.printSchema {
+		data, err := json.MarshalIndent(codesum.JSONSchema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("%s\n", data)
+		return err
+	}
 
-func recognizedExtension(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".go", ".cpp", ".hpp", ".cc", ".h", ".rs", ".c", ".py", ".md", ".java", ".js", ".jsx", ".ts", ".tsx", ".kt":
-		return true
+	recordCommandLineFlags()
+	configWarnings, err := loadConfigFiles(c.configFile)
+	if err == nil {
+		err = applyEnvironment()
+	}
+	// The log level can be configured too, so the logger is created once the configuration is read
+	logger := newLogger(os.Stderr, c.logLevel())
+	for _, warning := range configWarnings {
+		logger.Warn(warning)
+	}
+	if err != nil {
+		return err
+	}
+
+	renderOpts := &c.renderOpts
+	if c.presetName == "help" {
+		return printPresets(os.Stdout)
+	}
+	if c.presetName != "" {
+		text, err := applyPreset(c.presetName)
+		if err != nil {
+			return err
+		}
+		renderOpts.Template = text
+	}
+	if c.templateFile != "" {
+		data, err := os.ReadFile(c.templateFile)
+		if err != nil {
+			return fmt.Errorf("could not read template: %w", err)
+		}
+		renderOpts.Template = string(data)
+	}
+
+	if c.printConf {
+		return printConfig(os.Stdout)
 	}
-	return false
-}
 
-func languageFromExtension(ext string) string {
-	switch ext {
-	case ".go":
-		return "Go"
-	case ".cpp", ".cc":
-		return "C++"
-	case ".hpp", ".h":
-		return "C/C++ Header"
-	case ".rs":
-		return "Rust"
-	case ".c":
-		return "C"
-	case ".py":
-		return "Python"
-	case ".md":
-		return "Markdown"
-	case ".java":
-		return "Java"
-	case ".js", ".jsx":
-		return "JavaScript"
-	case ".ts", ".tsx":
-		return "TypeScript"
-	case ".kt":
-		return "Kotlin"
-	case ".adoc":
-		return "ASCIIDoc"
-	case ".rst":
-		return "reStructuredText"
-	case ".txt":
-		return "Plain text"
+	if renderOpts.OutlineOnly {
+		c.outline = true
+	}
+	if c.watchMode && c.outputFile == "" {
+		return errors.New("-watch requires -o, since the output is written again on every change")
+	}
+	if c.failOver < 0 || c.failOverTokens < 0 {
+		return errors.New("-fail-over and -fail-over-tokens can not be negative")
+	}
+	if c.format != "" && !slices.Contains(outputFormats, c.format) {
+		return fmt.Errorf("unknown format %q, must be one of: %s", c.format, strings.Join(outputFormats, ", "))
+	}
+	switch c.groupBy {
+	case "":
+	case "language":
+		renderOpts.GroupByLanguage = true
+	case "dir":
+		renderOpts.GroupByDirectory = true
 	default:
-		return "Unknown"
+		return fmt.Errorf("unknown -group-by %q, must be one of: %s", c.groupBy, strings.Join(groupByValues, ", "))
+	}
+	if renderOpts.GroupByLanguage && renderOpts.GroupByDirectory {
+		return errors.New("-group-by-language and -group-by dir can not be combined")
+	}
+	if renderOpts.GroupDepth < 1 {
+		return errors.New("-group-depth must be at least 1")
+	}
+	if format := c.outputFormat(); c.noContents && format != "json" && format != "template" {
+		return errors.New("-no-contents can only be used together with -json or a template, since the Markdown output is made of file contents")
 	}
-}
 
-func loadIgnorePatterns(filenames ...string) (map[string]struct{}, error) {
-	ignores := make(map[string]struct{})
-	for _, filename := range filenames {
-		data, err := os.ReadFile(filename)
-		if err != nil {
-			continue // Ignore files that cannot be read or don't exist
+	if c.relativeTo != "" && c.absolutePaths {
+		return errors.New("-relative-to and -absolute-paths can not be combined")
+	}
+	c.root = "."
+	if c.dir != "." {
+		if flag.NArg() > 0 || c.repo != "" {
+			return errors.New("-dir can not be combined with arguments or -repo")
+		}
+		if !isDirectory(c.dir) {
+			return fmt.Errorf("-dir: %q is not a directory", c.dir)
 		}
-		scanner := bufio.NewScanner(strings.NewReader(string(data)))
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line != "" && !strings.HasPrefix(line, "#") {
-				ignores[line] = struct{}{}
+		if c.watchMode {
+			return errors.New("-watch can only be used for the current directory")
+		}
+		c.root = c.dir
+	}
+	switch {
+	case c.repo != "":
+		if flag.NArg() > 0 {
+			return fmt.Errorf("-repo can not be combined with arguments: %s", strings.Join(flag.Args(), " "))
+		}
+		if c.repositoryURL = c.repo; !isGitURL(c.repositoryURL) {
+			return fmt.Errorf("-repo: %q is not a git URL", c.repositoryURL)
+		}
+		if c.watchMode || c.changedSince {
+			return errors.New("-watch and -changed-since-last can not be used for a git URL, since the clone is removed afterwards")
+		}
+	case flag.NArg() > 0 && isDirectory(flag.Arg(0)):
+		for _, arg := range flag.Args() {
+			if !isDirectory(arg) {
+				return fmt.Errorf("%q is not a directory, and only directories can be given together", arg)
 			}
 		}
+		if c.watchMode {
+			return errors.New("-watch can only be used for the current directory")
+		}
+		if flag.NArg() == 1 {
+			c.root = flag.Arg(0)
+			break
+		}
+		if c.changedSince {
+			return errors.New("-changed-since-last can not be used for several directories")
+		}
+		c.roots = flag.Args()
+	case flag.NArg() > 1:
+		return fmt.Errorf("%q is not a directory, and only directories can be given together", flag.Arg(0))
+	case flag.NArg() == 1 && codesum.IsArchive(flag.Arg(0)):
+		c.archive = flag.Arg(0)
+		if c.watchMode || c.changedSince || c.relativeTo != "" || c.absolutePaths {
+			return errors.New("-watch, -changed-since-last, -relative-to and -absolute-paths can not be used for an archive")
+		}
+	case flag.NArg() == 1:
+		if c.repositoryURL = flag.Arg(0); !isGitURL(c.repositoryURL) {
+			if _, err := os.Stat(c.repositoryURL); errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("%q does not exist", c.repositoryURL)
+			}
+			return fmt.Errorf("%q is not a directory, a git URL or a .zip, .tar, .tar.gz or .tgz archive", c.repositoryURL)
+		}
+		if c.watchMode || c.changedSince {
+			return errors.New("-watch and -changed-since-last can not be used for a git URL, since the clone is removed afterwards")
+		}
 	}
-	// Add common ignores
-	commonIgnores := []string{"vendor", "test", "tmp", "backup", "node_modules"}
-	for _, dir := range commonIgnores {
-		ignores[dir] = struct{}{}
+	if (c.ref != "" || c.keepClone != "") && c.repositoryURL == "" {
+		return errors.New("-ref and -keep-clone can only be used together with a git URL")
 	}
-	return ignores, nil
-}
-
-func shouldSkip(path string, ignores map[string]struct{}) bool {
-	for ignore := range ignores {
-		if matched, _ := filepath.Match(ignore, filepath.Base(path)); matched {
-			return true
+	if c.baseline != "" {
+		if !isDirectory(c.baseline) {
+			return fmt.Errorf("-baseline: %q is not a directory", c.baseline)
+		}
+		if c.roots != nil || c.archive != "" {
+			return errors.New("-baseline can only be used for one directory or git URL")
 		}
-		if strings.HasPrefix(path, ignore+"/") {
-			return true
+		if c.watchMode || c.changedSince || c.noContents || c.relativeTo != "" || c.absolutePaths {
+			return errors.New("-baseline can not be combined with -watch, -changed-since-last, -no-contents, -relative-to or -absolute-paths")
 		}
 	}
-	return false
-}
+	if c.separateProjects && c.roots == nil {
+		return errors.New("-separate-projects can only be used when several directories are given")
+	}
+	if c.separateProjects && c.outputFormat() == "gist" {
+		return errors.New("-separate-projects can not be used for the gist format, which has one flat list of files")
+	}
 
-func detectProjectType(files []FileInfo) string {
-	languageCount := make(map[string]int)
-	for _, file := range files {
-		languageCount[file.Language]++
+	if c.explain.enabled() {
+		if c.watchMode || c.roots != nil {
+			return errors.New("-explain can not be used together with -watch or several directories")
+		}
+		c.decisions = newDecisionRecorder(logger)
+		logger = slog.New(c.decisions)
 	}
 
-	maxCount := 0
-	projectType := "Unknown"
-	for lang, count := range languageCount {
-		if count > maxCount {
-			maxCount = count
-			projectType = lang
+	if c.list || c.listLong {
+		if c.watchMode || c.changedSince || c.baseline != "" {
+			return errors.New("-list can not be combined with -watch, -changed-since-last or -baseline")
 		}
+		// The files are not read, so the flags that need the contents are turned off
+		c.outline, c.summaries, c.signatures, c.imports, c.dirReadmes = false, false, false, false, false
+		c.stripLicenses, c.nearDupes, c.goEmbed, c.changelog = false, false, false, 0
 	}
-	return projectType
-}
 
-func countLines(path string) (int, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0, err
+	opts := c.options(logger)
+
+	// Support reproducible output, see https://reproducible-builds.org/specs/source-date-epoch/
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH: %w", err)
+		}
+		opts = append(opts, codesum.WithTime(time.Unix(seconds, 0)))
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	lineCount := 0
-	for scanner.Scan() {
-		lineCount++
+	if _, err := codesum.NewOptions(opts...); err != nil {
+		return err
 	}
-	return lineCount, scanner.Err()
-}
 
-func readProjectName(modFilePath string) (string, error) {
-	file, err := os.Open(modFilePath)
-	if err != nil {
-		return "", err
+	// Interrupting cancels the collection, and ends watch mode cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if c.repositoryURL != "" {
+		dir, cleanup, err := cloneRepository(ctx, c.repositoryURL, c.ref, c.keepClone, c.quiet)
+		// The clone is removed when returning, also when interrupted
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+		c.root = dir
+	}
+	if c.archive == "" {
+		// With several roots, the patterns of all of them apply to each root
+		for _, root := range c.directories() {
+			opts = append(opts, codesum.WithExclude(excludePatterns(root, c.outputFile, c.alsoJSON, c.errorReport, c.metricsOut)...))
+		}
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if strings.HasPrefix(scanner.Text(), "module ") {
-			parts := strings.Fields(scanner.Text())
-			if len(parts) > 1 {
-				return parts[1], nil // Return the module name
-			}
+	// The paths are relative to the repository root by default, so that they are the same when run from a subdirectory.
+	// Several roots are prefixed with the directories as given instead. With -baseline, the paths are relative to
+	// the directory, like those of the baseline, so that they can be compared.
+	if c.relativeTo == "" && !c.absolutePaths && c.archive == "" && c.roots == nil && c.baseline == "" {
+		if repoRoot, err := codesum.RepositoryRoot(ctx, c.root); err == nil {
+			opts = append(opts, codesum.WithRelativeTo(repoRoot))
+		} else {
+			logger.Debug("reporting the paths relative to the current directory", "error", err)
 		}
 	}
-	return "", fmt.Errorf("no module declaration found in %s", modFilePath)
+
+	if c.watchMode {
+		return c.watch(ctx, opts, logger)
+	}
+	return c.summarize(ctx, opts, logger)
 }
 
-func readGitConfig(configFilePath string) (string, error) {
-	file, err := os.Open(configFilePath)
+// summarize collects the project in c.root, in c.archive or in c.roots, and writes the output
+func (c *cliFlags) summarize(ctx context.Context, opts []codesum.Option, logger *slog.Logger) error {
+	renderOpts := &c.renderOpts
+	projects, err := c.collect(ctx, opts)
+	if ctx.Err() != nil {
+		return errInterrupted
+	}
 	if err != nil {
-		return "", err
+		return fmt.Errorf("could not walk directory and collect files: %w", err)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	inRemoteSection := false
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, "[remote \"origin\"]") {
-			inRemoteSection = true
-		} else if inRemoteSection && strings.Contains(line, "url =") {
-			return strings.TrimSpace(strings.Split(line, "=")[1]), nil
-		} else if inRemoteSection && line == "" {
-			break // Exit if we reach the end of the section
+	var (
+		fileErrors []codesum.FileError
+		fileCount  int
+	)
+	for i := range projects {
+		project := &projects[i]
+		if c.repositoryURL != "" {
+			project.Repository = c.repositoryURL
+		}
+		project.Generator = readBuildInfo().String()
+		for _, warning := range project.Warnings {
+			logger.Warn(warning)
 		}
+		for _, fileError := range project.Errors {
+			logger.Warn(fmt.Sprintf("skipped %s: %s", fileError.Path, fileError.Error))
+		}
+		for _, fileError := range project.EnrichErrors {
+			logger.Warn(fmt.Sprintf("could not enrich %s: %s", fileError.Path, fileError.Error))
+		}
+		fileErrors = append(fileErrors, project.Errors...)
+		fileCount += len(project.Files)
+	}
+	if c.errorReport != "" {
+		if err := writeErrorReport(c.errorReport, fileErrors); err != nil {
+			return fmt.Errorf("could not write the error report: %w", err)
+		}
+	}
+	if c.decisions != nil {
+		x := explainer{decisions: c.decisions.recorded(), files: projects[0].Files}
+		x.explain(os.Stderr, &c.explain)
 	}
-	return "", fmt.Errorf("no URL found in %s", configFilePath)
-}
 
-func walkDirectoryAndCollectFiles(ignores map[string]struct{}) ([]FileInfo, error) {
-	var files []FileInfo
+	var (
+		snapshotFilename string
+		currentSnapshot  codesum.Snapshot
+	)
+	if fileCount == 0 {
+		return errNoFiles
+	}
+	if c.list || c.listLong {
+		return writeList(os.Stdout, projects, c.listLong)
+	}
 
-	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+	// -changed-since-last is only allowed for a single project
+	if c.changedSince {
+		if snapshotFilename, err = snapshotFile(c.root); err != nil {
+			return fmt.Errorf("could not find the cache directory: %w", err)
+		}
+		snapshot, err := codesum.LoadSnapshot(snapshotFilename)
 		if err != nil {
-			return err
+			return fmt.Errorf("could not read the snapshot from the last run: %w", err)
 		}
-		if d.IsDir() && shouldSkip(path, ignores) {
-			return fs.SkipDir
-		}
-		if !d.IsDir() && recognizedExtension(path) {
-			ext := filepath.Ext(path)
-			language := languageFromExtension(ext)
-			if language != "Unknown" {
-				fileInfo, err := os.Stat(path)
-				if err != nil {
-					return err
-				}
-				content, err := os.ReadFile(path)
-				if err != nil {
-					return err
-				}
-				lineCount, _ := countLines(string(content))
-
-				files = append(files, FileInfo{
-					Path:         path,
-					Language:     language,
-					LineCount:    lineCount,
-					LastModified: fileInfo.ModTime().Format("2006-01-02 15:04:05"),
-					Contents:     string(content),
-				})
+		currentSnapshot = codesum.NewSnapshot(projects[0])
+		projects[0] = codesum.ChangedSince(projects[0], snapshot)
+	}
+	if c.baseline != "" {
+		// The baseline is collected with the same options, so that the same files are compared in the same way
+		baseline, err := codesum.Collect(ctx, c.baseline, opts...)
+		if ctx.Err() != nil {
+			return errInterrupted
+		}
+		if err != nil {
+			return fmt.Errorf("could not collect the baseline: %w", err)
+		}
+		projects[0] = codesum.AgainstBaseline(projects[0], baseline)
+	}
+
+	if renderOpts.OutlineOnly {
+		for _, project := range projects {
+			for i := range project.Files {
+				project.Files[i].Contents = ""
 			}
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
-	return files, nil
-}
 
-func outputProjectInfo(project ProjectInfo) {
-	if jsonOutput {
-		data, err := json.MarshalIndent(project, "", "  ")
-		if err != nil {
-			fmt.Println("Error marshaling JSON:", err)
-			return
+	format := c.outputFormat()
+	render := func(w io.Writer) error {
+		return writeProjects(w, format, projects, *renderOpts)
+	}
+	if renderOpts.Tight && logger.Enabled(ctx, slog.LevelInfo) {
+		// Render both ways, to show what -tight saves on top of any trimming of the contents
+		var tight, spaced byteCounter
+		spacedOpts := *renderOpts
+		spacedOpts.Tight = false
+		if err := render(&tight); err != nil {
+			return err
 		}
-		fmt.Println(string(data))
-	} else {
-		// Start Markdown output
-		fmt.Printf("# %s\n\n", project.Name)
-		fmt.Printf("* Main language: %s\n", project.Type)
-		fmt.Printf("* Package name: %s\n\n", project.Repository)
+		if err := writeProjects(&spaced, format, projects, spacedOpts); err != nil {
+			return err
+		}
+		if saved := int64(spaced) - int64(tight); spaced > 0 {
+			logger.Info("tight spacing", "bytes", int64(tight), "saved", saved, "percent", fmt.Sprintf("%.2f", float64(saved)*100/float64(spaced)))
+		}
+	}
+	if c.failOver > 0 || c.failOverTokens > 0 {
+		// Render to memory first, so that oversized output is never written
+		var buf bytes.Buffer
+		if err := render(&buf); err != nil {
+			return err
+		}
+		if err := checkOutputSize(int64(buf.Len()), c.failOver, c.failOverTokens); err != nil {
+			return err
+		}
+		render = func(w io.Writer) error {
+			_, err := buf.WriteTo(w)
+			return err
+		}
+	}
+	var outputBytes byteCounter
+	if err := writeLimitedOutput(c.outputFile, c.maxOutputBytes, func(w io.Writer) error {
+		return render(io.MultiWriter(w, &outputBytes))
+	}); err != nil {
+		return err
+	}
 
-		fmt.Print("## Source code\n\n")
-		for _, file := range project.Files {
-			fmt.Printf("### %s\n\n", file.Path)
-			fmt.Printf("```%s\n", file.Language)
-			fmt.Printf("%s```\n\n", file.Contents)
+	if c.alsoJSON != "" {
+		if err := writeLimitedOutput(c.alsoJSON, c.maxOutputBytes, func(w io.Writer) error {
+			return writeProjects(w, "json", projects, *renderOpts)
+		}); err != nil {
+			return err
 		}
 	}
-}
 
-func main() {
-	ignores, err := loadIgnorePatterns(".ignore", ".gitignore")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not load ignore patterns: %v\n", err)
+	if c.metricsOut != "" {
+		now := time.Now().UTC()
+		if c.localTime {
+			now = time.Now()
+		}
+		if err := appendMetrics(c.metricsOut, newMetricsRecord(now, projects, int64(outputBytes))); err != nil {
+			return fmt.Errorf("could not write the metrics: %w", err)
+		}
 	}
 
-	files, err := walkDirectoryAndCollectFiles(ignores)
-	if err != nil {
-		fmt.Printf("Error walking directory and collecting files: %v\n", err)
-		return
+	// Only update the snapshot once the output has been written, so that a failed run does not lose any changes
+	if c.changedSince {
+		if err := currentSnapshot.Save(snapshotFilename); err != nil {
+			return fmt.Errorf("could not save the snapshot: %w", err)
+		}
 	}
+	return nil
+}
 
-	// Fetch project name from go.mod, if available
-	projectName, err := readProjectName("go.mod")
+// collect collects the projects to summarize, which is one project unless several directories are given with -separate-projects
+func (c *cliFlags) collect(ctx context.Context, opts []codesum.Option) ([]codesum.ProjectInfo, error) {
+	var (
+		project codesum.ProjectInfo
+		err     error
+	)
+	switch {
+	case c.archive != "":
+		project, err = codesum.CollectArchive(ctx, c.archive, opts...)
+	case c.roots != nil && c.separateProjects:
+		return codesum.CollectRoots(ctx, c.roots, opts...)
+	case c.roots != nil:
+		project, err = codesum.CollectMerged(ctx, c.roots, opts...)
+	default:
+		project, err = codesum.Collect(ctx, c.root, opts...)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not discover the project name from 'go.mod': %v\n", err)
-		projectName = filepath.Base(filepath.Dir("."))
+		return nil, err
 	}
+	return []codesum.ProjectInfo{project}, nil
+}
 
-	// Fetch repository name from .git/config, if available
-	repoName, err := readGitConfig(".git/config")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not read the repository details from '.git/config': %v\n", err)
-		repoName = "Unknown"
+// writeProjects writes the projects in the given format. Several projects are written one after another,
+// as sections of the same document, except for JSON, where they are written as one array.
+func writeProjects(w io.Writer, format string, projects []codesum.ProjectInfo, opts codesum.RenderOptions) error {
+	write := outputWriters[format]
+	if len(projects) == 1 {
+		return write(w, projects[0], opts)
+	}
+	if format == "json" {
+		if opts.Tree {
+			projects = slices.Clone(projects)
+			for i := range projects {
+				projects[i].Tree = codesum.BuildTree(projects[i].Files)
+			}
+		}
+		data, err := json.MarshalIndent(projects, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal JSON: %w", err)
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	for _, project := range projects {
+		if err := write(w, project, opts); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	projectType := detectProjectType(files)
+// isDirectory checks if the argument is an existing directory
+func isDirectory(arg string) bool {
+	info, err := os.Stat(arg)
+	return err == nil && info.IsDir()
+}
+
+// usage prints the usage of the default command and lists the subcommands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  codesum [flags]                     summarize the current directory")
+	fmt.Fprintln(out, "  codesum [flags] DIR...              summarize one or more directories")
+	fmt.Fprintln(out, "  codesum [flags] URL                 summarize a shallow clone of a git repository")
+	fmt.Fprintln(out, "  codesum [flags] -repo URL           the same as above")
+	fmt.Fprintln(out, "  codesum [flags] ARCHIVE             summarize a .zip, .tar, .tar.gz or .tgz archive")
+	fmt.Fprintln(out, "  codesum diff [--json] OLD NEW       compare two JSON summaries")
+	fmt.Fprintln(out, "  codesum doctor [flags] [DIR]        report why files are skipped and which settings apply")
+	fmt.Fprintln(out, "  codesum init [--force]              write a .codesumignore and a .codesum.toml for this project")
+	fmt.Fprintln(out, "  codesum serve [--addr :8080] [--root DIR] [--ttl 30s]")
+	fmt.Fprintln(out, "                                      serve summaries over HTTP")
+	fmt.Fprintln(out, "  codesum mcp [--root DIR] [--max-size 1M]")
+	fmt.Fprintln(out, "                                      serve the Model Context Protocol over stdio")
+	fmt.Fprintln(out, "  codesum completion bash|zsh|fish    print a shell completion script")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.CommandLine.PrintDefaults()
+}
 
-	project := ProjectInfo{
-		Name:       projectName,
-		Repository: repoName,
-		Files:      files,
-		Type:       projectType,
+// checkOutputSize returns an error if the output is larger than maxBytes or maxTokens, where 0 means no limit
+func checkOutputSize(size, maxBytes, maxTokens int64) error {
+	tokens := codesum.EstimateTokens(size)
+	if maxBytes > 0 && size > maxBytes {
+		return &exitError{code: exitOverLimit, err: fmt.Errorf("the output is %d bytes (about %d tokens), which is over the limit of %d bytes", size, tokens, maxBytes)}
 	}
+	if maxTokens > 0 && tokens > maxTokens {
+		return &exitError{code: exitOverLimit, err: fmt.Errorf("the output is about %d tokens (%d bytes), which is over the limit of %d tokens", tokens, size, maxTokens)}
+	}
+	return nil
+}
 
-	outputProjectInfo(project)
+// writeErrorReport writes the skipped files as a JSON array, which is empty if no files were skipped
+func writeErrorReport(filename string, fileErrors []codesum.FileError) error {
+	if fileErrors == nil {
+		fileErrors = []codesum.FileError{}
+	}
+	return writeOutput(filename, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fileErrors)
+	})
+}
+
+func main() {
+	command, args := run, os.Args[1:]
+	if len(args) > 0 {
+		if subcommand, ok := subcommands[args[0]]; ok {
+			command, args = subcommand, args[1:]
+		}
+	}
+	if err := command(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			if exitErr.err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", exitErr.err)
+			}
+			os.Exit(exitErr.code)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitFatal)
+	}
 }
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain runs main instead of the tests when the test binary is started by runCodesum
+func TestMain(m *testing.M) {
+	if os.Getenv("CODESUM_TEST_MAIN") == "1" {
+		os.Args = append([]string{"codesum"}, os.Args[1:]...)
+		main()
+		os.Exit(exitSuccess)
+	}
+	os.Exit(m.Run())
+}
+
+// codesumCommand returns a command that runs codesum with the given arguments in dir
+func codesumCommand(t *testing.T, dir string, args ...string) *exec.Cmd {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CODESUM_TEST_MAIN=1", "XDG_CONFIG_HOME="+t.TempDir())
+	return cmd
+}
+
+// exitCode returns the exit code of a command that has finished
+func exitCode(t *testing.T, err error) int {
+	t.Helper()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return exitSuccess
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	}
+	t.Fatal(err)
+	return 0
+}
+
+// writeProject writes a directory with one Go file
+func writeProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestExitCodes(t *testing.T) {
+	project := writeProject(t)
+	tests := []struct {
+		name string
+		dir  string
+		args []string
+		want int
+	}{
+		{"success", project, nil, exitSuccess},
+		{"invalid flag", project, []string{"-no-such-flag"}, exitFatal},
+		{"unwritable output", project, []string{"-o", filepath.Join(project, "missing", "summary.md")}, exitFatal},
+		{"no files", t.TempDir(), nil, exitNoFiles},
+		{"no files to list", t.TempDir(), []string{"-list"}, exitNoFiles},
+		{"over the limit", project, []string{"-fail-over", "10"}, exitOverLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			cmd := codesumCommand(t, tt.dir, tt.args...)
+			cmd.Stderr = &stderr
+			if got := exitCode(t, cmd.Run()); got != tt.want {
+				t.Errorf("got the exit code %d, want %d\nstderr:\n%s", got, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestExitCodeInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("an interrupt can not be sent to a process on Windows")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// The clone connects to a server that never answers, so it only ends when codesum is interrupted
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	connected := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			connected <- conn
+		}
+	}()
+
+	cmd := codesumCommand(t, t.TempDir(), "-quiet", "http://"+listener.Addr().String()+"/repo.git")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case conn := <-connected:
+		defer conn.Close()
+	case <-time.After(30 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("git did not connect")
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if got := exitCode(t, cmd.Wait()); got != exitInterrupted {
+		t.Errorf("got the exit code %d, want %d", got, exitInterrupted)
+	}
+}
+
+// printConfigValues runs codesum -print-config with the given user and project configuration files, environment
+// variables and arguments, and returns the effective value and source of each flag
+func printConfigValues(t *testing.T, userConfig, projectConfig string, env []string, args ...string) map[string][2]string {
+	t.Helper()
+	project, configHome := t.TempDir(), t.TempDir()
+	if userConfig != "" {
+		if err := os.MkdirAll(filepath.Join(configHome, "codesum"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(configHome, "codesum", "config.toml"), []byte(userConfig), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if projectConfig != "" {
+		if err := os.WriteFile(filepath.Join(project, projectConfigFile), []byte(projectConfig), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := codesumCommand(t, project, append([]string{"-print-config"}, args...)...)
+	// The last value of a variable wins, so these replace those of the environment of the test
+	cmd.Env = append(cmd.Env, "XDG_CONFIG_HOME="+configHome)
+	cmd.Env = append(cmd.Env, env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%v\nstderr:\n%s", err, stderr.String())
+	}
+	values := make(map[string][2]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		setting, source, _ := strings.Cut(line, " # ")
+		name, value, _ := strings.Cut(setting, " = ")
+		values[name] = [2]string{value, source}
+	}
+	return values
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	const (
+		user    = "max_per_lang = 1\nauthor = [\"alice\", \"bob\"]\n"
+		project = "max_per_lang = 2\nauthor = [\"carol\"]\n"
+	)
+	env := []string{"CODESUM_MAX_PER_LANG=3"}
+	tests := []struct {
+		name                   string
+		user, project          string
+		env                    []string
+		args                   []string
+		wantValue, wantAuthors string
+		wantSource             string
+	}{
+		{"default", "", "", nil, nil, "0", "", "default"},
+		{"user configuration", user, "", nil, nil, "1", "alice,bob", "config.toml"},
+		{"project configuration", user, project, nil, nil, "2", "carol", projectConfigFile},
+		{"environment variable", user, project, env, nil, "3", "carol", "environment variable CODESUM_MAX_PER_LANG"},
+		{"command line", user, project, env, []string{"-max-per-lang", "4", "-author", "dave"}, "4", "dave", sourceCommandLine},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := printConfigValues(t, tt.user, tt.project, tt.env, tt.args...)
+			got := values["max-per-lang"]
+			if got[0] != tt.wantValue || !strings.HasSuffix(got[1], tt.wantSource) {
+				t.Errorf("got max-per-lang = %s from %s, want %s from %s", got[0], got[1], tt.wantValue, tt.wantSource)
+			}
+			if authors := values["author"][0]; authors != tt.wantAuthors {
+				t.Errorf("got author = %s, want %s", authors, tt.wantAuthors)
+			}
+		})
+	}
+}
+
+func TestConfigValues(t *testing.T) {
+	array := []any{"alice", "bob"}
+	if got, err := configValues(array, true); err != nil || !slices.Equal(got, []string{"alice", "bob"}) {
+		t.Errorf("got %q, %v for a flag that can be repeated, want one value per element", got, err)
+	}
+	if got, err := configValues(array, false); err != nil || !slices.Equal(got, []string{"alice,bob"}) {
+		t.Errorf("got %q, %v for a flag that can not be repeated, want a comma-separated list", got, err)
+	}
+	if got, err := configValues(int64(20), false); err != nil || !slices.Equal(got, []string{"20"}) {
+		t.Errorf("got %q, %v for a number", got, err)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	project := writeProject(t)
+	if err := os.WriteFile(filepath.Join(project, "extra.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		env          []string
+		args         []string
+		json         bool
+		includeExtra bool
+	}{
+		{"no variables", nil, nil, false, true},
+		{"format", []string{"CODESUM_FORMAT=json"}, nil, true, true},
+		{"exclude", []string{"CODESUM_EXCLUDE=extra.go"}, nil, false, false},
+		{"both", []string{"CODESUM_FORMAT=json", "CODESUM_EXCLUDE=extra.go"}, nil, true, false},
+		{"format flag", []string{"CODESUM_FORMAT=json"}, []string{"-format", "markdown"}, false, true},
+		{"exclude flag", []string{"CODESUM_EXCLUDE=extra.go"}, []string{"-exclude", "main.go"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := codesumCommand(t, project, tt.args...)
+			cmd.Env = append(cmd.Env, tt.env...)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%v\nstderr:\n%s", err, stderr.String())
+			}
+			if isJSON := bytes.HasPrefix(out, []byte("{")); isJSON != tt.json {
+				t.Errorf("got JSON output %t, want %t:\n%s", isJSON, tt.json, out)
+			}
+			if includesExtra := bytes.Contains(out, []byte("extra.go")); includesExtra != tt.includeExtra {
+				t.Errorf("got extra.go in the output %t, want %t:\n%s", includesExtra, tt.includeExtra, out)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	project := writeProject(t)
+	snapshots := t.TempDir()
+	// writeSnapshot writes a JSON summary of the project
+	writeSnapshot := func(name string) string {
+		t.Helper()
+		filename := filepath.Join(snapshots, name)
+		cmd := codesumCommand(t, project, "-json", "-o", filename)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		return filename
+	}
+	writeFile := func(name, contents string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(project, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("removed.go", "package main\n\nfunc removed() {}\n")
+	oldSnapshot := writeSnapshot("old.json")
+	writeFile("main.go", "package main\n\nfunc main() {\n}\n")
+	writeFile("added.go", "package main\n")
+	if err := os.Remove(filepath.Join(project, "removed.go")); err != nil {
+		t.Fatal(err)
+	}
+	newSnapshot := writeSnapshot("new.json")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+		out  []string
+	}{
+		{"same", []string{"diff", oldSnapshot, oldSnapshot}, exitSuccess, []string{"No differences\n"}},
+		{"different", []string{"diff", oldSnapshot, newSnapshot}, 1, []string{
+			"  + added.go (Go, 1 line)\n",
+			"  - removed.go (Go, 3 lines)\n",
+			"  ~ main.go: +1 line, +1 byte\n",
+			"  Go: +0 files, -1 line, -18 bytes\n",
+		}},
+		{"json", []string{"diff", "--json", oldSnapshot, newSnapshot}, 1, []string{`"path": "added.go"`, `"line_delta": 1`}},
+		{"missing snapshot", []string{"diff", oldSnapshot, filepath.Join(snapshots, "missing.json")}, 2, nil},
+		{"one snapshot", []string{"diff", oldSnapshot}, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := codesumCommand(t, project, tt.args...)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if got := exitCode(t, err); got != tt.want {
+				t.Errorf("got the exit code %d, want %d\nstderr:\n%s", got, tt.want, stderr.String())
+			}
+			for _, want := range tt.out {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("the output does not contain %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestDirFlag(t *testing.T) {
+	project := writeProject(t)
+	if err := os.MkdirAll(filepath.Join(project, "cmd", "tool"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "cmd", "tool", "tool.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "go.mod"), []byte("module example.com/dirtest\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The ignore file is read from the directory, not from the working directory
+	if err := os.WriteFile(filepath.Join(project, ".gitignore"), []byte("ignored.go\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "ignored.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, flagName := range []string{"-dir", "--dir"} {
+		t.Run(flagName, func(t *testing.T) {
+			cmd := codesumCommand(t, t.TempDir(), "-json", flagName, project)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%v\nstderr:\n%s", err, stderr.String())
+			}
+			var summary struct {
+				Name  string `json:"name"`
+				Files []struct {
+					Path string `json:"path"`
+				} `json:"files"`
+			}
+			if err := json.Unmarshal(out, &summary); err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, file := range summary.Files {
+				paths = append(paths, file.Path)
+			}
+			if want := []string{"cmd/tool/tool.go", "main.go"}; !slices.Equal(paths, want) {
+				t.Errorf("got the paths %q, want %q", paths, want)
+			}
+			if summary.Name != "example.com/dirtest" {
+				t.Errorf("got the name %q, want the module of the go.mod in the directory", summary.Name)
+			}
+		})
+	}
+}
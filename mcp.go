@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// mcpDefaultMaxSize is the default size limit of the files that are read by the tools
+const mcpDefaultMaxSize = 1 << 20
+
+// mcpProtocolVersions are the Model Context Protocol versions that are supported, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification if it has no ID
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response, with either a result or an error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC 2.0 error
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// mcpTool describes a tool for tools/list
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// mcpContent is the text content of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpToolResult is the result of tools/call. Errors from the tool itself are results with IsError set,
+// so that the model can see them, while invalid calls are JSON-RPC errors.
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// mcpServer is a Model Context Protocol server that reads requests from in and writes responses to out,
+// one JSON message per line. Nothing else may be written to out. The tools can only read below root, which
+// is an absolute path without symbolic links, and the contents of files larger than maxSize are left out.
+type mcpServer struct {
+	in      io.Reader
+	out     io.Writer
+	logger  *slog.Logger
+	root    string
+	maxSize int64
+}
+
+// runMCP serves the Model Context Protocol over stdin and stdout, until stdin is closed or it is interrupted
+func runMCP(args []string) error {
+	fs := flag.NewFlagSet("codesum mcp", flag.ContinueOnError)
+	root := fs.String("root", ".", "Only let the tools read files below the given directory")
+	maxSize := byteSize(mcpDefaultMaxSize)
+	fs.Var(&maxSize, "max-size", "Leave out the contents of the files that are larger than the given size, like 500k or 2M (0 for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	absRoot, err := filepath.Abs(*root)
+	if err == nil {
+		absRoot, err = filepath.EvalSymlinks(absRoot)
+	}
+	if err != nil {
+		return err
+	}
+	if !isDirectory(absRoot) {
+		return fmt.Errorf("%s is not a directory", absRoot)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := &mcpServer{in: os.Stdin, out: os.Stdout, logger: newLogger(os.Stderr, slog.LevelWarn), root: absRoot, maxSize: int64(maxSize)}
+	return s.serve(ctx)
+}
+
+// serve handles requests until the input ends or ctx is canceled
+func (s *mcpServer) serve(ctx context.Context) error {
+	r := bufio.NewReader(s.in)
+	w := bufio.NewWriter(s.out)
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			line, err := r.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			response := s.handleMessage(ctx, line)
+			if response == nil {
+				continue
+			}
+			data, err := json.Marshal(response)
+			if err != nil {
+				return err
+			}
+			w.Write(data)
+			w.WriteByte('\n')
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleMessage handles one message and returns the response, or nil for notifications
+func (s *mcpServer) handleMessage(ctx context.Context, line []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "parse error: " + err.Error()}}
+	}
+	id := req.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return &rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{rpcInvalidRequest, "invalid request"}}
+	}
+	result, err := s.handle(ctx, req.Method, req.Params)
+	if req.ID == nil {
+		// Notifications get no response, not even for errors
+		if err != nil {
+			s.logger.Warn(fmt.Sprintf("MCP notification %s: %v", req.Method, err))
+		}
+		return nil
+	}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{rpcInvalidParams, err.Error()}
+		}
+		return &rpcResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+// handle calls the given method
+func (s *mcpServer) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		// Use the version of the client if it is supported, and the newest version if not
+		version := mcpProtocolVersions[0]
+		for _, v := range mcpProtocolVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "codesum", "version": readBuildInfo().Version},
+		}, nil
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": mcpTools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		call, ok := mcpToolFuncs[p.Name]
+		if !ok {
+			return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", p.Name)}
+		}
+		text, err := call(s, ctx, p.Arguments)
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		if err != nil {
+			return mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method not found: %s", method)}
+}
+
+// unmarshalParams unmarshals the parameters into v, where missing parameters leave v as it is
+func unmarshalParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{rpcInvalidParams, "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// mcpLanguagesSchema is the schema of the languages argument
+var mcpLanguagesSchema = map[string]any{
+	"type":        "array",
+	"items":       map[string]any{"type": "string"},
+	"description": "Only include files of these languages, like Go or Python",
+}
+
+// mcpTools are the tools that are listed by tools/list
+var mcpTools = []mcpTool{
+	{
+		Name:        "summarize_project",
+		Description: "Summarize the source code of a project directory as Markdown, with the contents of each source file",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path":         map[string]any{"type": "string", "description": "The project directory, relative to the root of the server"},
+				"languages":    mcpLanguagesSchema,
+				"max_per_lang": map[string]any{"type": "integer", "minimum": 0, "description": "Include at most this many files per language"},
+				"outline_only": map[string]any{"type": "boolean", "description": "List the top-level declarations of Go and Python files instead of the file contents"},
+			},
+			"required": []string{"path"},
+		},
+	},
+	{
+		Name:        "list_files",
+		Description: "List the source files of a project directory, with their language, line count and size, as JSON",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path":      map[string]any{"type": "string", "description": "The project directory, relative to the root of the server"},
+				"languages": mcpLanguagesSchema,
+			},
+			"required": []string{"path"},
+		},
+	},
+	{
+		Name:        "get_file",
+		Description: "Read a file, or a range of its lines",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path":       map[string]any{"type": "string", "description": "The file, relative to the root of the server"},
+				"start_line": map[string]any{"type": "integer", "minimum": 1, "description": "The first line to return, counting from 1"},
+				"end_line":   map[string]any{"type": "integer", "minimum": 1, "description": "The last line to return"},
+			},
+			"required": []string{"path"},
+		},
+	},
+}
+
+// mcpToolFuncs implement the tools, returning the text of the result
+var mcpToolFuncs = map[string]func(s *mcpServer, ctx context.Context, arguments json.RawMessage) (string, error){
+	"summarize_project": (*mcpServer).summarizeProject,
+	"list_files":        (*mcpServer).listFiles,
+	"get_file":          (*mcpServer).getFile,
+}
+
+// mcpProjectArguments are the arguments of the tools that collect a project
+type mcpProjectArguments struct {
+	Path        string   `json:"path"`
+	Languages   []string `json:"languages"`
+	MaxPerLang  int      `json:"max_per_lang"`
+	OutlineOnly bool     `json:"outline_only"`
+}
+
+// parseToolArguments unmarshals the arguments of a tool call and checks that there is a path, which is then
+// resolved against the root
+func (s *mcpServer) parseToolArguments(arguments json.RawMessage, v any, path *string) error {
+	if err := unmarshalParams(arguments, v); err != nil {
+		return err
+	}
+	if *path == "" {
+		return &rpcError{rpcInvalidParams, "the path argument is required"}
+	}
+	resolved, err := s.resolve(*path)
+	if err != nil {
+		return err
+	}
+	*path = resolved
+	return nil
+}
+
+// resolve returns the path, which is relative to the root or absolute, as an absolute path below the root.
+// Symbolic links are followed first, so that a link can not lead outside of the root either.
+func (s *mcpServer) resolve(path string) (string, error) {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(s.root, abs)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the root directory", path)
+	}
+	return resolved, nil
+}
+
+func (s *mcpServer) summarizeProject(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args mcpProjectArguments
+	if err := s.parseToolArguments(arguments, &args, &args.Path); err != nil {
+		return "", err
+	}
+	project, err := codesum.Collect(ctx, args.Path,
+		codesum.WithLanguages(args.Languages...),
+		codesum.WithMaxPerLanguage(args.MaxPerLang),
+		codesum.WithMaxContentSize(s.maxSize),
+		codesum.WithOutline(args.OutlineOnly))
+	if err != nil {
+		return "", err
+	}
+	if args.OutlineOnly {
+		for i := range project.Files {
+			project.Files[i].Contents = ""
+		}
+	}
+	var buf bytes.Buffer
+	if err := codesum.WriteMarkdown(&buf, project, codesum.RenderOptions{OutlineOnly: args.OutlineOnly}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *mcpServer) listFiles(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args mcpProjectArguments
+	if err := s.parseToolArguments(arguments, &args, &args.Path); err != nil {
+		return "", err
+	}
+	project, err := codesum.Collect(ctx, args.Path,
+		codesum.WithLanguages(args.Languages...),
+		codesum.WithoutContents(true))
+	if err != nil {
+		return "", err
+	}
+	files := project.Files
+	if files == nil {
+		files = []codesum.FileInfo{}
+	}
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) getFile(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	if err := s.parseToolArguments(arguments, &args, &args.Path); err != nil {
+		return "", err
+	}
+	if args.StartLine < 0 || args.EndLine < 0 || (args.EndLine > 0 && args.EndLine < args.StartLine) {
+		return "", &rpcError{rpcInvalidParams, fmt.Sprintf("invalid line range %d-%d", args.StartLine, args.EndLine)}
+	}
+	data, err := s.readFile(args.Path)
+	if err != nil {
+		return "", err
+	}
+	if args.StartLine == 0 && args.EndLine == 0 {
+		return string(data), nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	start, end := args.StartLine, args.EndLine
+	if start < 1 {
+		start = 1
+	}
+	if end == 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		return "", nil
+	}
+	return strings.Join(lines[start-1:end], ""), nil
+}
+
+// readFile reads a regular file that is not larger than maxSize
+func (s *mcpServer) readFile(path string) ([]byte, error) {
+	// Named pipes and devices, like /dev/zero, would block or never end, so they are not opened
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if s.maxSize > 0 && info.Size() > s.maxSize {
+		return nil, fmt.Errorf("%s is %d bytes, and the limit is %d bytes", path, info.Size(), s.maxSize)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// The file may have grown since the stat
+	r := io.Reader(f)
+	if s.maxSize > 0 {
+		r = io.LimitReader(f, s.maxSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.maxSize > 0 && int64(len(data)) > s.maxSize {
+		return nil, fmt.Errorf("%s is larger than the limit of %d bytes", path, s.maxSize)
+	}
+	return data, nil
+}
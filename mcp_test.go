@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares the output with the golden file in testdata, or writes the golden file with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("the output differs from %s, run go test -update to update it\ngot:\n%s", golden, got)
+	}
+}
+
+// newTestMCPServer returns a server for the given root, with a size limit of 128 bytes
+func newTestMCPServer(t *testing.T, root string, in []byte, out *bytes.Buffer) *mcpServer {
+	t.Helper()
+	absRoot, err := filepath.Abs(root)
+	if err == nil {
+		absRoot, err = filepath.EvalSymlinks(absRoot)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mcpServer{in: bytes.NewReader(in), out: out, logger: newLogger(&bytes.Buffer{}, 0), root: absRoot, maxSize: 128}
+}
+
+// TestMCPSession replays the recorded requests in testdata/mcp/session.jsonl and compares the responses with
+// testdata/mcp/session.golden.jsonl, where the root directory is written as ROOT
+func TestMCPSession(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "mcp", "session.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s := newTestMCPServer(t, filepath.Join("testdata", "mcp", "project"), in, &out)
+	if err := s.serve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The root is escaped like in the JSON responses
+	root, _ := json.Marshal(s.root)
+	got := strings.ReplaceAll(out.String(), strings.Trim(string(root), `"`), "ROOT")
+	checkGolden(t, filepath.Join("mcp", "session.golden.jsonl"), []byte(got))
+}
+
+func TestMCPSymlinkOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symbolic links are not supported:", err)
+	}
+	s := newTestMCPServer(t, root, nil, nil)
+	if _, err := s.getFile(context.Background(), []byte(`{"path":"link.txt"}`)); err == nil || !strings.Contains(err.Error(), "outside of the root") {
+		t.Errorf("got the error %v, want an error about the path being outside of the root", err)
+	}
+	if _, err := s.getFile(context.Background(), []byte(`{"path":`+strconv.Quote(secret)+`}`)); err == nil || !strings.Contains(err.Error(), "outside of the root") {
+		t.Errorf("got the error %v for an absolute path, want an error about the path being outside of the root", err)
+	}
+}
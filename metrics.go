@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// metricsRecord is the line that -metrics-out appends to a JSON Lines file for each run
+type metricsRecord struct {
+	Timestamp string `json:"timestamp"`
+	// ContentHash is left out when several projects are summarized
+	ContentHash string                            `json:"content_hash,omitempty"`
+	Files       int                               `json:"files"`
+	Lines       int                               `json:"lines"`
+	Bytes       int64                             `json:"bytes"`
+	Languages   map[string]codesum.LanguageTotals `json:"languages"`
+	OutputBytes int64                             `json:"output_bytes"`
+}
+
+// newMetricsRecord sums up the totals of the projects, for a run that wrote outputBytes bytes of output
+func newMetricsRecord(now time.Time, projects []codesum.ProjectInfo, outputBytes int64) metricsRecord {
+	record := metricsRecord{
+		Timestamp:   now.Format(time.RFC3339),
+		Languages:   make(map[string]codesum.LanguageTotals),
+		OutputBytes: outputBytes,
+	}
+	if len(projects) == 1 {
+		record.ContentHash = projects[0].ContentHash
+	}
+	for _, project := range projects {
+		record.Files += project.Totals.Files
+		record.Lines += project.Totals.Lines
+		record.Bytes += project.Totals.Bytes
+		for language, totals := range project.Totals.Languages {
+			sum := record.Languages[language]
+			sum.Files += totals.Files
+			sum.Lines += totals.Lines
+			sum.Bytes += totals.Bytes
+			record.Languages[language] = sum
+		}
+	}
+	return record
+}
+
+// appendMetrics appends the record as one line to the given file, which is created if it does not exist
+func appendMetrics(filename string, record metricsRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeOutput calls render with either stdout or, if filename is given, a temporary file
+// that is renamed to filename only when rendering succeeded
+func writeOutput(filename string, render func(io.Writer) error) error {
+	if filename == "" {
+		return render(os.Stdout)
+	}
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	tempName := f.Name()
+	if err := render(f); err != nil {
+		f.Close()
+		os.Remove(tempName)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+	if err := os.Chmod(tempName, 0o644); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+	if err := os.Rename(tempName, filename); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+// excludePatterns returns exclude patterns for the given files that are in the root directory,
+// so that the output of one run is not collected by the next one
+func excludePatterns(root string, filenames ...string) []string {
+	escape := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, filename := range filenames {
+		if filename == "" {
+			continue
+		}
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absRoot, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		patterns = append(patterns, escape.Replace(filepath.ToSlash(rel)))
+	}
+	return patterns
+}
+
+// byteCounter is a writer that only counts the bytes that are written to it
+type byteCounter int64
+
+func (n *byteCounter) Write(p []byte) (int, error) {
+	*n += byteCounter(len(p))
+	return len(p), nil
+}
+
+// defaultMaxOutputBytes is the default of -max-output-bytes, which guards against writing without end,
+// like when summarizing a huge vendored checkout by mistake
+const defaultMaxOutputBytes = 1 << 30
+
+// limitWriter passes the writes on to w, but refuses any write that would make the output larger than limit bytes
+type limitWriter struct {
+	w        io.Writer
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.written+int64(len(p)) > l.limit {
+		l.exceeded = true
+		return 0, fmt.Errorf("the output would be larger than %d bytes", l.limit)
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// writeLimitedOutput is like writeOutput, but stops writing before the output gets larger than maxBytes (0 for no limit),
+// with an exitOverLimit error. A file is then not written at all, while a notice ends what was written to stdout.
+func writeLimitedOutput(filename string, maxBytes int64, render func(io.Writer) error) error {
+	if maxBytes <= 0 {
+		return writeOutput(filename, render)
+	}
+	var limited *limitWriter
+	err := writeOutput(filename, func(w io.Writer) error {
+		limited = &limitWriter{w: w, limit: maxBytes}
+		return render(limited)
+	})
+	if limited == nil || !limited.exceeded {
+		return err
+	}
+	if filename == "" {
+		fmt.Printf("\n\n[The output was cut off here, since it reached the limit of %d bytes given with -max-output-bytes]\n", maxBytes)
+	}
+	return &exitError{code: exitOverLimit, err: fmt.Errorf("stopped writing the output, since it would be larger than the limit of %d bytes given with -max-output-bytes", maxBytes)}
+}
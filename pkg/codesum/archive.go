@@ -0,0 +1,277 @@
+package codesum
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveExtensions are the extensions of the archives that can be collected, longest first
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// maxArchiveRootFileSize is the size limit for keeping files without a recognized extension from a tar archive in memory
+const maxArchiveRootFileSize = 1 << 20
+
+// IsArchive checks if the filename has the extension of an archive that CollectArchive can read
+func IsArchive(filename string) bool {
+	return archiveExtension(filename) != ""
+}
+
+// archiveExtension returns the archive extension of the filename, or an empty string
+func archiveExtension(filename string) string {
+	lower := strings.ToLower(filename)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// CollectArchive collects the files in a .zip, .tar, .tar.gz or .tgz archive, without extracting it to disk.
+// When all entries are in one top-level directory, like in the source archives of releases, that directory is the root.
+// Archives with entries outside of the root, like "../x", are rejected. Git metadata is not available.
+func CollectArchive(ctx context.Context, filename string, opts ...Option) (ProjectInfo, error) {
+	var (
+		fsys fs.FS
+		err  error
+	)
+	switch archiveExtension(filename) {
+	case ".zip":
+		r, err := zip.OpenReader(filename)
+		if err != nil {
+			return ProjectInfo{}, err
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			if err := checkArchivePath(f.Name); err != nil {
+				return ProjectInfo{}, fmt.Errorf("%s: %w", filename, err)
+			}
+		}
+		fsys = r
+	case ".tar", ".tar.gz", ".tgz":
+		if fsys, err = readTar(filename); err != nil {
+			return ProjectInfo{}, fmt.Errorf("%s: %w", filename, err)
+		}
+	default:
+		return ProjectInfo{}, fmt.Errorf("%s is not a .zip, .tar, .tar.gz or .tgz archive", filename)
+	}
+
+	base := filepath.Base(filename)
+	name := base[:len(base)-len(archiveExtension(filename))]
+	if dir, ok := singleTopLevelDir(fsys); ok {
+		if fsys, err = fs.Sub(fsys, dir); err != nil {
+			return ProjectInfo{}, err
+		}
+		name = dir
+	}
+	opts = append(opts, func(o *Options) error {
+		o.name = name
+		return nil
+	})
+	return CollectFS(ctx, fsys, opts...)
+}
+
+// checkArchivePath returns an error for entry names that are absolute or point outside of the archive
+func checkArchivePath(name string) error {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("the entry %q is outside of the archive", name)
+	}
+	return nil
+}
+
+// singleTopLevelDir returns the only entry in the root of fsys, if it is a directory
+func singleTopLevelDir(fsys fs.FS) (string, bool) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return "", false
+	}
+	return entries[0].Name(), true
+}
+
+// archiveEntry is a file or directory in a tar archive
+type archiveEntry struct {
+	name     string // the base name
+	mode     fs.FileMode
+	size     int64
+	modTime  time.Time
+	data     []byte
+	loaded   bool
+	children map[string]*archiveEntry
+}
+
+func (e *archiveEntry) Name() string       { return e.name }
+func (e *archiveEntry) Size() int64        { return e.size }
+func (e *archiveEntry) Mode() fs.FileMode  { return e.mode }
+func (e *archiveEntry) ModTime() time.Time { return e.modTime }
+func (e *archiveEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *archiveEntry) Sys() any           { return nil }
+
+// tarFS is an fs.FS of the entries of a tar archive. The archive is read once, and only the contents of the
+// files that can be collected, and of small files that may be configuration files, are kept in memory.
+type tarFS struct {
+	root *archiveEntry
+}
+
+// readTar reads the entries of a tar archive, which is gzip compressed if the filename ends with .gz or .tgz
+func readTar(filename string) (*tarFS, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if ext := archiveExtension(filename); ext == ".tar.gz" || ext == ".tgz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	fsys := &tarFS{root: &archiveEntry{name: ".", mode: fs.ModeDir | 0o755, children: make(map[string]*archiveEntry)}}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkArchivePath(hdr.Name); err != nil {
+			return nil, err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dir := fsys.mkdirAll(name)
+			dir.modTime = hdr.ModTime
+		case tar.TypeReg:
+			entry := &archiveEntry{name: path.Base(name), mode: fs.FileMode(hdr.Mode).Perm(), size: hdr.Size, modTime: hdr.ModTime}
+			if recognizedExtension(name) || hdr.Size <= maxArchiveRootFileSize && strings.Count(name, "/") <= 1 {
+				if entry.data, err = io.ReadAll(tr); err != nil {
+					return nil, err
+				}
+				entry.loaded = true
+			}
+			fsys.mkdirAll(path.Dir(name)).children[entry.name] = entry
+		default:
+			// Links and special files are skipped
+		}
+	}
+}
+
+// mkdirAll returns the directory with the given name, creating it and its parents if needed
+func (fsys *tarFS) mkdirAll(name string) *archiveEntry {
+	dir := fsys.root
+	if name == "." {
+		return dir
+	}
+	for _, element := range strings.Split(name, "/") {
+		child, ok := dir.children[element]
+		if !ok || !child.IsDir() {
+			child = &archiveEntry{name: element, mode: fs.ModeDir | 0o755}
+			if dir.children == nil {
+				dir.children = make(map[string]*archiveEntry)
+			}
+			dir.children[element] = child
+		}
+		if child.children == nil {
+			child.children = make(map[string]*archiveEntry)
+		}
+		dir = child
+	}
+	return dir
+}
+
+// lookup returns the entry with the given name
+func (fsys *tarFS) lookup(name string) (*archiveEntry, bool) {
+	entry := fsys.root
+	if name == "." {
+		return entry, true
+	}
+	for _, element := range strings.Split(name, "/") {
+		child, ok := entry.children[element]
+		if !ok {
+			return nil, false
+		}
+		entry = child
+	}
+	return entry, true
+}
+
+func (fsys *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := fsys.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if !entry.IsDir() && !entry.loaded {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("the contents were not kept in memory, since the file is not collected")}
+	}
+	return &archiveFile{entry: entry, reader: bytes.NewReader(entry.data)}, nil
+}
+
+// archiveFile is an open file or directory in a tarFS
+type archiveFile struct {
+	entry   *archiveEntry
+	reader  *bytes.Reader
+	entries []fs.DirEntry // the directory entries that have not been read yet
+	listed  bool
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *archiveFile) Close() error               { return nil }
+
+func (f *archiveFile) Read(p []byte) (int, error) {
+	if f.entry.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.entry.name, Err: errors.New("is a directory")}
+	}
+	return f.reader.Read(p)
+}
+
+// ReadDir returns the entries of a directory, sorted by name
+func (f *archiveFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.entry.name, Err: errors.New("not a directory")}
+	}
+	if !f.listed {
+		for _, child := range f.entry.children {
+			f.entries = append(f.entries, fs.FileInfoToDirEntry(child))
+		}
+		sort.Slice(f.entries, func(i, j int) bool { return f.entries[i].Name() < f.entries[j].Name() })
+		f.listed = true
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package codesum
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Asset is a file that is not source code, like an image or a model file, that is listed without its
+// contents, see WithAssets
+type Asset struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Type is the kind of file, as detected from the extension, like "image" or "archive"
+	Type string `json:"type"`
+	// EmbeddedBy is the Go file with the //go:embed directive that refers to the file, see WithGoEmbed
+	EmbeddedBy string `json:"embedded_by,omitempty"`
+}
+
+// assetTypes are the kinds of assets, by extension. Other files are "other".
+var assetTypes = map[string]string{
+	".png": "image", ".jpg": "image", ".jpeg": "image", ".gif": "image", ".svg": "image", ".webp": "image",
+	".ico": "image", ".bmp": "image", ".tif": "image", ".tiff": "image",
+	".ttf": "font", ".otf": "font", ".woff": "font", ".woff2": "font",
+	".wav": "audio", ".mp3": "audio", ".ogg": "audio", ".flac": "audio",
+	".mp4": "video", ".webm": "video", ".mov": "video", ".avi": "video",
+	".zip": "archive", ".tar": "archive", ".gz": "archive", ".tgz": "archive", ".xz": "archive", ".bz2": "archive",
+	".zst": "archive", ".7z": "archive", ".jar": "archive",
+	".pdf": "document", ".doc": "document", ".docx": "document", ".odt": "document",
+	".csv": "data", ".tsv": "data", ".json": "data", ".yaml": "data", ".yml": "data", ".toml": "data",
+	".xml": "data", ".sql": "data", ".sqlite": "data", ".db": "data", ".parquet": "data",
+	".onnx": "model", ".pt": "model", ".pth": "model", ".safetensors": "model", ".gguf": "model", ".h5": "model",
+	".pb": "model", ".tflite": "model",
+	".exe": "binary", ".dll": "binary", ".so": "binary", ".dylib": "binary", ".a": "binary", ".o": "binary",
+	".bin": "binary", ".wasm": "binary", ".class": "binary", ".pyc": "binary",
+}
+
+// assetType returns the kind of the file at the given path, from its extension
+func assetType(name string) string {
+	if kind, ok := assetTypes[strings.ToLower(path.Ext(name))]; ok {
+		return kind
+	}
+	return "other"
+}
+
+// noExtension is the group of the assets without an extension, when grouping by extension
+const noExtension = "(no extension)"
+
+// assetGroup is the number and the total size of the assets with one extension
+type assetGroup struct {
+	extension string
+	files     int
+	bytes     int64
+}
+
+// describe describes the group, like "12 files, 3.4MB"
+func (g assetGroup) describe() string {
+	return fmt.Sprintf("%s, %s", countFiles(g.files), formatSize(g.bytes))
+}
+
+// groupAssets groups the assets by their lowercase extension, sorted by extension
+func groupAssets(assets []Asset) []assetGroup {
+	groups := make(map[string]*assetGroup)
+	for _, asset := range assets {
+		ext := strings.ToLower(path.Ext(asset.Path))
+		if ext == "" || ext == path.Base(asset.Path) {
+			// Files like .gitignore have no extension, only a name
+			ext = noExtension
+		}
+		group, ok := groups[ext]
+		if !ok {
+			group = &assetGroup{extension: ext}
+			groups[ext] = group
+		}
+		group.files++
+		group.bytes += asset.Size
+	}
+	sorted := make([]assetGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].extension < sorted[j].extension })
+	return sorted
+}
@@ -0,0 +1,71 @@
+package codesum
+
+import (
+	"strings"
+)
+
+// AuthorMode selects which commits of a file WithAuthors looks at
+type AuthorMode string
+
+const (
+	// AuthorLast keeps the files where the last commit is by one of the authors
+	AuthorLast AuthorMode = "last"
+	// AuthorAnyRecent keeps the files where one of the last Options.AuthorCommits commits is by one of the authors
+	AuthorAnyRecent AuthorMode = "any-recent"
+)
+
+// DefaultAuthorCommits is the number of recent commits of each file that AuthorAnyRecent looks at
+const DefaultAuthorCommits = 5
+
+// AuthorModes are the valid author modes
+var AuthorModes = []AuthorMode{AuthorLast, AuthorAnyRecent}
+
+// valid checks if the mode is one of AuthorModes
+func (mode AuthorMode) valid() bool {
+	for _, m := range AuthorModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// joinAuthorModes returns the valid author modes, separated by sep
+func joinAuthorModes(sep string) string {
+	names := make([]string, len(AuthorModes))
+	for i, mode := range AuthorModes {
+		names[i] = string(mode)
+	}
+	return strings.Join(names, sep)
+}
+
+// matchesAuthor checks if the author of a commit is one of the given authors, which match if they are a part
+// of "Name <email>", ignoring case, so that both an email address and a part of a name match
+func matchesAuthor(info GitInfo, authors []string) bool {
+	author := strings.ToLower(info.Author + " <" + info.Email + ">")
+	for _, a := range authors {
+		if strings.Contains(author, strings.ToLower(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByAuthor returns the files where the last commit, or one of the recent commits for AuthorAnyRecent, is by
+// one of the authors. history has the recent commits of each path, newest first.
+func filterByAuthor(files []FileInfo, history map[string][]GitInfo, authors []string, mode AuthorMode) []FileInfo {
+	var kept []FileInfo
+	for _, file := range files {
+		commits := history[file.Path]
+		if mode != AuthorAnyRecent && len(commits) > 1 {
+			commits = commits[:1]
+		}
+		for _, info := range commits {
+			if matchesAuthor(info, authors) {
+				kept = append(kept, file)
+				break
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,46 @@
+package codesum
+
+import "sort"
+
+// AgainstBaseline returns the project with only the files that differ from the baseline, which is the same
+// project collected from another directory, like an older copy. The paths are compared as they are, so both should
+// be relative to their own directory. Modified files have FileInfo.Diff set to a unified diff from the baseline,
+// instead of the contents, while added files keep their contents. FileInfo.Status is set for each remaining file,
+// ProjectInfo.Removed lists the files that are only in the baseline, and the totals are recomputed.
+func AgainstBaseline(project, baseline ProjectInfo) ProjectInfo {
+	baseFiles := make(map[string]FileInfo, len(baseline.Files))
+	for _, file := range baseline.Files {
+		baseFiles[file.Path] = file
+	}
+	var files []FileInfo
+	current := make(map[string]bool, len(project.Files))
+	for _, file := range project.Files {
+		current[file.Path] = true
+		base, existed := baseFiles[file.Path]
+		switch {
+		case !existed:
+			file.Status = "added"
+		case base.Contents == file.Contents:
+			continue
+		case file.ContentEncoding != "":
+			// A diff of the encoded contents of a binary file says nothing, so the contents are kept
+			file.Status = "modified"
+		default:
+			file.Status = "modified"
+			file.Diff = unifiedDiff("a/"+file.Path, "b/"+file.Path, base.Contents, file.Contents)
+			file.Contents = ""
+		}
+		files = append(files, file)
+	}
+	var removed []string
+	for path := range baseFiles {
+		if !current[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+
+	project.Files = files
+	project.Removed = removed
+	return withTotals(project)
+}
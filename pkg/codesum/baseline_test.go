@@ -0,0 +1,53 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAgainstBaseline(t *testing.T) {
+	file := func(contents string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(contents), Mode: 0o644, ModTime: fixtureTime}
+	}
+	baselineFS := fstest.MapFS{
+		"main.go":    file("package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"),
+		"same.go":    file("package main\n\nconst same = 1\n"),
+		"removed.go": file("package main\n\nfunc removed() {}\n"),
+	}
+	currentFS := fstest.MapFS{
+		"main.go":  file("package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n"),
+		"same.go":  file("package main\n\nconst same = 1\n"),
+		"added.go": file("package main\n\nfunc added() {}\n"),
+	}
+	baseline, err := CollectFS(context.Background(), baselineFS, WithTime(fixtureTime), WithName("baseline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := CollectFS(context.Background(), currentFS, WithTime(fixtureTime), WithName("current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := AgainstBaseline(current, baseline)
+
+	statuses := make(map[string]string)
+	for _, file := range project.Files {
+		statuses[file.Path] = file.Status
+	}
+	if len(statuses) != 2 || statuses["added.go"] != "added" || statuses["main.go"] != "modified" {
+		t.Errorf("got the statuses %v, want added.go added and main.go modified", statuses)
+	}
+	if !slices.Equal(project.Removed, []string{"removed.go"}) {
+		t.Errorf("got the removed files %q, want removed.go", project.Removed)
+	}
+	if project.Totals.Files != 2 {
+		t.Errorf("got %d files in the totals, want 2", project.Totals.Files)
+	}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "baseline.md", buf.Bytes())
+}
@@ -0,0 +1,58 @@
+package codesum
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// foldCase returns s with each rune replaced by the smallest rune that it is equal to under Unicode simple case
+// folding, so that strings that only differ in case are the same. Unlike with strings.ToLower, this includes
+// runes like the Kelvin sign, which folds to "k".
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		smallest := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			smallest = min(smallest, f)
+		}
+		return smallest
+	}, s)
+}
+
+// caseTracker finds the paths that only differ in case, which collide on case-insensitive file systems, like
+// on macOS and Windows. The paths are added in walk order, where directories come before their contents.
+type caseTracker struct {
+	// paths are the paths with each folded path, in the order they were added
+	paths map[string][]string
+}
+
+func newCaseTracker() *caseTracker {
+	return &caseTracker{paths: make(map[string][]string)}
+}
+
+// add adds a slash separated path. The contents of directories that collide are not added, since they would
+// collide too.
+func (t *caseTracker) add(name string) {
+	folded := foldCase(name)
+	for dir := path.Dir(folded); dir != "."; dir = path.Dir(dir) {
+		if len(t.paths[dir]) > 1 {
+			return
+		}
+	}
+	t.paths[folded] = append(t.paths[folded], name)
+}
+
+// collisions returns the groups of paths that only differ in case, sorted
+func (t *caseTracker) collisions() [][]string {
+	var groups [][]string
+	for _, paths := range t.paths {
+		if len(paths) > 1 {
+			sorted := append([]string(nil), paths...)
+			sort.Strings(sorted)
+			groups = append(groups, sorted)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
@@ -0,0 +1,804 @@
+// Package codesum collects the source files of a project, so that they can be
+// summarized as a single Markdown or JSON document.
+package codesum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// SchemaVersion is bumped whenever the JSON output changes shape
+const SchemaVersion = 2
+
+// DefaultMaxDepth is the default maximum directory depth below the root directory
+const DefaultMaxDepth = 256
+
+const legacyTimeLayout = "2006-01-02 15:04:05"
+
+// ErrTooDeep is returned when the directory tree is deeper than Options.MaxDepth
+var ErrTooDeep = errors.New("directory tree is too deep")
+
+// ErrCaseCollision is returned when paths only differ in case and Options.FailOnCaseCollision is set
+var ErrCaseCollision = errors.New("paths only differ in case")
+
+// ErrBinaryTooLarge is returned when a file that WithEmbedBinary asks for is larger than MaxEmbeddedBinarySize
+var ErrBinaryTooLarge = errors.New("binary file is too large to embed")
+
+type FileInfo struct {
+	Path                  string        `json:"path"`
+	Language              string        `json:"language"`
+	Role                  string        `json:"role,omitempty"`
+	Reason                string        `json:"reason,omitempty"`
+	LineCount             int           `json:"line_count,omitempty"`
+	LastModified          string        `json:"last_modified,omitempty"`
+	LastModifiedLegacy    string        `json:"last_modified_legacy,omitempty"`
+	Size                  int64         `json:"size,omitempty"`
+	Hash                  string        `json:"sha256,omitempty"`
+	Status                string        `json:"status,omitempty"`
+	Git                   *GitInfo      `json:"git,omitempty"`
+	Submodule             string        `json:"submodule,omitempty"`
+	Outline               []Declaration `json:"outline,omitempty"`
+	Imports               *ImportCounts `json:"imports,omitempty"`
+	Summary               string        `json:"summary,omitempty"`
+	Contents              string        `json:"contents,omitempty"`
+	Diff                  string        `json:"diff,omitempty"`
+	ContentEncoding       string        `json:"content_encoding,omitempty"`
+	Encoding              string        `json:"encoding,omitempty"`
+	MimeType              string        `json:"mime_type,omitempty"`
+	SignaturesOnly        bool          `json:"signatures_only,omitempty"`
+	SameFileAs            string        `json:"same_file_as,omitempty"`
+	LicenseHeaderStripped bool          `json:"license_header_stripped,omitempty"`
+	Truncated             bool          `json:"truncated,omitempty"`
+	ModTime               time.Time     `json:"-"`
+
+	// Extra is metadata that was added by enrichers
+	Extra map[string]any `json:"extra,omitempty"`
+}
+
+// Totals are aggregated over all files in the output
+type Totals struct {
+	Files     int                       `json:"files"`
+	Lines     int                       `json:"lines"`
+	Bytes     int64                     `json:"bytes"`
+	Languages map[string]LanguageTotals `json:"languages"`
+
+	// Distribution is how the line counts are distributed, see WithDistribution
+	Distribution *Distribution `json:"distribution,omitempty"`
+}
+
+type LanguageTotals struct {
+	Files int   `json:"files"`
+	Lines int   `json:"lines"`
+	Bytes int64 `json:"bytes"`
+}
+
+// FileError describes a file that was skipped because it could not be read
+type FileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+type ProjectInfo struct {
+	SchemaVersion int            `json:"schema_version"`
+	GeneratedAt   string         `json:"generated_at"`
+	Generator     string         `json:"generator,omitempty"`
+	Name          string         `json:"name"`
+	Repository    string         `json:"repository"`
+	Files         []FileInfo     `json:"files"`
+	Type          string         `json:"type"`
+	RunID         string         `json:"run_id,omitempty"`
+	ContentHash   string         `json:"content_hash"`
+	BuildSystem   string         `json:"build_system,omitempty"`
+	Totals        Totals         `json:"totals"`
+	Omitted       map[string]int `json:"omitted,omitempty"`
+	Removed       []string       `json:"removed,omitempty"`
+
+	// Changelog is the most recent commits, newest first, see WithChangelog
+	Changelog []ChangelogEntry `json:"changelog,omitempty"`
+
+	// OmittedByDirectory is the number of files per top-level directory that did not fit in Options.DirBudget
+	OmittedByDirectory map[string]int `json:"omitted_by_directory,omitempty"`
+
+	// DroppedLargest are the paths of the files that were dropped by Options.DropLargestPercent, largest first
+	DroppedLargest []string `json:"dropped_largest,omitempty"`
+
+	// Recent are the most recently modified files, newest first, see WithRecent
+	Recent []RecentFile `json:"recent,omitempty"`
+
+	// ExcludedLanguages is the number of files per language that were left out, since they are not in one of
+	// the most common languages, see WithTopLanguages
+	ExcludedLanguages map[string]int `json:"excluded_languages,omitempty"`
+
+	// OtherLanguageFiles is the number of files that were left out, since they are not in the dominant language,
+	// see WithDominantOnly
+	OtherLanguageFiles int `json:"other_language_files,omitempty"`
+
+	// Assets are the files that are not source code, without their contents, see WithAssets
+	Assets []Asset `json:"assets,omitempty"`
+	// EmbeddedAssets are the files that //go:embed directives refer to, which are binary or too large to be
+	// included, see WithGoEmbed
+	EmbeddedAssets []Asset `json:"embedded_assets,omitempty"`
+	// CaseCollisions are the groups of paths that only differ in case, which collide on case-insensitive file
+	// systems, like on macOS and Windows
+	CaseCollisions [][]string `json:"case_collisions,omitempty"`
+	// NearDuplicates are the pairs of files that are very similar, see WithNearDuplicates
+	NearDuplicates []NearDuplicate `json:"near_duplicates,omitempty"`
+
+	// ExternalDependencies are the external modules that the Go files import, see WithImports
+	ExternalDependencies []ExternalDependency `json:"external_dependencies,omitempty"`
+
+	// Tree is the directory tree of the files, see RenderOptions.Tree
+	Tree *TreeNode `json:"tree,omitempty"`
+
+	// Directories are the directories with a README.md file, see WithDirReadmes
+	Directories []Directory `json:"directories,omitempty"`
+
+	// Manifest is what was read from the manifest file, see WithManifestFile
+	Manifest *Manifest `json:"manifest,omitempty"`
+
+	// GeneratedTime is when the project was collected, see WithTime
+	GeneratedTime time.Time `json:"-"`
+	// NameSource is where Name came from: "the name option", the path of the manifest file, "go.mod",
+	// "the archive name" or "the directory name"
+	NameSource string `json:"-"`
+	// Warnings are non-fatal problems that were encountered while collecting
+	Warnings []string `json:"-"`
+	// Errors are the files that were skipped because they could not be read
+	Errors []FileError `json:"-"`
+	// EnrichErrors are the errors that enrichers returned, per file
+	EnrichErrors []FileError `json:"-"`
+}
+
+func sortFileErrors(fileErrors []FileError) {
+	sort.SliceStable(fileErrors, func(i, j int) bool {
+		return fileErrors[i].Path < fileErrors[j].Path
+	})
+}
+
+// Collect walks the given root directory and returns information about the project and its source files
+func Collect(ctx context.Context, root string, opts ...Option) (ProjectInfo, error) {
+	opts = append(opts, func(o *Options) error {
+		o.root = root
+		return nil
+	})
+	return CollectFS(ctx, rootFS(root), opts...)
+}
+
+// CollectFS walks the given file system and returns information about the project and its source files.
+// Git metadata can only be collected when called through Collect, since git needs a real directory.
+func CollectFS(ctx context.Context, fsys fs.FS, opts ...Option) (ProjectInfo, error) {
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	if o.Time.IsZero() {
+		o.Time = time.Now()
+	}
+
+	ignores := loadIgnorePatterns(fsys, !o.NoCommonIgnores, o.IgnoreFiles...)
+	o.Logger.Debug("loaded the ignore patterns", "files", o.IgnoreFiles, "patterns", len(ignores.rulesIn("")), "concurrency", o.Concurrency)
+
+	// The totals are added up while the files are read, and the files that are left out later are subtracted
+	totals := newTotalsAccumulator()
+	cases := newCaseTracker()
+	files, assets, fileErrors, warnings, err := walkDirectoryAndCollectFiles(ctx, fsys, ignores, o, totals, cases)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	caseCollisions := cases.collisions()
+	for _, paths := range caseCollisions {
+		if o.FailOnCaseCollision {
+			return ProjectInfo{}, fmt.Errorf("%w: %s", ErrCaseCollision, strings.Join(paths, ", "))
+		}
+		warnings = append(warnings, fmt.Sprintf("the paths %s only differ in case, so they collide on case-insensitive file systems, like on macOS and Windows", strings.Join(paths, ", ")))
+	}
+
+	if o.Untested {
+		kept := untested(files, filepath.Base(o.root), o)
+		totals.omit(o, files, kept, "")
+		files = kept
+	}
+
+	otherLanguageFiles := 0
+	if o.DominantOnly && len(o.Languages) > 0 {
+		warnings = append(warnings, "only including the files of the dominant language is ignored, since the languages are given")
+	} else if o.DominantOnly {
+		// The same language as the type of the project, so that the two agree
+		dominant := totals.result().dominantLanguage(o.TypeThreshold)
+		var kept []FileInfo
+		for _, file := range files {
+			if file.Language == dominant {
+				kept = append(kept, file)
+			}
+		}
+		totals.omit(o, files, kept, "omitting file, since it is not in the dominant language")
+		otherLanguageFiles = len(files) - len(kept)
+		files = kept
+	}
+
+	var excludedLanguages map[string]int
+	if current := totals.result(); o.TopLanguages > 0 && len(current.Languages) > o.TopLanguages {
+		excludedLanguages = make(map[string]int)
+		for _, language := range current.rankedLanguages()[o.TopLanguages:] {
+			excludedLanguages[language] = current.Languages[language].Files
+		}
+		var kept []FileInfo
+		for _, file := range files {
+			if _, excluded := excludedLanguages[file.Language]; !excluded {
+				kept = append(kept, file)
+			}
+		}
+		totals.omit(o, files, kept, "omitting file, since it is not in one of the most common languages")
+		files = kept
+	}
+
+	kept, droppedLargest := dropLargest(files, o.DropLargestPercent)
+	totals.omit(o, files, kept, "")
+	files = kept
+	for _, path := range droppedLargest {
+		o.Logger.Info("dropping one of the largest files", "path", path, "percent", o.DropLargestPercent)
+	}
+
+	var lastCommits map[string]GitInfo
+	if o.GitMetadata || o.Sort == SortGitRecency {
+		if o.root == "" {
+			if o.GitMetadata {
+				warnings = append(warnings, "git metadata is only available when collecting from a directory")
+			}
+		} else if lastCommits, err = readGitLog(ctx, o.root); err != nil {
+			if o.GitMetadata {
+				warnings = append(warnings, fmt.Sprintf("could not read the git metadata: %v", err))
+			} else {
+				// Not being in a git repository is common, so this is not a warning
+				o.Logger.Info("sorting by the modification times instead of by the commit times", "error", err)
+			}
+		}
+	}
+	if o.GitMetadata {
+		for i := range files {
+			if info, ok := lastCommits[files[i].Path]; ok {
+				info.Date = o.formatTimestamp(info.Time)
+				files[i].Git = &info
+			}
+		}
+	}
+	if len(o.Authors) > 0 {
+		if o.root == "" {
+			return ProjectInfo{}, errors.New("the files can only be filtered by author when collecting from a directory in a git repository")
+		}
+		commits := 1
+		if o.AuthorMode == AuthorAnyRecent {
+			commits = o.AuthorCommits
+		}
+		history, err := readGitHistory(ctx, o.root, commits)
+		if err != nil {
+			return ProjectInfo{}, fmt.Errorf("could not filter the files by author, since the git history could not be read: %w", err)
+		}
+		kept := filterByAuthor(files, history, o.Authors, o.AuthorMode)
+		totals.omit(o, files, kept, "omitting file, since it is not by one of the authors")
+		files = kept
+	}
+	// The files are read in parallel, so they are sorted once all of them are read, for the same order on every run
+	switch o.Sort {
+	case SortGitRecency:
+		sortByRecency(files, lastCommits)
+	default:
+		sortFiles(files, o.Sort)
+	}
+	if o.Reverse {
+		slices.Reverse(files)
+	}
+	if o.MaxFiles > 0 && len(files) > o.MaxFiles {
+		o.Logger.Info("omitting files over the maximum number of files", "files", len(files)-o.MaxFiles, "limit", o.MaxFiles)
+		totals.omit(o, files, files[:o.MaxFiles], "omitting file over the maximum number of files")
+		files = files[:o.MaxFiles]
+	}
+
+	var changelog []ChangelogEntry
+	if o.Changelog > 0 {
+		if o.root == "" {
+			warnings = append(warnings, "the changelog is only available when collecting from a directory")
+		} else if changelog, err = readChangelog(ctx, o.root, o.Changelog, o.ChangelogFiles); err != nil {
+			// Not being in a git repository is common, so this is not a warning
+			o.Logger.Info("leaving out the changelog", "error", err)
+		}
+		for i := range changelog {
+			changelog[i].Date = o.formatTimestamp(changelog[i].Time)
+		}
+	}
+
+	var manifest *Manifest
+	if o.ManifestFile != "" {
+		m, err := readManifest(fsys, o.ManifestFile)
+		if err != nil {
+			return ProjectInfo{}, fmt.Errorf("could not read the manifest file: %w", err)
+		}
+		manifest = &m
+	}
+
+	// Fetch project name from the manifest file or go.mod, if available and not given
+	projectName, nameSource := o.Name, "the name option"
+	if projectName == "" && manifest != nil {
+		if manifest.Name != "" {
+			projectName, nameSource = manifest.Name, manifest.Path
+		} else {
+			warnings = append(warnings, fmt.Sprintf("the manifest file %s has no project name", manifest.Path))
+		}
+	}
+	if projectName == "" {
+		if projectName, err = readProjectName(fsys, "go.mod"); err == nil {
+			nameSource = "go.mod"
+		} else {
+			warnings = append(warnings, fmt.Sprintf("could not discover the project name from 'go.mod': %v", err))
+			projectName, nameSource = o.name, "the archive name"
+			if projectName == "" {
+				projectName, nameSource = filepath.Base(o.root), "the directory name"
+			}
+		}
+	}
+
+	// Fetch repository name from .git/config, if available
+	repoName, err := readGitConfig(fsys, ".git/config")
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not read the repository details from '.git/config': %v", err))
+		repoName = "Unknown"
+	}
+
+	projectType := totals.result().projectType(o.TypeThreshold)
+
+	// The embedded files are added after the filters, which are about the source code, and do not affect the type
+	var embeddedAssets []Asset
+	if o.GoEmbed {
+		embedded, assets, embedErrors, embedWarnings := collectGoEmbeds(fsys, files, ignores, o)
+		for _, file := range embedded {
+			totals.add(file)
+		}
+		files = append(files, embedded...)
+		embeddedAssets = assets
+		fileErrors = append(fileErrors, embedErrors...)
+		warnings = append(warnings, embedWarnings...)
+	}
+
+	limited, omitted := limitPerLanguage(files, o.MaxPerLanguage)
+	for language, n := range omitted {
+		o.Logger.Info("omitting files over the limit per language", "language", language, "files", n)
+	}
+	totals.omit(o, files, limited, "omitting file over the limit per language")
+	files, limited = limited, nil
+	limited, omittedByDirectory := limitPerDirectory(files, o.DirBudget)
+	for dir, n := range omittedByDirectory {
+		o.Logger.Info("omitting files over the directory budget", "dir", dir, "files", n)
+	}
+	totals.omit(o, files, limited, "omitting file over the directory budget")
+	files = limited
+
+	var recent []RecentFile
+	if o.Recent > 0 {
+		commits := lastCommits
+		if !o.GitMetadata {
+			// The modification times are used, unless they can not be trusted, like right after a clone
+			if !modTimeTies(files) {
+				commits = nil
+			} else if commits == nil && o.root != "" {
+				if commits, err = readGitLog(ctx, o.root); err != nil {
+					o.Logger.Info("using the modification times for the recently modified files", "error", err)
+				}
+			}
+		}
+		recent = recentFiles(files, o.Recent, commits, o)
+	}
+
+	if o.StripLicenses {
+		o.Logger.Info("stripped the license headers", "files", stripLicenseHeaders(files))
+	}
+
+	var nearDuplicates []NearDuplicate
+	if o.NearDuplicates > 0 {
+		start := time.Now()
+		nearDuplicates = findNearDuplicates(files, o.NearDuplicates)
+		o.Logger.Info("compared the files", "near-duplicates", len(nearDuplicates), "duration", time.Since(start).Round(time.Millisecond))
+	}
+
+	start := time.Now()
+	enrichErrors, err := enrichFiles(ctx, files, o)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	if len(o.Enrichers) > 0 {
+		o.Logger.Info("enriched the files", "duration", time.Since(start).Round(time.Millisecond))
+	}
+
+	var directories []Directory
+	if o.DirReadmes {
+		directories = collectDirReadmes(files)
+	}
+
+	var dependencies []ExternalDependency
+	if o.Imports {
+		// Without a go.mod, no imports are internal, and the modules are guessed from the import paths
+		modFile := "go.mod"
+		if path.Base(o.ManifestFile) == "go.mod" {
+			modFile = o.ManifestFile
+		}
+		mod := goModule{}
+		mod.path, _ = readProjectName(fsys, modFile)
+		mod.requires, _ = readGoModRequires(fsys, modFile)
+		var importErrors []FileError
+		dependencies, importErrors = classifyImports(files, mod)
+		enrichErrors = append(enrichErrors, importErrors...)
+		sortFileErrors(enrichErrors)
+	}
+
+	runID, err := newRunID(o.RunID, files, o.Time)
+	if err != nil {
+		return ProjectInfo{}, fmt.Errorf("could not create a run ID: %w", err)
+	}
+	contentHash := ContentHash(files)
+	if !o.Hashes && !o.keepHashes {
+		// The hashes were only needed for the run ID and the content hash, or to check embedded binary files
+		for i := range files {
+			if files[i].ContentEncoding == "" {
+				files[i].Hash = ""
+			}
+		}
+	}
+
+	project := ProjectInfo{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   o.formatTimestamp(o.Time),
+		GeneratedTime: o.Time,
+		NameSource:    nameSource,
+		Name:          projectName,
+		Repository:    repoName,
+		Files:         files,
+		Type:          projectType,
+		RunID:         runID,
+		ContentHash:   contentHash,
+		BuildSystem:   detectBuildSystems(fsys),
+		Totals:        totals.result(),
+		Omitted:       omitted,
+		Changelog:     changelog,
+
+		OmittedByDirectory: omittedByDirectory,
+		DroppedLargest:     droppedLargest,
+		Recent:             recent,
+		Assets:             assets,
+		EmbeddedAssets:     embeddedAssets,
+		NearDuplicates:     nearDuplicates,
+		CaseCollisions:     caseCollisions,
+		OtherLanguageFiles: otherLanguageFiles,
+		ExcludedLanguages:  excludedLanguages,
+
+		ExternalDependencies: dependencies,
+		Manifest:             manifest,
+		Directories:          directories,
+
+		Warnings:     warnings,
+		Errors:       fileErrors,
+		EnrichErrors: enrichErrors,
+	}
+
+	if o.Distribution {
+		project.Totals.Distribution = computeDistribution(files)
+	}
+
+	// The paths are rebased last, since everything else works with paths relative to the root
+	if o.RelativeTo != "" || o.AbsolutePaths || o.prefix != "" {
+		if o.root == "" {
+			project.Warnings = append(project.Warnings, "relative and absolute paths are only available when collecting from a directory")
+		} else {
+			rebaser, err := newPathRebaser(o.root, o.RelativeTo, o.AbsolutePaths)
+			if err != nil {
+				return ProjectInfo{}, err
+			}
+			rebaser.prefix = o.prefix
+			project.Warnings = append(project.Warnings, rebaser.rebaseProject(&project)...)
+		}
+	}
+	return project, nil
+}
+
+// describeFileType describes the type of a file that is not a regular file, like "a named pipe"
+func describeFileType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "a directory"
+	case mode&fs.ModeNamedPipe != 0:
+		return "a named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "a socket"
+	case mode&fs.ModeDevice != 0:
+		return "a device"
+	}
+	return "an irregular file"
+}
+
+// walkDirectoryAndCollectFiles returns the collected files, the assets if Options.Assets is set, the files that
+// were skipped because of errors and any warnings. Each file that is read is added to totals, and the paths of the
+// directories, files and assets are added to cases.
+func walkDirectoryAndCollectFiles(ctx context.Context, fsys fs.FS, ignores *ignoreMatcher, o Options, totals *totalsAccumulator, cases *caseTracker) ([]FileInfo, []Asset, []FileError, []string, error) {
+	var warnings []string
+	var assets []Asset
+	submodules := readGitModules(fsys)
+	start := time.Now()
+
+	// Find the files to read, in walk order
+	var candidates []FileInfo
+	// The candidates that are the same file as an earlier candidate are not read, see FileInfo.SameFileAs
+	same := newSameFiles(fsys)
+	sameAs := make(map[int]int)
+	track := func(path string, d fs.DirEntry) {
+		if first, ok := same.add(path, d, len(candidates)-1); ok {
+			candidates[len(candidates)-1].SameFileAs = candidates[first].Path
+			sameAs[len(candidates)-1] = first
+		}
+	}
+	var dirErrors []FileError
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories that can not be read are skipped, unless in strict mode
+			if errors.Is(err, fs.ErrPermission) && !o.Strict && path != "." {
+				dirErrors = append(dirErrors, FileError{Path: path, Error: err.Error()})
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if reason, ok := reservedName(path); ok && path != "." {
+			// Opening the entry would open a device instead, so it is skipped like an entry that can not be read
+			_, ignored := ignores.match(path, d.IsDir())
+			if _, excluded := o.excluded(path); !ignored && !(excluded && !d.IsDir()) {
+				dirErrors = append(dirErrors, FileError{Path: path, Error: reason})
+			}
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if rule, ok := ignores.match(path, d.IsDir()); ok {
+			if d.IsDir() {
+				o.Logger.Info("skipping directory", "path", path, "ignore", rule.pattern, "ignore-file", rule.source)
+				return fs.SkipDir
+			}
+			o.Logger.Info("skipping file", "path", path, "ignore", rule.pattern, "ignore-file", rule.source)
+			return nil
+		}
+		if name, isSubmodule := submodules[path]; d.IsDir() && isSubmodule {
+			if !o.Submodules {
+				o.Logger.Info("skipping submodule", "path", path, "name", name)
+				return fs.SkipDir
+			}
+			if !submoduleInitialized(fsys, path) {
+				warnings = append(warnings, fmt.Sprintf("skipping submodule %s in %s, since it is not initialized", name, path))
+				return fs.SkipDir
+			}
+		}
+		if d.IsDir() && o.MaxDepth > 0 && path != "." {
+			if depth := strings.Count(path, "/") + 1; depth > o.MaxDepth {
+				return fmt.Errorf("%w: %s is %d levels deep, the limit is %d", ErrTooDeep, path, depth, o.MaxDepth)
+			}
+		}
+		if d.IsDir() && path != "." {
+			cases.add(path)
+		}
+		if !d.IsDir() {
+			if pattern, ok := o.excluded(path); ok {
+				o.Logger.Info("skipping file", "path", path, "exclude", pattern)
+				return nil
+			}
+			if role := o.role(path); !o.includesRole(role) {
+				o.Logger.Info("skipping file, since the role is not included", "path", path, "role", role)
+				return nil
+			}
+			if pattern, ok := o.embedsBinary(path); ok {
+				o.Logger.Debug("found file", "path", path, "embed-binary", pattern)
+				candidates = append(candidates, FileInfo{Path: path, Language: BinaryLanguage, Role: o.role(path), ContentEncoding: ContentEncodingBase64, Submodule: submoduleOf(path, submodules)})
+				track(path, d)
+				cases.add(path)
+				return nil
+			}
+			if reason, ok := o.included(path); !ok && o.recognizedExtension(path) {
+				o.Logger.Info("skipping file, since "+reason, "path", path)
+				return nil
+			}
+		}
+		if !d.IsDir() && !o.recognizedExtension(path) {
+			if !o.Assets || !d.Type().IsRegular() {
+				o.Logger.Debug("skipping file, since the extension is not recognized", "path", path)
+				return nil
+			}
+			// Only the size is needed, which the directory entry has, so the file is not read
+			info, err := d.Info()
+			if err != nil {
+				dirErrors = append(dirErrors, FileError{Path: path, Error: err.Error()})
+				return nil
+			}
+			o.Logger.Debug("found asset", "path", path)
+			assets = append(assets, Asset{Path: path, Size: info.Size(), Type: assetType(path)})
+			cases.add(path)
+		}
+		if language, ok := o.extensionLanguage(path); !d.IsDir() && ok {
+			switch {
+			case !o.includesLanguage(language):
+				o.Logger.Info("skipping file, since the language is not included", "path", path, "language", language)
+			default:
+				o.Logger.Debug("found file", "path", path, "language", language)
+				candidates = append(candidates, FileInfo{Path: path, Language: language, Role: o.role(path), Submodule: submoduleOf(path, submodules)})
+				track(path, d)
+				cases.add(path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	o.Logger.Info("walked the directory tree", "files", len(candidates), "duration", time.Since(start).Round(time.Millisecond))
+
+	// Read the files in parallel, each goroutine filling in its own slot to keep the walk order
+	files := make([]*FileInfo, len(candidates))
+	fileErrors := make([]*FileError, len(candidates))
+	// The entries that turn out not to be regular files when read are skipped with a warning
+	irregular := make([]string, len(candidates))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(o.Concurrency)
+	for i := range candidates {
+		i := i
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			file := candidates[i]
+			if file.SameFileAs != "" {
+				// Filled in from the first path of the file, once it has been read
+				return nil
+			}
+			fileInfo, err := fs.Stat(fsys, file.Path)
+			if err != nil {
+				fileErrors[i] = &FileError{Path: file.Path, Error: err.Error()}
+				return nil
+			}
+			// The entry may have changed since the walk, or be a named pipe or a device, which can not be read
+			// like a file, or which reading would block on. Symbolic links are followed by fs.Stat.
+			if !fileInfo.Mode().IsRegular() {
+				irregular[i] = fmt.Sprintf("skipping %s, since it is %s, not a regular file", file.Path, describeFileType(fileInfo.Mode()))
+				return nil
+			}
+			if file.ContentEncoding == ContentEncodingBase64 && fileInfo.Size() > MaxEmbeddedBinarySize {
+				// The file was asked for, so it is not skipped silently
+				return fmt.Errorf("%w: %s is %d bytes, and the limit is %d bytes", ErrBinaryTooLarge, file.Path, fileInfo.Size(), MaxEmbeddedBinarySize)
+			}
+			if o.MaxFileSize > 0 && fileInfo.Size() > o.MaxFileSize {
+				o.Logger.Info("skipping file, since it is too large", "path", file.Path, "size", fileInfo.Size(), "limit", o.MaxFileSize)
+				return nil
+			}
+			// The contents of larger files are left out, and the files are read like with SkipContents
+			truncated := o.MaxContentSize > 0 && fileInfo.Size() > o.MaxContentSize && file.ContentEncoding == ""
+			if truncated && !o.SkipContents {
+				o.Logger.Debug("leaving out the contents, since the file is too large", "path", file.Path, "size", fileInfo.Size(), "limit", o.MaxContentSize)
+				file.Truncated = true
+			}
+			switch {
+			case o.ListOnly:
+				if o.ListLineCounts && file.ContentEncoding == "" {
+					if file.LineCount, err = countLines(fsys, file.Path); err != nil {
+						fileErrors[i] = &FileError{Path: file.Path, Error: err.Error()}
+						return nil
+					}
+				}
+			case o.SkipContents || truncated:
+				if file.ContentEncoding == "" {
+					lineCount, err := countLines(fsys, file.Path)
+					if err != nil {
+						fileErrors[i] = &FileError{Path: file.Path, Error: err.Error()}
+						return nil
+					}
+					file.LineCount = lineCount
+				}
+				// The hashes are needed for the content hash
+				var head []byte
+				if file.Hash, head, err = hashFile(fsys, file.Path); err != nil {
+					fileErrors[i] = &FileError{Path: file.Path, Error: err.Error()}
+					return nil
+				}
+				file.MimeType = sniffMimeType(head, file.Path)
+			default:
+				content, err := fs.ReadFile(fsys, file.Path)
+				if err != nil {
+					fileErrors[i] = &FileError{Path: file.Path, Error: err.Error()}
+					return nil
+				}
+				// The hashes are of the files as they are, for the content hash
+				file.Hash = hashBytes(content)
+				file.MimeType = sniffMimeType(content[:min(len(content), sniffLength)], file.Path)
+				switch {
+				case file.ContentEncoding == ContentEncodingBase64:
+					file.Contents = encodeBinary(content)
+				default:
+					// Files in other encodings are transcoded to UTF-8, while the file itself is left as it is
+					// A file that sniffs as a binary format, like an image, is binary even if it could be decoded
+					text, encoding, ok := decodeText(content)
+					if !ok || sniffedBinary(file.MimeType) {
+						o.Logger.Info("skipping file, since it is binary", "path", file.Path, "mime-type", file.MimeType)
+						return nil
+					}
+					if encoding != "" {
+						o.Logger.Debug("transcoded file to UTF-8", "path", file.Path, "encoding", encoding)
+					}
+					// The lines are counted in the contents that were already read, and a strings.Reader does not fail
+					file.LineCount, _ = countLinesFrom(strings.NewReader(text))
+					file.Contents = text
+					file.Encoding = encoding
+					if o.TrimEdges {
+						file.Contents = trimBlankLines(file.Contents)
+					}
+				}
+			}
+
+			modTime := fileInfo.ModTime()
+			file.LastModified = o.formatTimestamp(modTime)
+			file.Size = fileInfo.Size()
+			file.ModTime = modTime
+			if o.LegacyTimestamps {
+				file.LastModifiedLegacy = modTime.Format(legacyTimeLayout)
+			}
+			files[i] = &file
+			totals.add(file)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for i, first := range sameAs {
+		if files[first] == nil {
+			// The file was skipped or could not be read, so the other paths of it are left out too
+			continue
+		}
+		file := *files[first]
+		file.Path, file.Role, file.Submodule, file.SameFileAs = candidates[i].Path, candidates[i].Role, candidates[i].Submodule, candidates[i].SameFileAs
+		file.Contents = ""
+		o.Logger.Debug("not reading file, since it is the same file as another path", "path", file.Path, "same-file-as", file.SameFileAs)
+		files[i] = &file
+		totals.add(file)
+	}
+	o.Logger.Info("read the files", "duration", time.Since(start).Round(time.Millisecond))
+
+	for _, warning := range irregular {
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	var collected []FileInfo
+	for _, file := range files {
+		if file != nil {
+			collected = append(collected, *file)
+		}
+	}
+	skipped := dirErrors
+	for _, fileError := range fileErrors {
+		if fileError != nil {
+			skipped = append(skipped, *fileError)
+		}
+	}
+	if o.Strict && len(skipped) > 0 {
+		return nil, nil, nil, nil, fmt.Errorf("could not read %s: %s", skipped[0].Path, skipped[0].Error)
+	}
+	return collected, assets, skipped, warnings, nil
+}
@@ -0,0 +1,292 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// openCounter is a file system that counts how often each file is opened, where fs.Stat and fs.ReadDir do not
+// open the files
+type openCounter struct {
+	fs.FS
+	mu     sync.Mutex
+	opened map[string]int
+}
+
+func (c *openCounter) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	c.opened[name]++
+	c.mu.Unlock()
+	return c.FS.Open(name)
+}
+
+func (c *openCounter) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(c.FS, name)
+}
+
+func (c *openCounter) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(c.FS, name)
+}
+
+func TestListOnly(t *testing.T) {
+	for _, lineCounts := range []bool{false, true} {
+		fsys := &openCounter{FS: fixtureFS(), opened: make(map[string]int)}
+		project, err := CollectFS(context.Background(), fsys, WithListOnly(true, lineCounts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(project.Files) != 3 {
+			t.Fatalf("got %d files, want 3", len(project.Files))
+		}
+		for _, file := range project.Files {
+			if file.Contents != "" || file.Hash != "" {
+				t.Errorf("%s has contents or a hash", file.Path)
+			}
+			if file.Size == 0 {
+				t.Errorf("%s has no size", file.Path)
+			}
+			want := 0
+			if lineCounts {
+				want = 1
+				if file.LineCount == 0 {
+					t.Errorf("%s has no line count", file.Path)
+				}
+			} else if file.LineCount != 0 {
+				t.Errorf("%s has %d lines, without counting them", file.Path, file.LineCount)
+			}
+			if got := fsys.opened[file.Path]; got != want {
+				t.Errorf("%s was opened %d times with the line counts %t, want %d", file.Path, got, lineCounts, want)
+			}
+		}
+	}
+}
+
+func TestDefaultOrder(t *testing.T) {
+	fsys := fixtureFS()
+	// The walk visits lib/util before lib/util.h, since the names in a directory are sorted
+	fsys["lib/util/extra.h"] = &fstest.MapFile{Data: []byte("#pragma once\n"), Mode: 0o644, ModTime: fixtureTime}
+	var first []byte
+	for i := 0; i < 10; i++ {
+		project, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime), WithConcurrency(8))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			var paths []string
+			for _, file := range project.Files {
+				paths = append(paths, file.Path)
+			}
+			want := []string{"greeting.go", "lib/util.h", "lib/util/extra.h", "main.go"}
+			if !slices.Equal(paths, want) {
+				t.Errorf("got the files %q, want %q", paths, want)
+			}
+		}
+		var buf bytes.Buffer
+		if err := WriteMarkdown(&buf, project, RenderOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = buf.Bytes()
+		} else if !bytes.Equal(buf.Bytes(), first) {
+			t.Fatalf("run %d differs from the first run:\n%s\nfirst run:\n%s", i+1, buf.Bytes(), first)
+		}
+	}
+}
+
+// syntheticFS returns a tree of dirs directories that are nested a few levels deep, with ten files in each,
+// in a few languages and of different sizes
+func syntheticFS(dirs int) fstest.MapFS {
+	extensions := []string{".go", ".py", ".js", ".c", ".md"}
+	fsys := make(fstest.MapFS)
+	for d := 0; d < dirs; d++ {
+		dir := fmt.Sprintf("pkg%d/sub%d/dir%d", d%10, d%7, d)
+		for f := 0; f < 10; f++ {
+			line := fmt.Sprintf("line %d of a file in %s\n", f, dir)
+			fsys[fmt.Sprintf("%s/file%d%s", dir, f, extensions[f%len(extensions)])] = &fstest.MapFile{
+				Data:    []byte(strings.Repeat(line, 20+(d*f)%200)),
+				Mode:    0o644,
+				ModTime: fixtureTime,
+			}
+		}
+	}
+	return fsys
+}
+
+func BenchmarkCollectFS(b *testing.B) {
+	fsys := syntheticFS(500)
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{"contents", nil},
+		{"no-contents", []Option{WithoutContents(true)}},
+		{"list", []Option{WithListOnly(true, false)}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				project, err := CollectFS(context.Background(), fsys, append([]Option{WithTime(fixtureTime)}, bm.opts...)...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(project.Files) != len(fsys) {
+					b.Fatalf("got %d files, want %d", len(project.Files), len(fsys))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTotals compares adding up the totals file by file, like the read goroutines do, with a second pass
+// over the files
+func BenchmarkTotals(b *testing.B) {
+	project, err := CollectFS(context.Background(), syntheticFS(500), WithoutContents(true))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("one-pass", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			totals := newTotalsAccumulator()
+			for _, file := range project.Files {
+				totals.add(file)
+			}
+			totals.result()
+		}
+	})
+	b.Run("two-pass", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			computeTotals(project.Files)
+		}
+	})
+}
+
+// deniedFS is a file system where the given directory can not be read
+type deniedFS struct {
+	fs.FS
+	dir string
+}
+
+func (d deniedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == d.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+	return fs.ReadDir(d.FS, name)
+}
+
+// checkPermissionDenied checks that the files outside of the directory that can not be read are collected, and that
+// the directory is reported, and that collecting fails with WithStrict
+func checkPermissionDenied(t *testing.T, collect func(...Option) (ProjectInfo, error), dir string) {
+	t.Helper()
+	project, err := collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range project.Files {
+		paths = append(paths, file.Path)
+	}
+	if !slices.Equal(paths, []string{"main.go", "public/public.go"}) {
+		t.Errorf("got the files %q, want main.go and public/public.go", paths)
+	}
+	if len(project.Errors) != 1 || project.Errors[0].Path != dir || !strings.Contains(project.Errors[0].Error, "permission denied") {
+		t.Errorf("got the errors %v, want one for %s", project.Errors, dir)
+	}
+	if _, err := collect(WithStrict(true)); err == nil || !strings.Contains(err.Error(), dir) {
+		t.Errorf("got the error %v with WithStrict, want one for %s", err, dir)
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	fsys := deniedFS{FS: fstest.MapFS{
+		"main.go":            {Data: []byte("package main\n")},
+		"private/private.go": {Data: []byte("package private\n")},
+		"public/public.go":   {Data: []byte("package public\n")},
+	}, dir: "private"}
+	checkPermissionDenied(t, func(opts ...Option) (ProjectInfo, error) {
+		return CollectFS(context.Background(), fsys, opts...)
+	}, "private")
+}
+
+func TestPermissionDeniedOnDisk(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the permissions of a directory can not be removed with chmod on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("root can read directories without permissions")
+	}
+	root := t.TempDir()
+	for name, contents := range map[string]string{
+		"main.go":            "package main\n",
+		"private/private.go": "package private\n",
+		"public/public.go":   "package public\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	private := filepath.Join(root, "private")
+	if err := os.Chmod(private, 0); err != nil {
+		t.Fatal(err)
+	}
+	// The directory is made readable again, so that it can be removed
+	t.Cleanup(func() { os.Chmod(private, 0o755) })
+	checkPermissionDenied(t, func(opts ...Option) (ProjectInfo, error) {
+		return Collect(context.Background(), root, opts...)
+	}, "private")
+}
+
+func TestMaxContentSizeBoundary(t *testing.T) {
+	const limit = 64
+	// 8 lines of 8 bytes each make a file of exactly the limit
+	line := "// 4567\n"
+	tests := []struct {
+		name      string
+		size      int
+		truncated bool
+		lines     int
+	}{
+		{"one byte under", limit - 1, false, 8}, // the last line has no newline
+		{"at the limit", limit, false, 8},
+		{"one byte over", limit + 1, true, 9}, // the last line is one byte
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := strings.Repeat(line, 9)[:tt.size]
+			fsys := fstest.MapFS{"file.go": {Data: []byte(contents)}}
+			project, err := CollectFS(context.Background(), fsys, WithMaxContentSize(limit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := project.Files[0]
+			if file.Truncated != tt.truncated {
+				t.Errorf("got Truncated %t for %d bytes, want %t", file.Truncated, tt.size, tt.truncated)
+			}
+			if file.LineCount != tt.lines {
+				t.Errorf("got %d lines, want %d", file.LineCount, tt.lines)
+			}
+			if file.Size != int64(tt.size) {
+				t.Errorf("got the size %d, want %d", file.Size, tt.size)
+			}
+			if tt.truncated && file.Contents != "" {
+				t.Errorf("got the contents %q for a truncated file, want none", file.Contents)
+			}
+			if !tt.truncated && file.Contents != contents {
+				t.Errorf("got the contents %q, want %q", file.Contents, contents)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package codesum
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ProjectDiff describes what changed between two summaries of a project
+type ProjectDiff struct {
+	OldType   string                        `json:"old_type,omitempty"`
+	NewType   string                        `json:"new_type,omitempty"`
+	Added     []FileInfo                    `json:"added"`
+	Removed   []FileInfo                    `json:"removed"`
+	Changed   []FileChange                  `json:"changed"`
+	Languages map[string]LanguageTotalsDiff `json:"languages,omitempty"`
+}
+
+// FileChange describes a file that is in both summaries, but differs
+type FileChange struct {
+	Path        string `json:"path"`
+	OldLanguage string `json:"old_language,omitempty"`
+	NewLanguage string `json:"new_language,omitempty"`
+	LineDelta   int    `json:"line_delta"`
+	SizeDelta   int64  `json:"size_delta"`
+	// Diff is a unified diff of the contents, if both summaries have the contents of the file
+	Diff string `json:"diff,omitempty"`
+}
+
+// LanguageTotalsDiff is how much the totals of a language changed
+type LanguageTotalsDiff struct {
+	Files int   `json:"files"`
+	Lines int   `json:"lines"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Empty checks if there are no differences
+func (d ProjectDiff) Empty() bool {
+	return d.OldType == d.NewType && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ReadProjectInfo reads a summary that was written by WriteJSON. Summaries with an older or newer
+// SchemaVersion can be read too, where fields that are not known are ignored and missing fields are left empty.
+func ReadProjectInfo(r io.Reader) (ProjectInfo, error) {
+	var project ProjectInfo
+	if err := json.NewDecoder(r).Decode(&project); err != nil {
+		return ProjectInfo{}, fmt.Errorf("could not parse the summary: %w", err)
+	}
+	return project, nil
+}
+
+// DiffProjects compares two summaries of a project. Files are compared by their SHA-256 hashes
+// when both summaries have them, then by their contents and then by their size and line count.
+func DiffProjects(oldProject, newProject ProjectInfo) ProjectDiff {
+	d := ProjectDiff{Added: []FileInfo{}, Removed: []FileInfo{}, Changed: []FileChange{}}
+	if oldProject.Type != newProject.Type {
+		d.OldType, d.NewType = oldProject.Type, newProject.Type
+	}
+	oldFiles := make(map[string]FileInfo, len(oldProject.Files))
+	for _, file := range oldProject.Files {
+		oldFiles[file.Path] = file
+	}
+	newFiles := make(map[string]bool, len(newProject.Files))
+	for _, file := range newProject.Files {
+		newFiles[file.Path] = true
+		oldFile, ok := oldFiles[file.Path]
+		if !ok {
+			file.Contents = "" // The delta describes the files, the new summary has their contents
+			d.Added = append(d.Added, file)
+			continue
+		}
+		if change, changed := diffFiles(oldFile, file); changed {
+			d.Changed = append(d.Changed, change)
+		}
+	}
+	for _, file := range oldProject.Files {
+		if !newFiles[file.Path] {
+			file.Contents = ""
+			d.Removed = append(d.Removed, file)
+		}
+	}
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].Path < d.Added[j].Path })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].Path < d.Removed[j].Path })
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Path < d.Changed[j].Path })
+
+	// Older summaries may not have totals, so they are computed from the files
+	oldTotals, newTotals := computeTotals(oldProject.Files), computeTotals(newProject.Files)
+	for language := range mergedKeys(oldTotals.Languages, newTotals.Languages) {
+		o, n := oldTotals.Languages[language], newTotals.Languages[language]
+		delta := LanguageTotalsDiff{Files: n.Files - o.Files, Lines: n.Lines - o.Lines, Bytes: n.Bytes - o.Bytes}
+		if delta != (LanguageTotalsDiff{}) {
+			if d.Languages == nil {
+				d.Languages = make(map[string]LanguageTotalsDiff)
+			}
+			d.Languages[language] = delta
+		}
+	}
+	return d
+}
+
+// diffFiles compares two versions of a file
+func diffFiles(oldFile, newFile FileInfo) (FileChange, bool) {
+	change := FileChange{
+		Path:      newFile.Path,
+		LineDelta: newFile.LineCount - oldFile.LineCount,
+		SizeDelta: newFile.Size - oldFile.Size,
+	}
+	if oldFile.Language != newFile.Language {
+		change.OldLanguage, change.NewLanguage = oldFile.Language, newFile.Language
+	}
+	hasContents := oldFile.Contents != "" && newFile.Contents != ""
+	var changed bool
+	switch {
+	case oldFile.Hash != "" && newFile.Hash != "":
+		changed = oldFile.Hash != newFile.Hash
+	case hasContents:
+		changed = oldFile.Contents != newFile.Contents
+	default:
+		changed = change.LineDelta != 0 || change.SizeDelta != 0
+	}
+	changed = changed || change.OldLanguage != change.NewLanguage
+	if changed && hasContents {
+		change.Diff = unifiedDiff("a/"+oldFile.Path, "b/"+newFile.Path, oldFile.Contents, newFile.Contents)
+	}
+	return change, changed
+}
+
+// mergedKeys returns the set of keys that are in any of the given maps
+func mergedKeys[V any](maps ...map[string]V) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for _, m := range maps {
+		for key := range m {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// WriteDiff writes the differences between two summaries as a human-readable report
+func WriteDiff(w io.Writer, d ProjectDiff) error {
+	bw := bufio.NewWriter(w)
+	if d.Empty() {
+		bw.WriteString("No differences\n")
+		return bw.Flush()
+	}
+	if d.OldType != d.NewType {
+		fmt.Fprintf(bw, "Main language: %s -> %s\n\n", d.OldType, d.NewType)
+	}
+	if len(d.Added) > 0 {
+		bw.WriteString("Added files:\n")
+		for _, file := range d.Added {
+			fmt.Fprintf(bw, "  + %s (%s, %s)\n", file.Path, file.Language, pluralize(file.LineCount, "line"))
+		}
+		bw.WriteString("\n")
+	}
+	if len(d.Removed) > 0 {
+		bw.WriteString("Removed files:\n")
+		for _, file := range d.Removed {
+			fmt.Fprintf(bw, "  - %s (%s, %s)\n", file.Path, file.Language, pluralize(file.LineCount, "line"))
+		}
+		bw.WriteString("\n")
+	}
+	if len(d.Changed) > 0 {
+		bw.WriteString("Changed files:\n")
+		for _, change := range d.Changed {
+			fmt.Fprintf(bw, "  ~ %s: %s, %s", change.Path, pluralizeDelta(change.LineDelta, "line"), pluralizeDelta(change.SizeDelta, "byte"))
+			if change.OldLanguage != change.NewLanguage {
+				fmt.Fprintf(bw, ", %s -> %s", change.OldLanguage, change.NewLanguage)
+			}
+			bw.WriteString("\n")
+		}
+		bw.WriteString("\n")
+	}
+	if len(d.Languages) > 0 {
+		bw.WriteString("Languages:\n")
+		for _, language := range sortedKeys(d.Languages) {
+			delta := d.Languages[language]
+			fmt.Fprintf(bw, "  %s: %s, %s, %s\n", language, pluralizeDelta(delta.Files, "file"), pluralizeDelta(delta.Lines, "line"), pluralizeDelta(delta.Bytes, "byte"))
+		}
+		bw.WriteString("\n")
+	}
+	for _, change := range d.Changed {
+		bw.WriteString(change.Diff)
+	}
+	return bw.Flush()
+}
+
+// WriteDiffJSON writes the differences between two summaries as an indented JSON document
+func WriteDiffJSON(w io.Writer, d ProjectDiff) error {
+	return writeIndentedJSON(w, d)
+}
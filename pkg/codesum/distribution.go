@@ -0,0 +1,88 @@
+package codesum
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lineCountBuckets are the upper bounds of the buckets of Distribution, which are fixed so that the
+// distributions of different projects and runs can be compared. The last bucket has no upper bound.
+var lineCountBuckets = []int{50, 200, 500, 1000}
+
+// Distribution is how the line counts of the files are distributed, see WithDistribution
+type Distribution struct {
+	Buckets   []LineCountBucket          `json:"buckets"`
+	Median    int                        `json:"median"`
+	P90       int                        `json:"p90"`
+	Languages map[string]LineCountSpread `json:"languages"`
+}
+
+// LineCountBucket is the number of files with a line count from Min to Max, where a Max of 0 means no upper bound
+type LineCountBucket struct {
+	Label string `json:"label"`
+	Min   int    `json:"min"`
+	Max   int    `json:"max,omitempty"`
+	Files int    `json:"files"`
+}
+
+// LineCountSpread is the median and the 90th percentile of the line counts of the files of one language
+type LineCountSpread struct {
+	Median int `json:"median"`
+	P90    int `json:"p90"`
+}
+
+// computeDistribution buckets the line counts of the files and finds the median and 90th percentile
+func computeDistribution(files []FileInfo) *Distribution {
+	d := &Distribution{Languages: make(map[string]LineCountSpread)}
+	low := 0
+	for _, high := range lineCountBuckets {
+		d.Buckets = append(d.Buckets, LineCountBucket{Label: fmt.Sprintf("%d-%d", low, high), Min: low, Max: high})
+		low = high + 1
+	}
+	d.Buckets = append(d.Buckets, LineCountBucket{Label: fmt.Sprintf("%d+", low), Min: low})
+
+	var all []int
+	byLanguage := make(map[string][]int)
+	for _, file := range files {
+		i := sort.SearchInts(lineCountBuckets, file.LineCount)
+		d.Buckets[i].Files++
+		all = append(all, file.LineCount)
+		byLanguage[file.Language] = append(byLanguage[file.Language], file.LineCount)
+	}
+	d.Median, d.P90 = percentile(all, 50), percentile(all, 90)
+	for lang, counts := range byLanguage {
+		d.Languages[lang] = LineCountSpread{Median: percentile(counts, 50), P90: percentile(counts, 90)}
+	}
+	return d
+}
+
+// percentile returns the nearest-rank percentile p of the values, which are sorted in place
+func percentile(values []int, p int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Ints(values)
+	rank := (p*len(values) + 99) / 100 // rounded up
+	return values[max(rank, 1)-1]
+}
+
+// distributionBarWidth is the width of the longest bar of the histogram
+const distributionBarWidth = 40
+
+// writeHistogram writes the buckets as aligned text bars, with indent in front of each line
+func writeHistogram(bw *bufio.Writer, d *Distribution, indent string) {
+	most, labelWidth := 0, 0
+	for _, bucket := range d.Buckets {
+		most = max(most, bucket.Files)
+		labelWidth = max(labelWidth, len(bucket.Label))
+	}
+	for _, bucket := range d.Buckets {
+		bar := ""
+		if bucket.Files > 0 {
+			bar = strings.Repeat("#", (bucket.Files*distributionBarWidth+most-1)/most) + " "
+		}
+		fmt.Fprintf(bw, "%s%*s lines | %s%d\n", indent, labelWidth, bucket.Label, bar, bucket.Files)
+	}
+}
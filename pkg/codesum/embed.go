@@ -0,0 +1,44 @@
+package codesum
+
+import (
+	"encoding/base64"
+	"path"
+	"strings"
+)
+
+// MaxEmbeddedBinarySize is the size limit of the binary files that are embedded with WithEmbedBinary
+const MaxEmbeddedBinarySize = 64 * 1024
+
+// ContentEncodingBase64 is the FileInfo.ContentEncoding of embedded binary files
+const ContentEncodingBase64 = "base64"
+
+// BinaryLanguage is the language of embedded binary files
+const BinaryLanguage = "Binary"
+
+// base64LineLength is the length of the lines of the encoded contents, as in MIME
+const base64LineLength = 76
+
+// embedsBinary returns the WithEmbedBinary pattern that the slash separated path matches, if any
+func (o Options) embedsBinary(filename string) (string, bool) {
+	for _, pattern := range o.EmbedBinary {
+		if matched, _ := path.Match(pattern, filename); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// encodeBinary encodes data as base64, in lines of base64LineLength characters that end with a newline.
+// Decoders like base64.StdEncoding ignore the newlines.
+func encodeBinary(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var sb strings.Builder
+	for len(encoded) > base64LineLength {
+		sb.WriteString(encoded[:base64LineLength] + "\n")
+		encoded = encoded[base64LineLength:]
+	}
+	if encoded != "" {
+		sb.WriteString(encoded + "\n")
+	}
+	return sb.String()
+}
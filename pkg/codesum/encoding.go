@@ -0,0 +1,87 @@
+package codesum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The encodings that FileInfo.Encoding can be, for files that were transcoded to UTF-8
+const (
+	EncodingISO88591    = "ISO-8859-1"
+	EncodingWindows1252 = "Windows-1252"
+	EncodingUTF16LE     = "UTF-16LE"
+	EncodingUTF16BE     = "UTF-16BE"
+)
+
+// maxControlRatio is the largest share of control characters that a file in a single-byte encoding may have.
+// Text has next to none, while binary files have plenty.
+const maxControlRatio = 0.01
+
+// windows1252 are the characters of the bytes 0x80 to 0x9f in Windows-1252, where the other bytes are the same
+// as in ISO-8859-1. The five bytes that are not defined are 0.
+var windows1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// decodeText returns the contents of a file as UTF-8, and the encoding it was transcoded from, which is empty for
+// UTF-8. UTF-16 is recognized by its byte order mark. Other contents that are not valid UTF-8 are read as
+// Windows-1252, or as ISO-8859-1 if none of the characters that only Windows-1252 has are used, unless there are
+// too many control characters for it to be text. The last return value is false if the contents are not text.
+func decodeText(content []byte) (string, string, bool) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xfe}):
+		return decodeUTF16(content[2:], binary.LittleEndian, EncodingUTF16LE)
+	case bytes.HasPrefix(content, []byte{0xfe, 0xff}):
+		return decodeUTF16(content[2:], binary.BigEndian, EncodingUTF16BE)
+	case utf8.Valid(content):
+		return string(content), "", true
+	}
+	encoding, controls := EncodingISO88591, 0
+	for _, b := range content {
+		switch {
+		case b == 0:
+			return "", "", false
+		case b >= 0x80 && b < 0xa0 && windows1252[b-0x80] != 0:
+			encoding = EncodingWindows1252
+		case b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f', b == 0x7f, b >= 0x80 && b < 0xa0:
+			controls++
+		}
+	}
+	if float64(controls) > maxControlRatio*float64(len(content)) {
+		return "", "", false
+	}
+	var sb strings.Builder
+	sb.Grow(len(content) + len(content)/8)
+	for _, b := range content {
+		switch {
+		case encoding == EncodingWindows1252 && b >= 0x80 && b < 0xa0 && windows1252[b-0x80] != 0:
+			sb.WriteRune(windows1252[b-0x80])
+		default:
+			sb.WriteRune(rune(b))
+		}
+	}
+	return sb.String(), encoding, true
+}
+
+// decodeUTF16 decodes UTF-16 without the byte order mark. An odd length or unpaired surrogates, which are decoded
+// as replacement characters, mean that it is not text.
+func decodeUTF16(content []byte, order binary.ByteOrder, encoding string) (string, string, bool) {
+	if len(content)%2 != 0 {
+		return "", "", false
+	}
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[2*i:])
+	}
+	runes := utf16.Decode(units)
+	for _, r := range runes {
+		if r == utf8.RuneError {
+			return "", "", false
+		}
+	}
+	return string(runes), encoding, true
+}
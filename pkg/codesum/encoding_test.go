@@ -0,0 +1,94 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodingFixtures are the files in testdata/encoding, with the encoding they are in. The UTF-8 that they are
+// transcoded to is in a file with the same name and .want, except for binary.c, which is not text.
+var encodingFixtures = []struct {
+	name     string
+	encoding string
+}{
+	{"latin1.c", EncodingISO88591},
+	{"cp1252.c", EncodingWindows1252},
+	{"utf16le.c", EncodingUTF16LE},
+	{"utf16be.c", EncodingUTF16BE},
+	{"utf8.c", ""},
+}
+
+func TestDecodeText(t *testing.T) {
+	for _, tt := range encodingFixtures {
+		content, err := os.ReadFile(filepath.Join("testdata", "encoding", tt.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(filepath.Join("testdata", "encoding", tt.name+".want"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, encoding, ok := decodeText(content)
+		if !ok {
+			t.Errorf("%s is not decoded as text", tt.name)
+			continue
+		}
+		if encoding != tt.encoding {
+			t.Errorf("%s is decoded as %q, want %q", tt.name, encoding, tt.encoding)
+		}
+		if text != string(want) {
+			t.Errorf("%s is decoded as %q, want %q", tt.name, text, want)
+		}
+	}
+	binary, err := os.ReadFile(filepath.Join("testdata", "encoding", "binary.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := decodeText(binary); ok {
+		t.Error("binary.c is decoded as text")
+	}
+}
+
+func TestCollectTranscodes(t *testing.T) {
+	root := filepath.Join("testdata", "encoding")
+	before, err := os.ReadFile(filepath.Join(root, "latin1.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := Collect(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]FileInfo)
+	for _, file := range project.Files {
+		files[file.Path] = file
+	}
+	if _, ok := files["binary.c"]; ok {
+		t.Error("binary.c is included")
+	}
+	for _, tt := range encodingFixtures {
+		file, ok := files[tt.name]
+		if !ok {
+			t.Errorf("%s is not included", tt.name)
+			continue
+		}
+		want, err := os.ReadFile(filepath.Join(root, tt.name+".want"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Encoding != tt.encoding || file.Contents != string(want) {
+			t.Errorf("%s has the encoding %q and the contents %q, want %q and %q", tt.name, file.Encoding, file.Contents, tt.encoding, want)
+		}
+	}
+	// The files themselves are left as they are
+	after, err := os.ReadFile(filepath.Join(root, "latin1.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("latin1.c was changed")
+	}
+}
@@ -0,0 +1,65 @@
+package codesum
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Enricher adds metadata to a collected file, typically by calling SetExtra.
+// Enrich is called concurrently for different files.
+type Enricher interface {
+	Enrich(ctx context.Context, f *FileInfo) error
+}
+
+// EnricherFunc is an ordinary function that can be used as an Enricher
+type EnricherFunc func(ctx context.Context, f *FileInfo) error
+
+func (fn EnricherFunc) Enrich(ctx context.Context, f *FileInfo) error {
+	return fn(ctx, f)
+}
+
+// SetExtra stores a value in the extra metadata of the file
+func (f *FileInfo) SetExtra(key string, value any) {
+	if f.Extra == nil {
+		f.Extra = make(map[string]any)
+	}
+	f.Extra[key] = value
+}
+
+// enrichFiles runs all enrichers on all files, using at most o.Concurrency goroutines.
+// Errors from the enrichers are returned per file instead of stopping the enrichment.
+func enrichFiles(ctx context.Context, files []FileInfo, o Options) ([]FileError, error) {
+	if len(o.Enrichers) == 0 {
+		return nil, nil
+	}
+	var (
+		mut        sync.Mutex
+		fileErrors []FileError
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(o.Concurrency)
+	for i := range files {
+		i := i
+		g.Go(func() error {
+			for _, enricher := range o.Enrichers {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := enricher.Enrich(ctx, &files[i]); err != nil {
+					mut.Lock()
+					fileErrors = append(fileErrors, FileError{Path: files[i].Path, Error: err.Error()})
+					mut.Unlock()
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	// The goroutines finish in any order
+	sortFileErrors(fileErrors)
+	return fileErrors, nil
+}
@@ -0,0 +1,45 @@
+package codesum
+
+import "io/fs"
+
+// fileID identifies a file on a file system, see fileIdentity
+type fileID struct {
+	device, index uint64
+}
+
+// sameFiles finds the paths that lead to the same file, like hard links and symbolic links, so that each file is
+// only read once
+type sameFiles struct {
+	fsys  fs.FS
+	first map[fileID]int
+}
+
+func newSameFiles(fsys fs.FS) *sameFiles {
+	return &sameFiles{fsys: fsys, first: make(map[fileID]int)}
+}
+
+// add adds the file at the given path, which is the candidate with the given index, and returns the index of the
+// first candidate that is the same file, if any. Symbolic links are followed.
+func (s *sameFiles) add(name string, d fs.DirEntry, index int) (int, bool) {
+	var (
+		info fs.FileInfo
+		err  error
+	)
+	if d.Type()&fs.ModeSymlink != 0 {
+		info, err = fs.Stat(s.fsys, name)
+	} else {
+		info, err = d.Info()
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	id, ok := fileIdentity(s.fsys, name, info)
+	if !ok {
+		return 0, false
+	}
+	if first, seen := s.first[id]; seen {
+		return first, true
+	}
+	s.first[id] = index
+	return 0, false
+}
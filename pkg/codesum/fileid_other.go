@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package codesum
+
+import "io/fs"
+
+// fileIdentity is not available on this platform, so every path is a file of its own
+func fileIdentity(fs.FS, string, fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
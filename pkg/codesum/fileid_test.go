@@ -0,0 +1,55 @@
+package codesum
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCollectHardLinks(t *testing.T) {
+	root := t.TempDir()
+	original := filepath.Join(root, "a.go")
+	if err := os.WriteFile(original, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(original, filepath.Join(root, "b.go")); err != nil {
+		t.Skip("hard links are not supported:", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "store"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(original, filepath.Join(root, "store", "c.go")); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &openCounter{FS: os.DirFS(root), opened: make(map[string]int)}
+	project, err := CollectFS(context.Background(), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Files) != 3 {
+		t.Fatalf("got %d files, want 3", len(project.Files))
+	}
+	for _, file := range project.Files {
+		if file.Path == "a.go" {
+			if file.SameFileAs != "" || file.Contents != "package a\n" {
+				t.Errorf("a.go is the same file as %q, with the contents %q", file.SameFileAs, file.Contents)
+			}
+			continue
+		}
+		if file.SameFileAs != "a.go" || file.Contents != "" {
+			t.Errorf("%s is the same file as %q, with the contents %q, want a.go without contents", file.Path, file.SameFileAs, file.Contents)
+		}
+		if file.LineCount != 1 || file.Size != int64(len("package a\n")) {
+			t.Errorf("%s has %d lines and %d bytes, want the line count and the size of a.go", file.Path, file.LineCount, file.Size)
+		}
+		// On Windows, each path is opened once to find the file index
+		if runtime.GOOS != "windows" && fsys.opened[file.Path] > 0 {
+			t.Errorf("%s was opened, even though it is the same file as a.go", file.Path)
+		}
+	}
+	if project.Totals.Files != 3 {
+		t.Errorf("the totals have %d files, want 3", project.Totals.Files)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package codesum
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of a file, which are the same for the paths of a file that is hard
+// linked, or reached through symbolic links
+func fileIdentity(_ fs.FS, _ string, info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{device: uint64(stat.Dev), index: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package codesum
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the volume serial number and the file index of a file, which are the same for the paths
+// of a file that is hard linked, or reached through symbolic links. The file is opened to get them, since the
+// directory entries do not have them.
+func fileIdentity(fsys fs.FS, name string, _ fs.FileInfo) (fileID, bool) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return fileID{}, false
+	}
+	defer f.Close()
+	osFile, ok := f.(*os.File)
+	if !ok {
+		return fileID{}, false
+	}
+	var data syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(osFile.Fd()), &data); err != nil {
+		return fileID{}, false
+	}
+	return fileID{device: uint64(data.VolumeSerialNumber), index: uint64(data.FileIndexHigh)<<32 | uint64(data.FileIndexLow)}, true
+}
@@ -0,0 +1,68 @@
+package codesum
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type gistFile struct {
+	Content string `json:"content"`
+}
+
+type gistPayload struct {
+	Description string              `json:"description"`
+	Public      bool                `json:"public"`
+	Files       map[string]gistFile `json:"files"`
+}
+
+// gistFilename flattens the path separators in a path, since gist filenames can not contain slashes
+func gistFilename(filePath string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(filePath)
+}
+
+// WriteGist writes the project as a JSON payload for the GitHub Gist API,
+// where each file is stored under a flattened filename
+func WriteGist(w io.Writer, project ProjectInfo, opts RenderOptions) error {
+	payload := gistPayload{
+		Description: project.Name,
+		Files:       make(map[string]gistFile, len(project.Files)),
+	}
+	for _, file := range project.Files {
+		name := gistFilename(file.Path)
+		if _, taken := payload.Files[name]; taken {
+			ext := path.Ext(name)
+			base := strings.TrimSuffix(name, ext)
+			for i := 2; ; i++ {
+				candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+				if _, taken := payload.Files[candidate]; !taken {
+					name = candidate
+					break
+				}
+			}
+		}
+		payload.Files[name] = gistFile{Content: gistContent(file)}
+	}
+	return writeIndentedJSON(w, payload)
+}
+
+// gistContent returns the contents of the file in the gist, or a one-line note when there are none, since the
+// Gist API rejects files with blank contents
+func gistContent(file FileInfo) string {
+	switch {
+	case file.Diff != "":
+		return file.Diff
+	case strings.TrimSpace(file.Contents) != "":
+		return file.Contents
+	case file.SameFileAs != "":
+		return fmt.Sprintf("(same file as %s)\n", file.SameFileAs)
+	case file.Truncated:
+		return fmt.Sprintf("(left out, since the file is %s)\n", formatSize(file.Size))
+	case file.Size == 0:
+		return "(empty)\n"
+	case file.Contents == "":
+		return "(contents left out)\n"
+	}
+	return "(blank)\n"
+}
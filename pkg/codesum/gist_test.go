@@ -0,0 +1,47 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGistNoBlankFiles(t *testing.T) {
+	fsys := fixtureFS()
+	fsys["empty.go"] = &fstest.MapFile{Mode: 0o644, ModTime: fixtureTime}
+	fsys["blank.go"] = &fstest.MapFile{Data: []byte("\n\n"), Mode: 0o644, ModTime: fixtureTime}
+	fsys["large.go"] = &fstest.MapFile{Data: []byte("package main\n\n" + strings.Repeat("// filler\n", 100)), Mode: 0o644, ModTime: fixtureTime}
+	project, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime), WithMaxContentSize(512))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteGist(&buf, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var payload gistPayload
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Files) != len(project.Files) {
+		t.Errorf("got %d files in the gist, want %d", len(payload.Files), len(project.Files))
+	}
+	for name, file := range payload.Files {
+		if strings.TrimSpace(file.Content) == "" {
+			t.Errorf("%s has blank contents", name)
+		}
+	}
+	want := map[string]string{
+		"empty.go": "(empty)\n",
+		"blank.go": "(blank)\n",
+		"large.go": "(left out, since the file is 1014B)\n",
+	}
+	for name, content := range want {
+		if got := payload.Files[name].Content; got != content {
+			t.Errorf("%s has the contents %q, want %q", name, got, content)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package codesum
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitInfo describes the last commit that touched a file
+type GitInfo struct {
+	Commit string    `json:"commit"`
+	Author string    `json:"author"`
+	Email  string    `json:"email"`
+	Date   string    `json:"date"`
+	Time   time.Time `json:"-"`
+}
+
+// ChangelogEntry is one of the most recent commits
+type ChangelogEntry struct {
+	GitInfo
+	Subject string   `json:"subject"`
+	Files   []string `json:"files,omitempty"`
+}
+
+// RepositoryRoot returns the top-level directory of the git repository that dir is in
+func RepositoryRoot(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git rev-parse: %s", msg)
+		}
+		return "", fmt.Errorf("git rev-parse: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runGitLog runs git log in the given directory, with the given extra arguments
+func runGitLog(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	args = append([]string{"-c", "core.quotepath=off", "log"}, args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git log: %s", msg)
+		}
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return out, nil
+}
+
+// readGitLog runs git log once in the given directory and returns the last commit for each path,
+// relative to dir and with forward slashes
+func readGitLog(ctx context.Context, dir string) (map[string]GitInfo, error) {
+	history, err := readGitHistory(ctx, dir, 1)
+	if err != nil {
+		return nil, err
+	}
+	lastCommits := make(map[string]GitInfo, len(history))
+	for path, commits := range history {
+		lastCommits[path] = commits[0]
+	}
+	return lastCommits, nil
+}
+
+// readGitHistory runs git log once in the given directory and returns the last n commits for each path, newest
+// first, with the paths relative to dir and with forward slashes
+func readGitHistory(ctx context.Context, dir string, n int) (map[string][]GitInfo, error) {
+	out, err := runGitLog(ctx, dir, "--format=\x1e%H\x1f%an\x1f%ae\x1f%aI", "--name-only", "--relative", "--no-renames")
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[string][]GitInfo)
+	for _, record := range bytes.Split(out, []byte{0x1e}) {
+		scanner := bufio.NewScanner(bytes.NewReader(record))
+		if !scanner.Scan() {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, fields[3])
+		info := GitInfo{Commit: fields[0], Author: fields[1], Email: fields[2], Time: t}
+		for scanner.Scan() {
+			path := scanner.Text()
+			if path == "" {
+				continue
+			}
+			// The log is newest first, so the first commits that are seen for a path are the last ones
+			if len(history[path]) < n {
+				history[path] = append(history[path], info)
+			}
+		}
+	}
+	return history, nil
+}
+
+// readChangelog returns the last n commits in the given directory, newest first.
+// The files that each commit touched are included if withFiles is true.
+func readChangelog(ctx context.Context, dir string, n int, withFiles bool) ([]ChangelogEntry, error) {
+	args := []string{"-n", strconv.Itoa(n), "--format=\x1e%H\x1f%an\x1f%ae\x1f%aI\x1f%s"}
+	if withFiles {
+		args = append(args, "--name-only", "--relative", "--no-renames")
+	}
+	out, err := runGitLog(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ChangelogEntry
+	for _, record := range bytes.Split(out, []byte{0x1e}) {
+		scanner := bufio.NewScanner(bytes.NewReader(record))
+		if !scanner.Scan() {
+			continue
+		}
+		fields := strings.SplitN(scanner.Text(), "\x1f", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, fields[3])
+		entry := ChangelogEntry{
+			GitInfo: GitInfo{Commit: fields[0], Author: fields[1], Email: fields[2], Time: t},
+			Subject: fields[4],
+		}
+		for scanner.Scan() {
+			if path := scanner.Text(); path != "" {
+				entry.Files = append(entry.Files, path)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
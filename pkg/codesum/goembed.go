@@ -0,0 +1,257 @@
+package codesum
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxGoEmbedSize is the size limit of the text files that are included because a //go:embed directive refers to
+// them, see WithGoEmbed. Larger files are listed in ProjectInfo.EmbeddedAssets instead.
+const MaxGoEmbedSize = 64 * 1024
+
+// goEmbedDirective is the start of a //go:embed line
+const goEmbedDirective = "//go:embed"
+
+// embeddedByPrefix is the start of FileInfo.Reason for the files that a //go:embed directive refers to
+const embeddedByPrefix = "embedded by "
+
+// globEscaper escapes the characters that path.Match treats specially, for using a directory name in a pattern
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+
+// parseGoEmbeds returns the patterns of the //go:embed directives in Go source code, in order. The patterns are
+// separated by spaces, and may be quoted with double quotes or backquotes.
+func parseGoEmbeds(src string) ([]string, error) {
+	var patterns []string
+	for _, line := range strings.Split(src, "\n") {
+		args, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), goEmbedDirective)
+		if !ok || args != "" && args[0] != ' ' && args[0] != '\t' {
+			continue
+		}
+		args = strings.TrimSpace(args)
+		for args != "" {
+			var pattern string
+			switch args[0] {
+			case '"', '`':
+				end := strings.IndexByte(args[1:], args[0])
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated quoted pattern in %s%s", goEmbedDirective, line[len(goEmbedDirective):])
+				}
+				unquoted, err := strconv.Unquote(args[:end+2])
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted pattern %s: %w", args[:end+2], err)
+				}
+				pattern, args = unquoted, args[end+2:]
+			default:
+				end := strings.IndexAny(args, " \t")
+				if end < 0 {
+					end = len(args)
+				}
+				pattern, args = args[:end], args[end:]
+			}
+			patterns = append(patterns, pattern)
+			args = strings.TrimSpace(args)
+		}
+	}
+	return patterns, nil
+}
+
+// validEmbedPattern checks a //go:embed pattern like the go command does: it is a slash separated path, relative
+// to the directory of the Go file, without "." or ".." elements
+func validEmbedPattern(pattern string) error {
+	pattern = strings.TrimPrefix(pattern, "all:")
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	if pattern == "" || !fs.ValidPath(pattern) || pattern == "." {
+		return errors.New("invalid pattern syntax")
+	}
+	return nil
+}
+
+// resolveGoEmbed returns the regular files that a //go:embed pattern in the given directory refers to, sorted.
+// Like the go command, the files in directories that a pattern matches are included, except for the files and
+// directories that start with "." or "_", unless the pattern starts with "all:". Files that the pattern matches
+// directly are always included.
+func resolveGoEmbed(fsys fs.FS, dir, pattern string) ([]string, error) {
+	all := strings.HasPrefix(pattern, "all:")
+	pattern = strings.TrimPrefix(pattern, "all:")
+	matches, err := fs.Glob(fsys, path.Join(globEscaper.Replace(dir), pattern))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, errors.New("no matching files found")
+	}
+	var found []string
+	for _, match := range matches {
+		info, err := fs.Stat(fsys, match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if info.Mode().IsRegular() {
+				found = append(found, match)
+			}
+			continue
+		}
+		err = fs.WalkDir(fsys, match, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if base := path.Base(name); name != match && !all && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() {
+				found = append(found, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
+// collectGoEmbeds finds the files that the //go:embed directives in the given Go files refer to. The text files
+// that are not already among the files are returned with FileInfo.Reason set, the files that are binary or
+// larger than MaxGoEmbedSize are returned as assets, and the directives that can not be resolved are returned as
+// warnings. The files that are already collected get FileInfo.Reason set too.
+func collectGoEmbeds(fsys fs.FS, files []FileInfo, ignores *ignoreMatcher, o Options) ([]FileInfo, []Asset, []FileError, []string) {
+	collected := make(map[string]int, len(files))
+	for i, file := range files {
+		collected[file.Path] = i
+	}
+	seen := make(map[string]bool)
+	var (
+		embedded   []FileInfo
+		assets     []Asset
+		fileErrors []FileError
+		warnings   []string
+	)
+	for _, goFile := range files {
+		if goFile.Language != "Go" {
+			continue
+		}
+		src := goFile.Contents
+		if o.SkipContents {
+			data, err := fs.ReadFile(fsys, goFile.Path)
+			if err != nil {
+				fileErrors = append(fileErrors, FileError{Path: goFile.Path, Error: err.Error()})
+				continue
+			}
+			src = string(data)
+		}
+		patterns, err := parseGoEmbeds(src)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not parse the //go:embed directives of %s: %v", goFile.Path, err))
+			continue
+		}
+		reason := embeddedByPrefix + goFile.Path
+		for _, pattern := range patterns {
+			if err := validEmbedPattern(pattern); err != nil {
+				warnings = append(warnings, fmt.Sprintf("skipping the //go:embed pattern %s in %s: %v", pattern, goFile.Path, err))
+				continue
+			}
+			names, err := resolveGoEmbed(fsys, path.Dir(goFile.Path), pattern)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("skipping the //go:embed pattern %s in %s: %v", pattern, goFile.Path, err))
+				continue
+			}
+			for _, name := range names {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				if i, ok := collected[name]; ok {
+					if files[i].Reason == "" {
+						files[i].Reason = reason
+					}
+					continue
+				}
+				if pattern, ok := o.excluded(name); ok {
+					o.Logger.Info("skipping embedded file", "path", name, "exclude", pattern)
+					continue
+				}
+				if rule, ok := ignores.match(name, false); ok {
+					o.Logger.Info("skipping embedded file", "path", name, "ignore", rule.pattern, "ignore-file", rule.source)
+					continue
+				}
+				file, asset, err := readGoEmbed(fsys, name, o)
+				switch {
+				case err != nil:
+					fileErrors = append(fileErrors, FileError{Path: name, Error: err.Error()})
+				case asset != nil:
+					o.Logger.Debug("found embedded asset", "path", name, "embedded-by", goFile.Path)
+					asset.EmbeddedBy = goFile.Path
+					assets = append(assets, *asset)
+				default:
+					o.Logger.Debug("found embedded file", "path", name, "embedded-by", goFile.Path)
+					file.Reason = reason
+					embedded = append(embedded, file)
+				}
+			}
+		}
+	}
+	sort.Slice(embedded, func(i, j int) bool { return embedded[i].Path < embedded[j].Path })
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Path < assets[j].Path })
+	return embedded, assets, fileErrors, warnings
+}
+
+// readGoEmbed reads an embedded file, which is returned as an asset if it is binary or too large
+func readGoEmbed(fsys fs.FS, name string, o Options) (FileInfo, *Asset, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+	if info.Size() > MaxGoEmbedSize {
+		return FileInfo{}, &Asset{Path: name, Size: info.Size(), Type: assetType(name)}, nil
+	}
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+	mimeType := sniffMimeType(content[:min(len(content), sniffLength)], name)
+	text, encoding, ok := decodeText(content)
+	if !ok || sniffedBinary(mimeType) {
+		return FileInfo{}, &Asset{Path: name, Size: info.Size(), Type: assetType(name)}, nil
+	}
+	language, ok := o.extensionLanguage(name)
+	if !ok {
+		if language, ok = textLanguages[strings.ToLower(path.Ext(name))]; !ok {
+			language = "Plain text"
+		}
+	}
+	lineCount, _ := countLinesFrom(strings.NewReader(text))
+	file := FileInfo{
+		Path:         name,
+		Language:     language,
+		Role:         o.role(name),
+		LineCount:    lineCount,
+		LastModified: o.formatTimestamp(info.ModTime()),
+		Size:         info.Size(),
+		Hash:         hashBytes(content),
+		Encoding:     encoding,
+		MimeType:     mimeType,
+		ModTime:      info.ModTime(),
+	}
+	if o.LegacyTimestamps {
+		file.LastModifiedLegacy = info.ModTime().Format(legacyTimeLayout)
+	}
+	if !o.SkipContents {
+		file.Contents = text
+		if o.TrimEdges {
+			file.Contents = trimBlankLines(file.Contents)
+		}
+	}
+	return file, nil, nil
+}
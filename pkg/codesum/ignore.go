@@ -0,0 +1,295 @@
+package codesum
+
+import (
+	"bufio"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// commonIgnores are the directories that are skipped unless WithCommonIgnores(false) is used
+var commonIgnores = []string{"vendor", "test", "tmp", "backup", "node_modules"}
+
+// ignoreRule is a pattern of an ignore file, which applies to the paths below the directory of the file
+type ignoreRule struct {
+	// pattern is the line as it was written
+	pattern string
+	// source is the ignore file, relative to the root, or CommonIgnoresSource
+	source string
+	// dir is the directory of the ignore file, relative to the root, or "" for the root
+	dir string
+	// glob is the pattern without the "!", the leading and trailing slash and the escaping backslash
+	glob string
+	// negated patterns start with "!", and include the paths that an earlier pattern ignores
+	negated bool
+	// anchored patterns have a slash before the end, and are matched against the path relative to dir, like
+	// gitignore does, instead of against the name
+	anchored bool
+	// dirOnly patterns end with a slash, and only match directories
+	dirOnly bool
+}
+
+// newIgnoreRule parses a line of the ignore file in the given directory, like .gitignore does
+func newIgnoreRule(pattern, source, dir string) ignoreRule {
+	rule := ignoreRule{pattern: pattern, source: source, dir: dir, glob: pattern}
+	if glob, ok := strings.CutPrefix(rule.glob, "!"); ok {
+		rule.glob, rule.negated = glob, true
+	} else if strings.HasPrefix(rule.glob, `\!`) || strings.HasPrefix(rule.glob, `\#`) {
+		// A pattern for names that start with "!" or "#"
+		rule.glob = rule.glob[1:]
+	}
+	if trimmed := strings.TrimRight(rule.glob, "/"); trimmed != rule.glob {
+		rule.glob, rule.dirOnly = trimmed, true
+	}
+	if strings.Contains(rule.glob, "/") {
+		rule.glob, rule.anchored = strings.TrimPrefix(rule.glob, "/"), true
+	}
+	return rule
+}
+
+// matches checks if the rule matches the given path, relative to the root
+func (rule ignoreRule) matches(p string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	rel := p
+	if rule.dir != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(p, rule.dir+"/"); !ok {
+			return false
+		}
+	}
+	if !rule.anchored {
+		matched, _ := path.Match(rule.glob, path.Base(rel))
+		return matched
+	}
+	return matchSegments(strings.Split(rule.glob, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches the directories and the name of a path against those of a pattern, where "**" matches any
+// number of directories, and a "**" at the end matches everything below the directory before it
+func matchSegments(pattern, names []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(names) > 0
+			}
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(pattern[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], names[0]); !matched {
+			return false
+		}
+		pattern, names = pattern[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// ignoreMatcher has the rules of the ignore files in the root, and those of the ignore files in the directories
+// below it, which are read when the first path in the directory is matched
+type ignoreMatcher struct {
+	fsys      fs.FS
+	filenames []string
+	mut       sync.Mutex
+	rules     map[string][]ignoreRule // per directory, where "" is the root
+	dirs      map[string]ignoreMatch  // the directories that were matched, so that each one is matched once
+}
+
+// ignoreMatch is the rule that ignores a path, if any
+type ignoreMatch struct {
+	rule    ignoreRule
+	ignored bool
+}
+
+// loadIgnorePatterns returns a matcher with the common ignores, if wanted, and the patterns of the given ignore files,
+// which are read in the root and in each directory below it
+func loadIgnorePatterns(fsys fs.FS, common bool, filenames ...string) *ignoreMatcher {
+	m := &ignoreMatcher{fsys: fsys, filenames: filenames, rules: make(map[string][]ignoreRule), dirs: make(map[string]ignoreMatch)}
+	var rules []ignoreRule
+	if common {
+		for _, dir := range commonIgnores {
+			rules = append(rules, newIgnoreRule(dir, CommonIgnoresSource, ""))
+		}
+	}
+	m.rules[""] = m.readRules("", rules)
+	return m
+}
+
+// readRules reads the ignore files in the given directory, and returns the rules that are given with those in them
+func (m *ignoreMatcher) readRules(dir string, rules []ignoreRule) []ignoreRule {
+	for _, filename := range m.filenames {
+		source := filename
+		if dir != "" {
+			source = dir + "/" + filename
+		}
+		patterns, err := readIgnoreFile(m.fsys, source)
+		if err != nil {
+			continue // Ignore files that cannot be read or don't exist
+		}
+		for _, pattern := range patterns {
+			rules = append(rules, newIgnoreRule(pattern, source, dir))
+		}
+	}
+	return rules
+}
+
+// rulesIn returns the rules of the ignore files in the given directory, reading them the first time
+func (m *ignoreMatcher) rulesIn(dir string) []ignoreRule {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	rules, ok := m.rules[dir]
+	if !ok {
+		rules = m.readRules(dir, nil)
+		m.rules[dir] = rules
+	}
+	return rules
+}
+
+// match returns the rule that ignores the given path, relative to the root, if any. Like with git, the last rule
+// that matches wins, where the rules of the ignore files in deeper directories come later, and the paths in an
+// ignored directory are ignored, even if a negated rule matches them.
+func (m *ignoreMatcher) match(p string, isDir bool) (ignoreRule, bool) {
+	if p == "." {
+		return ignoreRule{}, false
+	}
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			if found := m.matchDir(p[:i]); found.ignored {
+				return found.rule, true
+			}
+		}
+	}
+	found := m.matchPath(p, isDir)
+	return found.rule, found.ignored
+}
+
+// matchDir matches a directory, remembering the result, since it is matched again for each path below it
+func (m *ignoreMatcher) matchDir(dir string) ignoreMatch {
+	m.mut.Lock()
+	found, ok := m.dirs[dir]
+	m.mut.Unlock()
+	if ok {
+		return found
+	}
+	found = m.matchPath(dir, true)
+	m.mut.Lock()
+	m.dirs[dir] = found
+	m.mut.Unlock()
+	return found
+}
+
+// matchPath returns the last rule that matches the path itself, from the ignore files in the root and in the
+// directories above the path, where a negated rule means that the path is not ignored
+func (m *ignoreMatcher) matchPath(p string, isDir bool) ignoreMatch {
+	var found ignoreMatch
+	check := func(dir string) {
+		for _, rule := range m.rulesIn(dir) {
+			if rule.matches(p, isDir) {
+				found = ignoreMatch{rule: rule, ignored: !rule.negated}
+			}
+		}
+	}
+	check("")
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			check(p[:i])
+		}
+	}
+	if !found.ignored {
+		return ignoreMatch{}
+	}
+	return found
+}
+
+// readIgnoreFile returns the patterns in the given ignore file, leaving out blank lines and comments
+func readIgnoreFile(fsys fs.FS, filename string) ([]string, error) {
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
+	var patterns []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// Ignorer reports which directories are skipped when collecting, because of the ignore files or the common ignores
+type Ignorer struct {
+	matcher *ignoreMatcher
+	sources []IgnoreSource
+	origins map[string]string // the first source of each pattern
+}
+
+// IgnoreSource is an ignore file, or the common ignores, and the number of patterns it contributed
+type IgnoreSource struct {
+	File     string `json:"file"`
+	Found    bool   `json:"found"`
+	Patterns int    `json:"patterns"`
+}
+
+// CommonIgnoresSource is the name of the IgnoreSource for the directories that are always skipped, like vendor
+const CommonIgnoresSource = "(common ignores)"
+
+// NewIgnorer reads the ignore files from fsys, as given by WithIgnoreFiles
+func NewIgnorer(fsys fs.FS, opts ...Option) (*Ignorer, error) {
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	ig := &Ignorer{matcher: loadIgnorePatterns(fsys, !o.NoCommonIgnores, o.IgnoreFiles...), origins: make(map[string]string)}
+	addSource := func(source IgnoreSource, patterns []string) {
+		ig.sources = append(ig.sources, source)
+		for _, pattern := range patterns {
+			if _, ok := ig.origins[pattern]; !ok && !strings.HasPrefix(pattern, "!") {
+				ig.origins[pattern] = source.File
+			}
+		}
+	}
+	for _, filename := range o.IgnoreFiles {
+		filePatterns, err := readIgnoreFile(fsys, filename)
+		addSource(IgnoreSource{File: filename, Found: err == nil, Patterns: len(filePatterns)}, filePatterns)
+	}
+	if !o.NoCommonIgnores {
+		addSource(IgnoreSource{File: CommonIgnoresSource, Found: true, Patterns: len(commonIgnores)}, commonIgnores)
+	}
+	return ig, nil
+}
+
+// Ignored checks if the directory with the given slash separated path, relative to the root, is skipped.
+// The ignore files in the directories above it are read too.
+func (ig *Ignorer) Ignored(path string) bool {
+	_, ok := ig.matcher.match(path, true)
+	return ok
+}
+
+// IgnoredFile checks if the file with the given slash separated path, relative to the root, is skipped, either by
+// a pattern or because a directory above it is skipped
+func (ig *Ignorer) IgnoredFile(path string) bool {
+	_, ok := ig.matcher.match(path, false)
+	return ok
+}
+
+// Sources returns the ignore files in the root, in the order they are read, followed by the common ignores, unless
+// they are turned off
+func (ig *Ignorer) Sources() []IgnoreSource {
+	return ig.sources
+}
+
+// Origin returns the ignore file in the root that the given pattern came from, or CommonIgnoresSource
+func (ig *Ignorer) Origin(pattern string) (string, bool) {
+	origin, ok := ig.origins[pattern]
+	return origin, ok
+}
@@ -0,0 +1,125 @@
+package codesum
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte(
+			"# build output\n" +
+				"*.log\n" +
+				"!keep.log\n" +
+				"build/\n" +
+				"/docs/**/*.pdf\n" +
+				"gen/**\n" +
+				"**/fixtures/*.bin\n" +
+				"*.tmp\n" +
+				"!*.tmp\n" +
+				"secret.txt\n")},
+		"src/.gitignore":     &fstest.MapFile{Data: []byte("!debug.log\nlocal.go\n")},
+		"src/sub/.gitignore": &fstest.MapFile{Data: []byte("*.go\n!main.go\n")},
+	}
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+		source  string
+	}{
+		{"app.log", false, true, ".gitignore"},
+		{"a/b/app.log", false, true, ".gitignore"},
+		{"keep.log", false, false, ""},                 // negated by a later pattern
+		{"build", true, true, ".gitignore"},            // dirOnly matches a directory
+		{"build", false, false, ""},                    // but not a file
+		{"build/out.go", false, true, ".gitignore"},    // paths in an ignored directory
+		{"docs/manual.pdf", false, true, ".gitignore"}, // "**" matches no directories
+		{"docs/a/b/manual.pdf", false, true, ".gitignore"},
+		{"src/docs/manual.pdf", false, false, ""}, // anchored to the root
+		{"gen", true, false, ""},                  // a trailing "**" only matches below the directory
+		{"gen/a/b.go", false, true, ".gitignore"},
+		{"fixtures/x.bin", false, true, ".gitignore"}, // a leading "**" matches no directories
+		{"a/b/fixtures/x.bin", false, true, ".gitignore"},
+		{"a/b/fixtures/c/x.bin", false, false, ""},
+		{"x.tmp", false, false, ""}, // the last matching pattern wins
+		{"secret.txt", false, true, ".gitignore"},
+		{"src/debug.log", false, false, ""}, // negated by the ignore file in src
+		{"debug.log", false, true, ".gitignore"},
+		{"src/local.go", false, true, "src/.gitignore"}, // only below the directory of the ignore file
+		{"local.go", false, false, ""},
+		{"src/sub/util.go", false, true, "src/sub/.gitignore"},
+		{"src/sub/main.go", false, false, ""},
+		{"src/other/util.go", false, false, ""},
+		{"src/sub/deeper/util.go", false, true, "src/sub/.gitignore"},
+		{"build/keep.log", false, true, ".gitignore"}, // a negation does not include paths in an ignored directory
+	}
+	m := loadIgnorePatterns(fsys, false, ".gitignore")
+	for _, tt := range tests {
+		rule, ignored := m.match(tt.path, tt.isDir)
+		if ignored != tt.ignored {
+			t.Errorf("match(%q, %v) = %v, want %v", tt.path, tt.isDir, ignored, tt.ignored)
+			continue
+		}
+		if ignored && rule.source != tt.source {
+			t.Errorf("match(%q, %v) is ignored by %s (%q), want %s", tt.path, tt.isDir, rule.source, rule.pattern, tt.source)
+		}
+	}
+}
+
+func TestIgnoreMatcherCommon(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte("!vendor\n")},
+	}
+	if _, ignored := loadIgnorePatterns(fsys, true).match("vendor", true); !ignored {
+		t.Error("vendor is not ignored with the common ignores")
+	}
+	if _, ignored := loadIgnorePatterns(fsys, false).match("vendor", true); ignored {
+		t.Error("vendor is ignored without the common ignores")
+	}
+	// The ignore files come after the common ignores, so they can include a directory again
+	if _, ignored := loadIgnorePatterns(fsys, true, ".gitignore").match("vendor", true); ignored {
+		t.Error("vendor is ignored, even though .gitignore includes it")
+	}
+}
+
+func TestCollectNestedIgnoreFiles(t *testing.T) {
+	file := func(contents string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(contents)}
+	}
+	fsys := fstest.MapFS{
+		".gitignore":                   file("build/output\n"),
+		"main.go":                      file("package main\n"),
+		"build/output/out.go":          file("package output\n"),
+		"build/keep.go":                file("package build\n"),
+		"x/build/output/kept.go":       file("package output\n"), // anchored patterns only match below their directory
+		"src/.gitignore":               file("generated/\n*_gen.go\n"),
+		"src/app.go":                   file("package src\n"),
+		"src/app_gen.go":               file("package src\n"),
+		"src/generated/types.go":       file("package generated\n"),
+		"src/deep/.gitignore":          file("!keep_gen.go\n"),
+		"src/deep/deeper/keep_gen.go":  file("package deeper\n"),
+		"src/deep/deeper/other_gen.go": file("package deeper\n"),
+		"other/generated/types.go":     file("package generated\n"), // src/.gitignore does not apply here
+	}
+	project, err := CollectFS(context.Background(), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range project.Files {
+		paths = append(paths, file.Path)
+	}
+	want := []string{
+		"build/keep.go",
+		"main.go",
+		"other/generated/types.go",
+		"src/app.go",
+		"src/deep/deeper/keep_gen.go",
+		"x/build/output/kept.go",
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("got the files %q, want %q", paths, want)
+	}
+}
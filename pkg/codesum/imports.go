@@ -0,0 +1,155 @@
+package codesum
+
+import (
+	"bufio"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportCounts is the number of imports of a Go file per kind, see WithImports
+type ImportCounts struct {
+	Stdlib   int `json:"stdlib"`
+	Internal int `json:"internal"`
+	External int `json:"external"`
+	// Modules are the external modules that are imported, sorted
+	Modules []string `json:"external_modules,omitempty"`
+}
+
+// ExternalDependency is an external module and the Go files that import it, see WithImports
+type ExternalDependency struct {
+	Module string   `json:"module"`
+	Files  []string `json:"files"`
+}
+
+// majorVersion matches the major version suffix of a module path, like "v2"
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// goModule is the module path and the required modules from a go.mod file
+type goModule struct {
+	path     string
+	requires []string
+}
+
+// readGoModRequires returns the module paths in the require directives of a go.mod file,
+// both the single line and the block form
+func readGoModRequires(fsys fs.FS, modFilePath string) ([]string, error) {
+	file, err := fsys.Open(modFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var requires []string
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			requires = append(requires, fields[0])
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "require" && len(fields) > 1:
+			requires = append(requires, fields[1])
+		}
+	}
+	return requires, scanner.Err()
+}
+
+// classifyImports sets FileInfo.Imports for the Go files, and returns the external modules with the files that
+// import them, sorted by module. Files that can not be parsed are returned as errors.
+func classifyImports(files []FileInfo, mod goModule) ([]ExternalDependency, []FileError) {
+	var fileErrors []FileError
+	importers := make(map[string][]string)
+	for i := range files {
+		if files[i].Language != "Go" {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), files[i].Path, files[i].Contents, parser.ImportsOnly)
+		if err != nil {
+			fileErrors = append(fileErrors, FileError{Path: files[i].Path, Error: err.Error()})
+			continue
+		}
+		counts := &ImportCounts{}
+		seen := make(map[string]bool)
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || importPath == "C" {
+				continue
+			}
+			switch module := mod.classify(importPath); module {
+			case "":
+				counts.Stdlib++
+			case mod.path:
+				counts.Internal++
+			default:
+				counts.External++
+				if !seen[module] {
+					seen[module] = true
+					counts.Modules = append(counts.Modules, module)
+					importers[module] = append(importers[module], files[i].Path)
+				}
+			}
+		}
+		sort.Strings(counts.Modules)
+		files[i].Imports = counts
+	}
+	dependencies := make([]ExternalDependency, 0, len(importers))
+	for _, module := range sortedKeys(importers) {
+		dependencies = append(dependencies, ExternalDependency{Module: module, Files: importers[module]})
+	}
+	return dependencies, fileErrors
+}
+
+// classify returns the module that an import path belongs to, which is the module itself for internal imports,
+// or an empty string for the standard library. Vendored import paths are classified by the path after "vendor/".
+func (mod goModule) classify(importPath string) string {
+	if i := strings.LastIndex("/"+importPath, "/vendor/"); i >= 0 {
+		importPath = importPath[i+len("vendor/"):]
+	}
+	if mod.path != "" && (importPath == mod.path || strings.HasPrefix(importPath, mod.path+"/")) {
+		return mod.path
+	}
+	first, _, _ := strings.Cut(importPath, "/")
+	if !strings.Contains(first, ".") {
+		return ""
+	}
+	// The longest required module that the import path is in, like github.com/org/repo/v2 for github.com/org/repo/v2/sub
+	module := ""
+	for _, required := range mod.requires {
+		if (importPath == required || strings.HasPrefix(importPath, required+"/")) && len(required) > len(module) {
+			module = required
+		}
+	}
+	if module != "" {
+		return module
+	}
+	return guessModule(importPath)
+}
+
+// guessModule guesses the module of an import path that is not required in go.mod, from the conventions of the
+// common hosts, where a major version suffix is part of the module path
+func guessModule(importPath string) string {
+	elements := strings.Split(importPath, "/")
+	n := len(elements)
+	switch elements[0] {
+	case "github.com", "gitlab.com", "bitbucket.org", "golang.org":
+		n = 3
+	case "gopkg.in", "google.golang.org":
+		n = 2
+	}
+	n = min(n, len(elements))
+	if n < len(elements) && majorVersion.MatchString(elements[n]) {
+		n++
+	}
+	return strings.Join(elements[:n], "/")
+}
@@ -0,0 +1,195 @@
+package codesum
+
+import (
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// languageNames are the languages of the built-in extensions, see extensionLanguages and textLanguages
+var languageNames = []string{
+	"Go", "C++", "C/C++ Header", "Rust", "C", "Python", "Markdown", "Java",
+	"JavaScript", "TypeScript", "Kotlin", "ASCIIDoc", "reStructuredText", "Plain text",
+	"Protocol Buffers", "Thrift", "Cap'n Proto",
+}
+
+// interfaceGroup is the group of the interface definition languages, when grouping by language
+const interfaceGroup = "Interfaces/IDL"
+
+// interfaceLanguages define interfaces between programs that may be written in different languages
+var interfaceLanguages = map[string]bool{
+	"Protocol Buffers": true,
+	"Thrift":           true,
+	"Cap'n Proto":      true,
+}
+
+// fenceLanguages are the code fence languages, which Markdown renderers use for syntax highlighting. Languages
+// that are not listed, like those of WithExtensionLanguages, use the name in lowercase and without spaces.
+var fenceLanguages = map[string]string{
+	"Go":               "go",
+	"C++":              "cpp",
+	"C/C++ Header":     "cpp",
+	"Rust":             "rust",
+	"C":                "c",
+	"Python":           "python",
+	"Markdown":         "markdown",
+	"Java":             "java",
+	"JavaScript":       "javascript",
+	"TypeScript":       "typescript",
+	"Kotlin":           "kotlin",
+	"ASCIIDoc":         "asciidoc",
+	"reStructuredText": "rst",
+	"Plain text":       "text",
+	"Protocol Buffers": "protobuf",
+	"Thrift":           "thrift",
+	"Cap'n Proto":      "capnp",
+}
+
+// extensionLanguages are the languages of the extensions that are collected, in lowercase and with the dot.
+// WithExtensionLanguages adds to them or replaces them.
+var extensionLanguages = map[string]string{
+	".go":     "Go",
+	".cpp":    "C++",
+	".cc":     "C++",
+	".hpp":    "C/C++ Header",
+	".h":      "C/C++ Header",
+	".rs":     "Rust",
+	".c":      "C",
+	".py":     "Python",
+	".md":     "Markdown",
+	".java":   "Java",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".kt":     "Kotlin",
+	".proto":  "Protocol Buffers",
+	".thrift": "Thrift",
+	".capnp":  "Cap'n Proto",
+}
+
+// textLanguages are the languages of the text extensions that are not collected, but that name the language of
+// files that are included for other reasons, like with WithGoEmbed
+var textLanguages = map[string]string{
+	".adoc": "ASCIIDoc",
+	".rst":  "reStructuredText",
+	".txt":  "Plain text",
+}
+
+// recognizedExtension checks if the extension of the path is one of the built-in extensions that are collected
+func recognizedExtension(path string) bool {
+	_, ok := extensionLanguages[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// extensionLanguage returns the language of the extension of the path, if it is collected. The extensions of
+// WithExtensionLanguages come first, and the built-in ones are used unless WithOnlyExtensionLanguages is used.
+func (o Options) extensionLanguage(path string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if language, ok := o.ExtensionLanguages[ext]; ok {
+		return language, true
+	}
+	if o.OnlyExtensionLanguages {
+		return "", false
+	}
+	language, ok := extensionLanguages[ext]
+	return language, ok
+}
+
+// recognizedExtension checks if files with the extension of the path are collected
+func (o Options) recognizedExtension(path string) bool {
+	_, ok := o.extensionLanguage(path)
+	return ok
+}
+
+// knownLanguages returns the built-in languages, followed by the other languages of WithExtensionLanguages
+func (o Options) knownLanguages() []string {
+	known := slices.Clone(languageNames)
+	for _, ext := range sortedKeys(o.ExtensionLanguages) {
+		if language := o.ExtensionLanguages[ext]; !slices.Contains(known, language) {
+			known = append(known, language)
+		}
+	}
+	return known
+}
+
+// isKnownLanguage checks if the name is one of knownLanguages, in any case
+func (o Options) isKnownLanguage(name string) bool {
+	for _, lang := range o.knownLanguages() {
+		if strings.EqualFold(lang, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mimeTypes are the MIME types of the text files per extension, which refine the text/plain that content sniffing
+// gives, see sniffMimeType. Keep them in sync with extensionLanguages and textLanguages.
+var mimeTypes = map[string]string{
+	".go":     "text/x-go",
+	".cpp":    "text/x-c++",
+	".cc":     "text/x-c++",
+	".hpp":    "text/x-c++hdr",
+	".h":      "text/x-chdr",
+	".rs":     "text/x-rust",
+	".c":      "text/x-c",
+	".py":     "text/x-python",
+	".md":     "text/markdown",
+	".java":   "text/x-java",
+	".js":     "text/javascript",
+	".jsx":    "text/javascript",
+	".ts":     "text/x-typescript",
+	".tsx":    "text/x-typescript",
+	".kt":     "text/x-kotlin",
+	".adoc":   "text/asciidoc",
+	".rst":    "text/x-rst",
+	".txt":    "text/plain",
+	".proto":  "text/x-protobuf",
+	".thrift": "text/x-thrift",
+	".capnp":  "text/x-capnp",
+}
+
+// sniffMimeType returns the MIME type of a file from the first bytes of it, like http.DetectContentType, where
+// text/plain is refined by the extension of the path, like text/x-go for .go files. The parameters, like the
+// charset, are kept.
+func sniffMimeType(head []byte, path string) string {
+	sniffed := http.DetectContentType(head)
+	mediaType, params, _ := strings.Cut(sniffed, ";")
+	refined, ok := mimeTypes[strings.ToLower(filepath.Ext(path))]
+	if mediaType != "text/plain" || !ok {
+		return sniffed
+	}
+	if params != "" {
+		return refined + ";" + params
+	}
+	return refined
+}
+
+// sniffedBinary checks if a sniffed MIME type is of a binary format, like an image or an archive. The generic
+// application/octet-stream is not, since it only means that no format was recognized.
+func sniffedBinary(mimeType string) bool {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/octet-stream", mediaType == "application/json",
+		mediaType == "application/postscript":
+		return false
+	}
+	return true
+}
+
+// languageGroup returns the heading that files of the given language are listed under, when grouping by language
+func languageGroup(language string) string {
+	if interfaceLanguages[language] {
+		return interfaceGroup
+	}
+	return language
+}
+
+// fenceLanguage returns the language that is used for code fences, like "cpp" for "C++"
+func fenceLanguage(language string) string {
+	if fence, ok := fenceLanguages[language]; ok {
+		return fence
+	}
+	return strings.ToLower(strings.ReplaceAll(language, " ", ""))
+}
@@ -0,0 +1,62 @@
+package codesum
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExtensionLanguages(t *testing.T) {
+	tests := []struct {
+		path, language, fence string
+	}{
+		{"api/service.proto", "Protocol Buffers", "protobuf"},
+		{"api/SERVICE.PROTO", "Protocol Buffers", "protobuf"},
+		{"api/service.thrift", "Thrift", "thrift"},
+		{"api/schema.capnp", "Cap'n Proto", "capnp"},
+		{"web/app.ts", "TypeScript", "typescript"},
+		{"web/App.tsx", "TypeScript", "typescript"},
+	}
+	var o Options
+	for _, tt := range tests {
+		language, ok := o.extensionLanguage(tt.path)
+		if !ok || language != tt.language {
+			t.Errorf("the language of %s is %q (collected: %t), want %q", tt.path, language, ok, tt.language)
+			continue
+		}
+		if fence := fenceLanguage(language); fence != tt.fence {
+			t.Errorf("the fence of %s is %q, want %q", tt.path, fence, tt.fence)
+		}
+	}
+}
+
+func TestInterfaceGroup(t *testing.T) {
+	for _, language := range []string{"Protocol Buffers", "Thrift", "Cap'n Proto"} {
+		if group := languageGroup(language); group != interfaceGroup {
+			t.Errorf("%s is grouped under %q, want %q", language, group, interfaceGroup)
+		}
+	}
+	if group := languageGroup("Go"); group != "Go" {
+		t.Errorf("Go is grouped under %q, want Go", group)
+	}
+}
+
+func TestCustomExtensionLanguage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":    {Data: []byte("package main\n")},
+		"web/app.ts": {Data: []byte("export const answer = 42;\n")},
+		"app.rb":     {Data: []byte("puts 42\n")},
+	}
+	project, err := CollectFS(context.Background(), fsys, WithExtensionLanguages(map[string]string{".ts": "TypeScript", "rb": "Ruby"}), WithOnlyExtensionLanguages(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	languages := make(map[string]string)
+	for _, file := range project.Files {
+		languages[file.Path] = file.Language
+	}
+	want := map[string]string{"web/app.ts": "TypeScript", "app.rb": "Ruby"}
+	if len(languages) != len(want) || languages["web/app.ts"] != want["web/app.ts"] || languages["app.rb"] != want["app.rb"] {
+		t.Errorf("got the languages %v, want %v", languages, want)
+	}
+}
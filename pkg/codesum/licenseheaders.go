@@ -0,0 +1,182 @@
+package codesum
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// minLicenseHeaderRepeats is the number of files that need to start with the same comment for it to be
+// stripped as a license header, when it does not look like one
+const minLicenseHeaderRepeats = 3
+
+// licenseTemplates are phrases from the common license headers, in lowercase
+var licenseTemplates = []string{
+	"licensed under the apache license",
+	"permission is hereby granted, free of charge",
+	"redistribution and use in source and binary forms",
+	"gnu general public license",
+	"gnu lesser general public license",
+	"gnu affero general public license",
+	"mozilla public license",
+	"use of this source code is governed by",
+	"this source code is licensed under",
+	"all rights reserved",
+}
+
+var (
+	// yearRange matches years and ranges of years, which differ between otherwise identical headers
+	yearRange = regexp.MustCompile(`\b(19|20)[0-9]{2}(\s*[-,]\s*((19|20)[0-9]{2}|present))*\b`)
+	// commentMarker matches the comment markers at the start or end of a line
+	commentMarker = regexp.MustCompile(`(?m)^\s*(/\*+|\*+/|\*|//+|#+)|\*+/\s*$`)
+)
+
+// matchesLicenseTemplate checks if the text of a comment looks like a license header
+func matchesLicenseTemplate(text string) bool {
+	if isLicenseHeader(text) {
+		return true
+	}
+	lower := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, phrase := range licenseTemplates {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadingComment returns the start and the end of the comment block at the start of the contents, after a
+// shebang line and blank lines, with the blank lines after it. The block is the "/* */" comment or the
+// consecutive line comments there, where directives like //go:build end the block. The end is 0 if there is none.
+func leadingComment(contents, language string) (int, int) {
+	prefix, ok := lineCommentPrefixes[language]
+	if !ok {
+		prefix = "//"
+	}
+	if prefix == "" {
+		return 0, 0
+	}
+	start := 0
+	if strings.HasPrefix(contents, "#!") {
+		start = strings.IndexByte(contents, '\n') + 1
+		if start == 0 {
+			return 0, 0
+		}
+	}
+	for start < len(contents) && strings.ContainsRune(" \t\r\n", rune(contents[start])) {
+		start++
+	}
+	// The start of the line, so that indentation is stripped too
+	start = strings.LastIndexByte(contents[:start], '\n') + 1
+
+	end := start
+	if prefix == "//" && strings.HasPrefix(strings.TrimLeft(contents[start:], " \t"), "/*") {
+		closing := strings.Index(contents[start:], "*/")
+		if closing < 0 {
+			return 0, 0
+		}
+		end = start + closing + 2
+		if newline := strings.IndexByte(contents[end:], '\n'); newline >= 0 && strings.TrimSpace(contents[end:end+newline]) == "" {
+			end += newline + 1
+		} else if newline >= 0 {
+			// Code follows the comment on the same line
+			return 0, 0
+		}
+	} else {
+		for end < len(contents) {
+			line, _, _ := strings.Cut(contents[end:], "\n")
+			trimmed := strings.TrimSpace(line)
+			if !strings.HasPrefix(trimmed, prefix) || strings.HasPrefix(trimmed, "//go:") || strings.HasPrefix(trimmed, "// +build") {
+				break
+			}
+			end += len(line) + 1
+		}
+		end = min(end, len(contents))
+	}
+	if end == start {
+		return 0, 0
+	}
+	for end < len(contents) {
+		line, _, found := strings.Cut(contents[end:], "\n")
+		if strings.TrimSpace(line) != "" {
+			break
+		}
+		if !found {
+			end = len(contents)
+			break
+		}
+		end += len(line) + 1
+	}
+	return start, end
+}
+
+// normalizeHeader returns the text of a comment block without the comment markers, the years and the
+// differences in whitespace, so that headers that only differ in those are the same
+func normalizeHeader(block string) string {
+	text := commentMarker.ReplaceAllString(block, "")
+	text = yearRange.ReplaceAllString(text, "YEAR")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// stripLicenseHeaders removes the license headers from the contents of the files and sets
+// FileInfo.LicenseHeaderStripped. A leading comment is a license header if it looks like one, or if at least
+// minLicenseHeaderRepeats files start with it, apart from the years and whitespace. Generated code markers and
+// Go package comments that do not look like a license are kept. The first file with each header gets a one-line
+// note instead of the header.
+func stripLicenseHeaders(files []FileInfo) int {
+	type header struct {
+		start, end int
+		key        string
+	}
+	headers := make([]header, len(files))
+	repeats := make(map[string]int)
+	for i, file := range files {
+		if file.ContentEncoding != "" || file.Diff != "" {
+			continue
+		}
+		start, end := leadingComment(file.Contents, file.Language)
+		if end == 0 {
+			continue
+		}
+		key := normalizeHeader(file.Contents[start:end])
+		if key == "" || strings.Contains(key, "DO NOT EDIT") {
+			continue
+		}
+		headers[i] = header{start: start, end: end, key: key}
+		repeats[key]++
+	}
+
+	noted := make(map[string]bool)
+	stripped := 0
+	for i := range files {
+		h := headers[i]
+		if h.key == "" {
+			continue
+		}
+		template := matchesLicenseTemplate(h.key)
+		if !template && repeats[h.key] < minLicenseHeaderRepeats {
+			continue
+		}
+		if !template && files[i].Language == "Go" && strings.HasPrefix(files[i].Contents[h.end:], "package ") &&
+			!strings.HasSuffix(files[i].Contents[:h.end], "\n\n") {
+			// A package comment, which documents the package even if it is repeated
+			continue
+		}
+		note := ""
+		if !noted[h.key] {
+			noted[h.key] = true
+			prefix := lineCommentPrefixes[files[i].Language]
+			if prefix == "" {
+				prefix = "//"
+			}
+			note = prefix + " The license header is left out\n\n"
+			if n := repeats[h.key]; n > 1 {
+				note = fmt.Sprintf("%s The license header is left out, here and in the %d other files that start with it\n\n", prefix, n-1)
+			}
+		}
+		files[i].Contents = files[i].Contents[:h.start] + note + files[i].Contents[h.end:]
+		files[i].LicenseHeaderStripped = true
+		stripped++
+	}
+	return stripped
+}
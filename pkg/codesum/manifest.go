@@ -0,0 +1,138 @@
+package codesum
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Manifest is the project name, version and dependencies, as read from the manifest file that is given with
+// WithManifestFile
+type Manifest struct {
+	Path    string `json:"path"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Dependencies are the names of the dependencies, sorted
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// manifestParsers read a manifest file, by filename
+var manifestParsers = map[string]func(fsys fs.FS, filename string) (Manifest, error){
+	"go.mod":         parseGoMod,
+	"package.json":   parsePackageJSON,
+	"Cargo.toml":     parseCargoToml,
+	"pyproject.toml": parsePyprojectToml,
+}
+
+// manifestFilenames returns the filenames of the manifest files that can be read, sorted
+func manifestFilenames() []string {
+	return sortedKeys(manifestParsers)
+}
+
+// readManifest reads the manifest file at the given path, with the parser for its filename
+func readManifest(fsys fs.FS, manifestPath string) (Manifest, error) {
+	parse, ok := manifestParsers[path.Base(manifestPath)]
+	if !ok {
+		return Manifest{}, fmt.Errorf("unknown manifest file %s, must be one of: %s", manifestPath, strings.Join(manifestFilenames(), ", "))
+	}
+	manifest, err := parse(fsys, manifestPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest.Path = manifestPath
+	sort.Strings(manifest.Dependencies)
+	return manifest, nil
+}
+
+// parseGoMod reads the module path and the required modules of a go.mod file, which has no version
+func parseGoMod(fsys fs.FS, filename string) (Manifest, error) {
+	name, err := readProjectName(fsys, filename)
+	if err != nil {
+		return Manifest{}, err
+	}
+	requires, err := readGoModRequires(fsys, filename)
+	if err != nil {
+		return Manifest{}, err
+	}
+	return Manifest{Name: name, Dependencies: requires}, nil
+}
+
+// parsePackageJSON reads the name, the version and the dependencies of a package.json file.
+// The development dependencies are left out.
+func parsePackageJSON(fsys fs.FS, filename string) (Manifest, error) {
+	var pkg struct {
+		Name         string            `json:"name"`
+		Version      string            `json:"version"`
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return Manifest{}, fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+	return Manifest{Name: pkg.Name, Version: pkg.Version, Dependencies: sortedKeys(pkg.Dependencies)}, nil
+}
+
+// parseCargoToml reads the name, the version and the dependencies of a Cargo.toml file.
+// A version that is inherited from the workspace is left out.
+func parseCargoToml(fsys fs.FS, filename string) (Manifest, error) {
+	var cargo struct {
+		Package struct {
+			Name    string `toml:"name"`
+			Version any    `toml:"version"`
+		} `toml:"package"`
+		Dependencies map[string]any `toml:"dependencies"`
+	}
+	if _, err := toml.DecodeFS(fsys, filename, &cargo); err != nil {
+		return Manifest{}, fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+	version, _ := cargo.Package.Version.(string)
+	return Manifest{Name: cargo.Package.Name, Version: version, Dependencies: sortedKeys(cargo.Dependencies)}, nil
+}
+
+// parsePyprojectToml reads the name, the version and the dependencies of a pyproject.toml file, from the
+// [project] table, or from the [tool.poetry] table if there is no [project] table
+func parsePyprojectToml(fsys fs.FS, filename string) (Manifest, error) {
+	var pyproject struct {
+		Project *struct {
+			Name         string   `toml:"name"`
+			Version      string   `toml:"version"`
+			Dependencies []string `toml:"dependencies"`
+		} `toml:"project"`
+		Tool struct {
+			Poetry struct {
+				Name         string         `toml:"name"`
+				Version      string         `toml:"version"`
+				Dependencies map[string]any `toml:"dependencies"`
+			} `toml:"poetry"`
+		} `toml:"tool"`
+	}
+	if _, err := toml.DecodeFS(fsys, filename, &pyproject); err != nil {
+		return Manifest{}, fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+	if project := pyproject.Project; project != nil {
+		manifest := Manifest{Name: project.Name, Version: project.Version}
+		for _, requirement := range project.Dependencies {
+			manifest.Dependencies = appendUnique(manifest.Dependencies, requirementName(requirement))
+		}
+		return manifest, nil
+	}
+	poetry := pyproject.Tool.Poetry
+	delete(poetry.Dependencies, "python") // the Python version, not a dependency
+	return Manifest{Name: poetry.Name, Version: poetry.Version, Dependencies: sortedKeys(poetry.Dependencies)}, nil
+}
+
+// requirementName returns the name of the package in a requirement like "requests[socks]>=2.0; python_version<'3.12'"
+func requirementName(requirement string) string {
+	if i := strings.IndexAny(requirement, " <>=!~;[(@"); i >= 0 {
+		requirement = requirement[:i]
+	}
+	return requirement
+}
@@ -0,0 +1,54 @@
+package codesum
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifestFile(t *testing.T) {
+	// A Python tool with a Go helper, where go.mod would be used for the name by default
+	fsys := fstest.MapFS{
+		"go.mod":         {Data: []byte("module example.com/helper\n\ngo 1.22\n\nrequire golang.org/x/sync v0.7.0\n")},
+		"pyproject.toml": {Data: []byte("[project]\nname = \"pytool\"\nversion = \"1.2.3\"\ndependencies = [\"requests>=2.0\", \"click[extra]; python_version<'3.12'\"]\n")},
+		"helper/main.go": {Data: []byte("package main\n")},
+		"pytool/cli.py":  {Data: []byte("print('cli')\n")},
+		"pytool/run.py":  {Data: []byte("print('run')\n")},
+	}
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantName     string
+		wantManifest *Manifest
+	}{
+		{"detected", nil, "example.com/helper", nil},
+		{"pyproject.toml", []Option{WithManifestFile("pyproject.toml")}, "pytool",
+			&Manifest{Path: "pyproject.toml", Name: "pytool", Version: "1.2.3", Dependencies: []string{"click", "requests"}}},
+		{"go.mod", []Option{WithManifestFile("go.mod")}, "example.com/helper",
+			&Manifest{Path: "go.mod", Name: "example.com/helper", Dependencies: []string{"golang.org/x/sync"}}},
+		{"name option", []Option{WithManifestFile("pyproject.toml"), WithName("custom")}, "custom",
+			&Manifest{Path: "pyproject.toml", Name: "pytool", Version: "1.2.3", Dependencies: []string{"click", "requests"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := CollectFS(context.Background(), fsys, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if project.Name != tt.wantName {
+				t.Errorf("got the name %q, want %q", project.Name, tt.wantName)
+			}
+			if !reflect.DeepEqual(project.Manifest, tt.wantManifest) {
+				t.Errorf("got the manifest %+v, want %+v", project.Manifest, tt.wantManifest)
+			}
+			// The manifest file does not change what the project is made of
+			if project.Type != "Python" || project.BuildSystem != "pyproject.toml" {
+				t.Errorf("got the type %q and the build system %q, want Python and pyproject.toml", project.Type, project.BuildSystem)
+			}
+		})
+	}
+	if _, err := CollectFS(context.Background(), fsys, WithManifestFile("setup.py")); err == nil {
+		t.Error("got no error for a manifest file that can not be read")
+	}
+}
@@ -0,0 +1,227 @@
+package codesum
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultNearDuplicateThreshold is the similarity, in percent, from which files are reported as possible
+// duplicates, see WithNearDuplicates
+const DefaultNearDuplicateThreshold = 80
+
+// NearDuplicate is a pair of files in the same language that are very similar, see WithNearDuplicates
+type NearDuplicate struct {
+	Files []string `json:"files"`
+	// Similarity is the percentage of the shingles of the two files that they have in common
+	Similarity int `json:"similarity"`
+}
+
+const (
+	// shingleSize is the number of tokens in each shingle
+	shingleSize = 5
+	// minShingles is the number of shingles that a file needs to be compared, since small files are similar by chance
+	minShingles = 20
+	// minHashBands and minHashRows are the number of bands of the MinHash signature and the number of values in
+	// each. Files that have the same values in at least one band are compared. With 8 bands of 4 values, files
+	// that are 80% similar are compared with a probability of 98.5%, and files that are 30% similar with 6%.
+	minHashBands = 8
+	minHashRows  = 4
+)
+
+// lineCommentPrefixes are the prefixes of the line comments per language, where it is not "//"
+var lineCommentPrefixes = map[string]string{
+	"Python":           "#",
+	"Cap'n Proto":      "#",
+	"Markdown":         "",
+	"ASCIIDoc":         "",
+	"reStructuredText": "",
+	"Plain text":       "",
+}
+
+// shingleFile returns the sorted and unique hashes of the shingles of the tokens of a file, where the comments and
+// the whitespace are left out
+func shingleFile(contents, language string) []uint64 {
+	tokens := tokenize(stripComments(contents, language))
+	if len(tokens) < shingleSize {
+		return nil
+	}
+	seen := make(map[uint64]bool)
+	var shingles []uint64
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		h := fnv.New64a()
+		for _, token := range tokens[i : i+shingleSize] {
+			h.Write([]byte(token))
+			h.Write([]byte{0})
+		}
+		if sum := h.Sum64(); !seen[sum] {
+			seen[sum] = true
+			shingles = append(shingles, sum)
+		}
+	}
+	sort.Slice(shingles, func(i, j int) bool { return shingles[i] < shingles[j] })
+	return shingles
+}
+
+// stripComments removes the comments from source code, as a heuristic that does not know about string literals.
+// Python uses "#" for line comments, the C-family languages use "//" and "/* */", and text formats have none.
+func stripComments(src, language string) string {
+	prefix, ok := lineCommentPrefixes[language]
+	if !ok {
+		prefix = "//"
+	}
+	if prefix == "" {
+		return src
+	}
+	var sb strings.Builder
+	for i := 0; i < len(src); i++ {
+		switch {
+		case strings.HasPrefix(src[i:], prefix):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			sb.WriteByte('\n')
+		case prefix == "//" && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return sb.String()
+			}
+			i += end + 3
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte(src[i])
+		}
+	}
+	return sb.String()
+}
+
+// tokenize splits source code into identifiers, numbers and single punctuation characters
+func tokenize(src string) []string {
+	var tokens []string
+	start := -1
+	for i, r := range src {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+		if word && start < 0 {
+			start = i
+		}
+		if !word {
+			if start >= 0 {
+				tokens = append(tokens, src[start:i])
+				start = -1
+			}
+			if !unicode.IsSpace(r) {
+				tokens = append(tokens, string(r))
+			}
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, src[start:])
+	}
+	return tokens
+}
+
+// minHash returns the MinHash signature of the shingles, with one minimum per hash function
+func minHash(shingles []uint64) [minHashBands * minHashRows]uint64 {
+	var signature [minHashBands * minHashRows]uint64
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	for _, shingle := range shingles {
+		for i := range signature {
+			if h := mix64(shingle ^ uint64(i+1)*0x9e3779b97f4a7c15); h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// mix64 is the finalizer of SplitMix64, which spreads the bits of x
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// jaccard returns the share of the shingles that two sorted sets of shingles have in common
+func jaccard(a, b []uint64) float64 {
+	common := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			common++
+			i++
+			j++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// findNearDuplicates returns the pairs of files in the same language that are at least threshold percent similar,
+// sorted by similarity and then by path. Only the files that have the same values in a band of their MinHash
+// signatures are compared, so that not every pair of files is compared.
+func findNearDuplicates(files []FileInfo, threshold float64) []NearDuplicate {
+	type bandKey struct {
+		language string
+		band     int
+		hash     uint64
+	}
+	shingles := make([][]uint64, len(files))
+	buckets := make(map[bandKey][]int)
+	for i, file := range files {
+		if file.ContentEncoding != "" {
+			continue
+		}
+		if shingles[i] = shingleFile(file.Contents, file.Language); len(shingles[i]) < minShingles {
+			continue
+		}
+		signature := minHash(shingles[i])
+		for band := 0; band < minHashBands; band++ {
+			h := uint64(0)
+			for _, value := range signature[band*minHashRows : (band+1)*minHashRows] {
+				h = mix64(h ^ value)
+			}
+			key := bandKey{language: file.Language, band: band, hash: h}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	compared := make(map[[2]int]bool)
+	var duplicates []NearDuplicate
+	for _, bucket := range buckets {
+		for x := 0; x < len(bucket); x++ {
+			for y := x + 1; y < len(bucket); y++ {
+				pair := [2]int{bucket[x], bucket[y]}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				similarity := 100 * jaccard(shingles[pair[0]], shingles[pair[1]])
+				if similarity >= threshold {
+					duplicates = append(duplicates, NearDuplicate{
+						Files:      []string{files[pair[0]].Path, files[pair[1]].Path},
+						Similarity: int(similarity),
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Similarity != duplicates[j].Similarity {
+			return duplicates[i].Similarity > duplicates[j].Similarity
+		}
+		if duplicates[i].Files[0] != duplicates[j].Files[0] {
+			return duplicates[i].Files[0] < duplicates[j].Files[0]
+		}
+		return duplicates[i].Files[1] < duplicates[j].Files[1]
+	})
+	return duplicates
+}
@@ -0,0 +1,892 @@
+package codesum
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Options control which files are collected and how they are described.
+// Use NewOptions to create validated options.
+type Options struct {
+	IgnoreFiles         []string
+	Languages           []string
+	MaxFileSize         int64
+	Concurrency         int
+	GitMetadata         bool
+	LocalTime           bool
+	LegacyTimestamps    bool
+	MaxPerLanguage      int
+	MaxDepth            int
+	DirBudget           int64
+	Enrichers           []Enricher
+	SkipContents        bool
+	Time                time.Time
+	Submodules          bool
+	Hashes              bool
+	Logger              *slog.Logger
+	Changelog           int
+	Exclude             []string
+	Include             []string
+	Extensions          []string
+	Sort                SortOrder
+	Reverse             bool
+	MaxFiles            int
+	TrimEdges           bool
+	RunID               RunIDMode
+	Strict              bool
+	ChangelogFiles      bool
+	RelativeTo          string
+	AbsolutePaths       bool
+	Untested            bool
+	Name                string
+	Recent              int
+	Distribution        bool
+	Assets              bool
+	EmbedBinary         []string
+	Imports             bool
+	DominantOnly        bool
+	ManifestFile        string
+	TopLanguages        int
+	DirReadmes          bool
+	Roles               []string
+	RoleDirs            map[string]string
+	TypeThreshold       float64
+	GoEmbed             bool
+	NearDuplicates      float64
+	StripLicenses       bool
+	FailOnCaseCollision bool
+	Authors             []string
+	AuthorMode          AuthorMode
+	AuthorCommits       int
+
+	// ExtensionLanguages maps extensions, like ".rb", to languages, like "Ruby", see WithExtensionLanguages
+	ExtensionLanguages map[string]string
+	// OnlyExtensionLanguages only collects the extensions of ExtensionLanguages, see WithOnlyExtensionLanguages
+	OnlyExtensionLanguages bool
+	// NoCommonIgnores does not skip the common directories, like vendor and test, see WithCommonIgnores
+	NoCommonIgnores bool
+	// MaxContentSize leaves out the contents of the files that are larger than it, see WithMaxContentSize
+	MaxContentSize int64
+
+	// DropLargestPercent is the percentage of the files to drop, largest first, see WithDropLargestPercent
+	DropLargestPercent float64
+
+	// ListOnly only stats the files, without reading or hashing them, see WithListOnly
+	ListOnly bool
+	// ListLineCounts counts the lines of the files when ListOnly is set, see WithListOnly
+	ListLineCounts bool
+
+	// root is the directory that is being collected, if any
+	root string
+	// name is the project name when it is not found in go.mod and not given with WithName,
+	// if not the name of the root directory
+	name string
+	// prefix is prepended to the paths, see CollectRoots
+	prefix string
+	// keepHashes keeps FileInfo.Hash when it is only needed for the run ID and the content hash, so that they
+	// can be created again for merged projects
+	keepHashes bool
+}
+
+// Option is a functional option for NewOptions and Collect
+type Option func(*Options) error
+
+// NewOptions returns the default options, modified by the given options and then validated
+func NewOptions(opts ...Option) (Options, error) {
+	o := Options{
+		IgnoreFiles:   []string{".ignore", ".gitignore", ".codesumignore"},
+		Concurrency:   runtime.NumCPU(),
+		MaxDepth:      DefaultMaxDepth,
+		TypeThreshold: DefaultTypeThreshold,
+		AuthorMode:    AuthorLast,
+		AuthorCommits: DefaultAuthorCommits,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return Options{}, err
+		}
+	}
+	if o.ListOnly {
+		o.SkipContents = true
+	}
+	if err := o.Validate(); err != nil {
+		return Options{}, err
+	}
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return o, nil
+}
+
+// Validate checks that the options are consistent
+func (o Options) Validate() error {
+	if o.OnlyExtensionLanguages && len(o.ExtensionLanguages) == 0 {
+		return errors.New("only the extensions with a given language are collected, but none are given")
+	}
+	for _, lang := range o.Languages {
+		if !o.isKnownLanguage(lang) {
+			return fmt.Errorf("unknown language %q (known languages: %s)", lang, strings.Join(o.knownLanguages(), ", "))
+		}
+	}
+	for _, ext := range o.Extensions {
+		if !o.recognizedExtension(ext) {
+			return fmt.Errorf("unknown extension %q", ext)
+		}
+	}
+	if o.MaxFileSize < 0 {
+		return fmt.Errorf("the maximum file size can not be negative, got %d", o.MaxFileSize)
+	}
+	if o.MaxContentSize < 0 {
+		return fmt.Errorf("the maximum content size can not be negative, got %d", o.MaxContentSize)
+	}
+	if o.Concurrency < 1 {
+		return fmt.Errorf("the concurrency must be at least 1, got %d", o.Concurrency)
+	}
+	if o.MaxPerLanguage < 0 {
+		return fmt.Errorf("the maximum number of files per language can not be negative, got %d", o.MaxPerLanguage)
+	}
+	if o.SkipContents && o.hasEnricher(outlineEnricher{}) {
+		return errors.New("the outline can not be created without the file contents")
+	}
+	if o.SkipContents && o.hasEnricher(summaryEnricher{}) {
+		return errors.New("the summaries can not be extracted without the file contents")
+	}
+	if o.SkipContents && o.hasEnricher(signaturesEnricher{}) {
+		return errors.New("the signatures can not be extracted without the file contents")
+	}
+	if o.SkipContents && o.Imports {
+		return errors.New("the imports can not be classified without the file contents")
+	}
+	if o.SkipContents && o.DirReadmes {
+		return errors.New("the README files of the directories can not be included without the file contents")
+	}
+	if o.AuthorMode != "" && !o.AuthorMode.valid() {
+		return fmt.Errorf("unknown author mode %q, must be one of: %s", o.AuthorMode, joinAuthorModes(", "))
+	}
+	if o.AuthorMode == AuthorAnyRecent && o.AuthorCommits < 1 {
+		return fmt.Errorf("the number of recent commits to look for the authors in must be at least 1, got %d", o.AuthorCommits)
+	}
+	if o.SkipContents && o.StripLicenses {
+		return errors.New("the license headers can not be stripped without the file contents")
+	}
+	if o.SkipContents && o.NearDuplicates > 0 {
+		return errors.New("the near duplicates can not be found without the file contents")
+	}
+	if o.NearDuplicates < 0 || o.NearDuplicates > 100 {
+		return fmt.Errorf("the near duplicate threshold must be a percentage from 0 to 100, got %g", o.NearDuplicates)
+	}
+	if o.DirBudget < 0 {
+		return fmt.Errorf("the directory budget can not be negative, got %d", o.DirBudget)
+	}
+	if o.RunID != RunIDNone && !o.RunID.valid() {
+		return fmt.Errorf("unknown run ID mode %q, must be one of: %s", o.RunID, joinRunIDModes(", "))
+	}
+	if o.Changelog < 0 {
+		return fmt.Errorf("the number of changelog entries can not be negative, got %d", o.Changelog)
+	}
+	if o.RelativeTo != "" && o.AbsolutePaths {
+		return errors.New("the paths can not be both relative to a directory and absolute")
+	}
+	if o.Sort != SortDefault && !o.Sort.valid() {
+		return fmt.Errorf("unknown sort order %q, must be one of: %s", o.Sort, joinSortOrders(", "))
+	}
+	if o.DropLargestPercent < 0 || o.DropLargestPercent >= 100 {
+		return fmt.Errorf("the percentage of the largest files to drop must be at least 0 and less than 100, got %g", o.DropLargestPercent)
+	}
+	for _, role := range o.Roles {
+		if !slices.Contains(o.knownRoles(), role) {
+			return fmt.Errorf("unknown role %q (known roles: %s)", role, strings.Join(o.knownRoles(), ", "))
+		}
+	}
+	if o.TypeThreshold < 0 || o.TypeThreshold > 100 {
+		return fmt.Errorf("the type threshold must be a percentage from 0 to 100, got %g", o.TypeThreshold)
+	}
+	if o.TopLanguages < 0 {
+		return fmt.Errorf("the number of languages can not be negative, got %d", o.TopLanguages)
+	}
+	if o.Recent < 0 {
+		return fmt.Errorf("the number of recently modified files can not be negative, got %d", o.Recent)
+	}
+	if o.MaxFiles < 0 {
+		return fmt.Errorf("the maximum number of files can not be negative, got %d", o.MaxFiles)
+	}
+	if o.MaxDepth < 0 {
+		return fmt.Errorf("the maximum directory depth can not be negative, got %d", o.MaxDepth)
+	}
+	return nil
+}
+
+// WithIgnoreFiles sets the files in the root directory that ignore patterns are read from.
+// The default is .ignore, .gitignore and .codesumignore.
+func WithIgnoreFiles(filenames ...string) Option {
+	return func(o *Options) error {
+		o.IgnoreFiles = filenames
+		return nil
+	}
+}
+
+// WithCommonIgnores skips the common directories that are rarely wanted, like vendor, test and node_modules, as if
+// they were in an ignore file. A line like "!test" in an ignore file removes one of them. The default is true.
+func WithCommonIgnores(enabled bool) Option {
+	return func(o *Options) error {
+		o.NoCommonIgnores = !enabled
+		return nil
+	}
+}
+
+// WithLanguages only includes files of the given languages, like "Go" or "python".
+// The names are case-insensitive. The default is to include all languages.
+func WithLanguages(languages ...string) Option {
+	return func(o *Options) error {
+		o.Languages = languages
+		return nil
+	}
+}
+
+// WithMaxFileSize skips files that are larger than n bytes. The default is 0, for no limit.
+func WithMaxFileSize(n int64) Option {
+	return func(o *Options) error {
+		o.MaxFileSize = n
+		return nil
+	}
+}
+
+// WithMaxContentSize leaves out the contents of the files that are larger than n bytes, without reading them into
+// memory, and sets FileInfo.Truncated. The files are still listed, with their lines counted. Binary files that are
+// embedded with WithEmbedBinary have their own limit. The default is 0, for no limit.
+func WithMaxContentSize(n int64) Option {
+	return func(o *Options) error {
+		o.MaxContentSize = n
+		return nil
+	}
+}
+
+// WithConcurrency sets how many files are read in parallel. The default is the number of CPUs.
+func WithConcurrency(n int) Option {
+	return func(o *Options) error {
+		o.Concurrency = n
+		return nil
+	}
+}
+
+// WithGitMetadata adds the last commit of each file, by running git log once.
+// The default is false.
+func WithGitMetadata(enabled bool) Option {
+	return func(o *Options) error {
+		o.GitMetadata = enabled
+		return nil
+	}
+}
+
+// WithLocalTime formats timestamps in the local time zone instead of in UTC. The default is false.
+func WithLocalTime(enabled bool) Option {
+	return func(o *Options) error {
+		o.LocalTime = enabled
+		return nil
+	}
+}
+
+// WithLegacyTimestamps also sets FileInfo.LastModifiedLegacy. The default is false.
+func WithLegacyTimestamps(enabled bool) Option {
+	return func(o *Options) error {
+		o.LegacyTimestamps = enabled
+		return nil
+	}
+}
+
+// WithMaxPerLanguage includes at most n files per language. The default is 0, for no limit.
+func WithMaxPerLanguage(n int) Option {
+	return func(o *Options) error {
+		o.MaxPerLanguage = n
+		return nil
+	}
+}
+
+// WithMaxDepth fails the collection if the directory tree is deeper than n levels below the root.
+// The default is DefaultMaxDepth. 0 means no limit.
+func WithMaxDepth(n int) Option {
+	return func(o *Options) error {
+		o.MaxDepth = n
+		return nil
+	}
+}
+
+// WithDirBudget limits the total size of the files in each top-level directory to n bytes.
+// Files are kept in sort order, and files that do not fit are omitted. The default is 0, for no limit.
+func WithDirBudget(n int64) Option {
+	return func(o *Options) error {
+		o.DirBudget = n
+		return nil
+	}
+}
+
+// WithEnrichers adds enrichers that are called for each file after it has been collected.
+// Errors from enrichers are collected per file in ProjectInfo.EnrichErrors. The default is no enrichers.
+func WithEnrichers(enrichers ...Enricher) Option {
+	return func(o *Options) error {
+		o.Enrichers = append(o.Enrichers, enrichers...)
+		return nil
+	}
+}
+
+// WithoutContents leaves FileInfo.Contents empty, and avoids reading whole files into memory.
+// The line counts are still computed. The default is false.
+func WithoutContents(enabled bool) Option {
+	return func(o *Options) error {
+		o.SkipContents = enabled
+		return nil
+	}
+}
+
+// WithListOnly only collects the paths and the metadata from the walk and a stat of each file, for listing the
+// files that would be included. The files are not read, so FileInfo.Contents, Hash and MimeType are left empty,
+// and FileInfo.LineCount is 0, unless lineCounts is true, which reads each file once to count its lines.
+// This implies WithoutContents. The default is false.
+func WithListOnly(enabled, lineCounts bool) Option {
+	return func(o *Options) error {
+		o.ListOnly = enabled
+		o.ListLineCounts = lineCounts
+		return nil
+	}
+}
+
+// WithOutline adds the top-level declarations of Go and Python files to FileInfo.Outline.
+// Go files are parsed with go/parser, while Python files are scanned line by line.
+// This needs the file contents. The default is false.
+func WithOutline(enabled bool) Option {
+	return func(o *Options) error {
+		if enabled {
+			o.Enrichers = append(o.Enrichers, outlineEnricher{})
+		}
+		return nil
+	}
+}
+
+// WithSummaries sets FileInfo.Summary to the first sentence of the leading doc comment of Go, Python and Rust files:
+// the package comment in Go, the module docstring in Python and the //! or /// comment at the top in Rust.
+// This needs the file contents. The default is false.
+func WithSummaries(enabled bool) Option {
+	return func(o *Options) error {
+		if enabled {
+			o.Enrichers = append(o.Enrichers, summaryEnricher{})
+		}
+		return nil
+	}
+}
+
+// WithImports classifies the imports of each Go file as standard library, internal or external imports in
+// FileInfo.Imports, where internal imports are in the module of go.mod, and lists the external modules with the
+// files that import them in ProjectInfo.ExternalDependencies. The modules come from the require directives in
+// go.mod, including any major version suffix. This needs the file contents. The default is false.
+func WithImports(enabled bool) Option {
+	return func(o *Options) error {
+		o.Imports = enabled
+		return nil
+	}
+}
+
+// WithDirReadmes lists the directories that have a README.md file among the collected files in
+// ProjectInfo.Directories, with the contents of the README files, cut off at MaxDirReadmeSize. With
+// RenderOptions.GroupByDirectory, they introduce the sections of the directories. This needs the file contents.
+// The default is false.
+func WithDirReadmes(enabled bool) Option {
+	return func(o *Options) error {
+		o.DirReadmes = enabled
+		return nil
+	}
+}
+
+// WithRoleDirs changes the roles that the conventional directory names give the files below them in FileInfo.Role,
+// like "entrypoint" for cmd/, by adding to DefaultRoleDirs or replacing its entries. An empty role removes the
+// directory name from the table.
+func WithRoleDirs(dirs map[string]string) Option {
+	return func(o *Options) error {
+		table := make(map[string]string)
+		for dir, role := range o.roleDirs() {
+			table[dir] = role
+		}
+		for dir, role := range dirs {
+			if dir == "" || strings.Contains(dir, "/") {
+				return fmt.Errorf("invalid role directory %q, which must be one directory name", dir)
+			}
+			if role == "" {
+				delete(table, dir)
+				continue
+			}
+			table[dir] = role
+		}
+		o.RoleDirs = table
+		return nil
+	}
+}
+
+// WithRoles only includes the files with one of the given roles, see WithRoleDirs. The default is all files,
+// also those without a role.
+func WithRoles(roles ...string) Option {
+	return func(o *Options) error {
+		o.Roles = append(o.Roles, roles...)
+		return nil
+	}
+}
+
+// WithTypeThreshold sets the share of the lines, in percent, that a language needs to be ProjectInfo.Type.
+// When no language has it, the type is MixedType. A threshold of 0 makes the language with the most files the
+// type. The default is DefaultTypeThreshold.
+func WithTypeThreshold(percent float64) Option {
+	return func(o *Options) error {
+		o.TypeThreshold = percent
+		return nil
+	}
+}
+
+// WithGoEmbed includes the files that the //go:embed directives of the collected Go files refer to, even if their
+// extensions are not recognized, with FileInfo.Reason set to "embedded by" and the Go file. The patterns are
+// resolved like the go command does. Files that are binary or larger than MaxGoEmbedSize are listed in
+// ProjectInfo.EmbeddedAssets instead. The default is false.
+func WithGoEmbed(enabled bool) Option {
+	return func(o *Options) error {
+		o.GoEmbed = enabled
+		return nil
+	}
+}
+
+// WithNearDuplicates lists the pairs of files in the same language that are at least the given percentage similar
+// in ProjectInfo.NearDuplicates, like copies of a helper that drifted apart. The comments and the whitespace are
+// left out when comparing, and no files are excluded. Zero disables it, which is the default, and
+// DefaultNearDuplicateThreshold is a good threshold. This needs the file contents.
+func WithNearDuplicates(threshold float64) Option {
+	return func(o *Options) error {
+		o.NearDuplicates = threshold
+		return nil
+	}
+}
+
+// WithStripLicenseHeaders removes the license headers at the start of the files from their contents, and sets
+// FileInfo.LicenseHeaderStripped. A leading comment is only taken to be a license header if it looks like a known
+// license, or if several files start with it, so that the doc comments of the files are kept. The first file with
+// a header gets a one-line note instead. This needs the file contents. The default is false.
+func WithStripLicenseHeaders(enabled bool) Option {
+	return func(o *Options) error {
+		o.StripLicenses = enabled
+		return nil
+	}
+}
+
+// WithFailOnCaseCollision makes collecting fail with ErrCaseCollision if paths only differ in case, like Util.go
+// and util.go, instead of listing them in ProjectInfo.CaseCollisions with a warning. The default is false.
+func WithFailOnCaseCollision(enabled bool) Option {
+	return func(o *Options) error {
+		o.FailOnCaseCollision = enabled
+		return nil
+	}
+}
+
+// WithAuthors only includes the files where the last commit is by one of the given authors, or one of the recent
+// commits, see WithAuthorMode. An author matches if it is a part of the name and email address of the commit
+// author, like "Name <email>", ignoring case. This needs a git repository, and the history is read with one git
+// log for all files. No authors includes all files, which is the default.
+func WithAuthors(authors ...string) Option {
+	return func(o *Options) error {
+		o.Authors = authors
+		return nil
+	}
+}
+
+// WithAuthorMode selects which commits of each file WithAuthors looks at: the last one, for AuthorLast, or the
+// given number of recent commits, for AuthorAnyRecent. The default is AuthorLast.
+func WithAuthorMode(mode AuthorMode, commits int) Option {
+	return func(o *Options) error {
+		o.AuthorMode = mode
+		o.AuthorCommits = commits
+		return nil
+	}
+}
+
+// WithSignatures replaces the contents of C, C++ and header files with their declarations, like function
+// prototypes and struct declarations, and sets FileInfo.SignaturesOnly. The declarations are found with a
+// heuristic, not a parser, see cSignatures. This needs the file contents. The default is false.
+func WithSignatures(enabled bool) Option {
+	return func(o *Options) error {
+		if enabled {
+			o.Enrichers = append(o.Enrichers, signaturesEnricher{})
+		}
+		return nil
+	}
+}
+
+// WithDominantOnly only includes the files of the main language of the project, as detected from the files
+// that were found, and counts the other files in ProjectInfo.OtherLanguageFiles. The main language is the one with
+// the largest share of the lines, like for ProjectInfo.Type, or the one with the most files if the threshold of
+// WithTypeThreshold is 0. It is ignored with a warning when WithLanguages is used too. The default is false.
+func WithDominantOnly(enabled bool) Option {
+	return func(o *Options) error {
+		o.DominantOnly = enabled
+		return nil
+	}
+}
+
+// WithTopLanguages only includes the files of the n languages with the most files, and counts the other files
+// per language in ProjectInfo.ExcludedLanguages. Ties are broken by the number of lines. The default is 0, for all languages.
+func WithTopLanguages(n int) Option {
+	return func(o *Options) error {
+		o.TopLanguages = n
+		return nil
+	}
+}
+
+// WithSubmodules includes the files of initialized git submodules that are listed in .gitmodules,
+// with FileInfo.Submodule set to the name of the submodule. Uninitialized submodules are skipped with a warning.
+// The default is false, which skips all submodule directories.
+func WithSubmodules(enabled bool) Option {
+	return func(o *Options) error {
+		o.Submodules = enabled
+		return nil
+	}
+}
+
+// WithHashes sets FileInfo.Hash to the SHA-256 hash of each file. The default is false.
+func WithHashes(enabled bool) Option {
+	return func(o *Options) error {
+		o.Hashes = enabled
+		return nil
+	}
+}
+
+// WithTime sets the time that is used for ProjectInfo.GeneratedAt, for reproducible output.
+// The default is the time when Collect is called.
+func WithTime(t time.Time) Option {
+	return func(o *Options) error {
+		o.Time = t
+		return nil
+	}
+}
+
+// WithChangelog adds the last n commits, read with git log, to ProjectInfo.Changelog.
+// If withFiles is true, the files that each commit touched are included too.
+// The changelog is left out if the directory is not in a git repository. The default is 0, for no changelog.
+func WithChangelog(n int, withFiles bool) Option {
+	return func(o *Options) error {
+		o.Changelog = n
+		o.ChangelogFiles = withFiles
+		return nil
+	}
+}
+
+// WithStrict makes directories and files that can not be read an error, instead of skipping them.
+// The default is false, where they are listed in ProjectInfo.Errors.
+func WithStrict(enabled bool) Option {
+	return func(o *Options) error {
+		o.Strict = enabled
+		return nil
+	}
+}
+
+// WithRunID sets ProjectInfo.RunID to a UUID that is created in the given mode. The default is RunIDNone.
+func WithRunID(mode RunIDMode) Option {
+	return func(o *Options) error {
+		o.RunID = mode
+		return nil
+	}
+}
+
+// WithTrimEdges removes the leading and trailing blank lines from the contents of each file.
+// Blank lines within the contents are kept, and FileInfo.LineCount is the line count of the file. The default is false.
+func WithTrimEdges(enabled bool) Option {
+	return func(o *Options) error {
+		o.TrimEdges = enabled
+		return nil
+	}
+}
+
+// WithName sets the project name, instead of detecting it from go.mod or the directory name
+func WithName(name string) Option {
+	return func(o *Options) error {
+		o.Name = name
+		return nil
+	}
+}
+
+// WithManifestFile reads the project name, version and dependencies from the given manifest file, relative to
+// the root and with forward slashes, instead of detecting the name from go.mod or the directory name. The file is
+// read as the kind of manifest that its filename shows: go.mod, package.json, Cargo.toml or pyproject.toml.
+// The name given with WithName is still used instead of the name in the manifest file. An empty path is ignored.
+func WithManifestFile(manifestPath string) Option {
+	return func(o *Options) error {
+		if manifestPath == "" {
+			return nil
+		}
+		if !fs.ValidPath(manifestPath) {
+			return fmt.Errorf("invalid manifest file %q, which must be relative to the root and with forward slashes", manifestPath)
+		}
+		if _, ok := manifestParsers[path.Base(manifestPath)]; !ok {
+			return fmt.Errorf("unknown manifest file %s, must be one of: %s", manifestPath, strings.Join(manifestFilenames(), ", "))
+		}
+		o.ManifestFile = manifestPath
+		return nil
+	}
+}
+
+// WithUntested only keeps the Go files that have no test file, for a report of the gaps in the tests.
+// A file like foo.go has a test file if foo_test.go is in the same directory, or if the directory has a
+// test file named after it, like codesum/codesum_test.go, which counts for the whole package.
+// The test files themselves and the files in other languages are left out. The default is false.
+func WithUntested(enabled bool) Option {
+	return func(o *Options) error {
+		o.Untested = enabled
+		return nil
+	}
+}
+
+// WithExclude skips the files that match any of the given path.Match patterns,
+// like "docs/*.md", relative to the root and with forward slashes.
+func WithExclude(patterns ...string) Option {
+	return func(o *Options) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+		}
+		o.Exclude = append(o.Exclude, patterns...)
+		return nil
+	}
+}
+
+// WithEmbedBinary includes the files that match any of the given patterns as base64, even though they are
+// binary, with BinaryLanguage as the language and ContentEncodingBase64 as the content encoding. The patterns are
+// matched like the patterns of WithExclude. Collecting fails with ErrBinaryTooLarge if a matching file is larger
+// than MaxEmbeddedBinarySize.
+func WithEmbedBinary(patterns ...string) Option {
+	return func(o *Options) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid embed-binary pattern %q: %w", pattern, err)
+			}
+		}
+		o.EmbedBinary = append(o.EmbedBinary, patterns...)
+		return nil
+	}
+}
+
+// WithRelativeTo reports the paths relative to the given directory instead of to the root.
+// Paths that are outside of dir are reported as absolute paths, with a warning.
+// Only available when collecting with Collect. The default is the root.
+func WithRelativeTo(dir string) Option {
+	return func(o *Options) error {
+		o.RelativeTo = dir
+		return nil
+	}
+}
+
+// WithAbsolutePaths reports absolute paths, with forward slashes.
+// Only available when collecting with Collect. The default is false.
+func WithAbsolutePaths(enabled bool) Option {
+	return func(o *Options) error {
+		o.AbsolutePaths = enabled
+		return nil
+	}
+}
+
+// WithInclude only collects the files that match any of the given path.Match patterns,
+// like "cmd/*/*.go", relative to the root and with forward slashes. The default is to include all files.
+func WithInclude(patterns ...string) Option {
+	return func(o *Options) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+			}
+		}
+		o.Include = append(o.Include, patterns...)
+		return nil
+	}
+}
+
+// WithExtensions only collects the files with the given extensions, like ".go" or "py".
+// The extensions are case-insensitive. The default is all recognized extensions.
+func WithExtensions(extensions ...string) Option {
+	return func(o *Options) error {
+		for _, ext := range extensions {
+			o.Extensions = append(o.Extensions, normalizeExtension(ext))
+		}
+		return nil
+	}
+}
+
+// WithExtensionLanguages collects the files with the given extensions, like ".rb" or "rb", as the given languages,
+// like "Ruby". The extensions are case-insensitive, and they are added to the built-in ones, where they replace the
+// language of an extension that is already known. The languages can be used with WithLanguages.
+func WithExtensionLanguages(languages map[string]string) Option {
+	return func(o *Options) error {
+		for ext, language := range languages {
+			language = strings.TrimSpace(language)
+			if strings.Trim(ext, ".") == "" || language == "" {
+				return fmt.Errorf("an extension and a language are needed, got %q and %q", ext, language)
+			}
+			if o.ExtensionLanguages == nil {
+				o.ExtensionLanguages = make(map[string]string)
+			}
+			o.ExtensionLanguages[normalizeExtension(ext)] = language
+		}
+		return nil
+	}
+}
+
+// WithOnlyExtensionLanguages only collects the extensions that are given with WithExtensionLanguages, instead of
+// adding them to the built-in ones. The default is false.
+func WithOnlyExtensionLanguages(only bool) Option {
+	return func(o *Options) error {
+		o.OnlyExtensionLanguages = only
+		return nil
+	}
+}
+
+// normalizeExtension returns the extension in lowercase and with a leading dot
+func normalizeExtension(ext string) string {
+	return "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+}
+
+// WithSort sorts the files in the given order. The default is SortDefault, which is by path.
+func WithSort(order SortOrder) Option {
+	return func(o *Options) error {
+		o.Sort = order
+		return nil
+	}
+}
+
+// WithReverse reverses the order of the files, after sorting them. The default is false.
+func WithReverse(enabled bool) Option {
+	return func(o *Options) error {
+		o.Reverse = enabled
+		return nil
+	}
+}
+
+// WithMaxFiles keeps the first n files, after sorting. The default is 0, for no limit.
+func WithMaxFiles(n int) Option {
+	return func(o *Options) error {
+		o.MaxFiles = n
+		return nil
+	}
+}
+
+// WithRecent lists the n most recently modified files in ProjectInfo.Recent. The times are the dates of the
+// last commits when WithGitMetadata is used, and else the modification times, unless several files have the
+// same modification time, like after a clone, and the dates of the last commits are available. The default is 0.
+func WithRecent(n int) Option {
+	return func(o *Options) error {
+		o.Recent = n
+		return nil
+	}
+}
+
+// WithAssets lists the files with extensions that are not recognized, like images and model files, in
+// ProjectInfo.Assets, with their sizes and types as detected from the extensions. Their contents are not read.
+// The default is false.
+func WithAssets(enabled bool) Option {
+	return func(o *Options) error {
+		o.Assets = enabled
+		return nil
+	}
+}
+
+// WithDistribution adds a histogram of the line counts, with fixed buckets, and the median and 90th percentile
+// of the line counts per language, to ProjectInfo.Totals. The default is false.
+func WithDistribution(enabled bool) Option {
+	return func(o *Options) error {
+		o.Distribution = enabled
+		return nil
+	}
+}
+
+// WithDropLargestPercent drops the largest percent of the files by size, rounded down, as a relative alternative
+// to WithMaxFileSize. The dropped files are listed in ProjectInfo.DroppedLargest. The default is 0.
+func WithDropLargestPercent(percent float64) Option {
+	return func(o *Options) error {
+		o.DropLargestPercent = percent
+		return nil
+	}
+}
+
+// WithLogger logs why files are skipped, at the info level, and internal details, at the debug level.
+// The default is to not log anything. Warnings are also returned in ProjectInfo.Warnings.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) error {
+		o.Logger = logger
+		return nil
+	}
+}
+
+// formatTimestamp formats t as RFC3339, in UTC unless o.LocalTime is set
+func (o Options) formatTimestamp(t time.Time) string {
+	if o.LocalTime {
+		return t.Local().Format(time.RFC3339)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// hasEnricher checks if the given enricher has been added
+func (o Options) hasEnricher(enricher Enricher) bool {
+	for _, e := range o.Enrichers {
+		if e == enricher {
+			return true
+		}
+	}
+	return false
+}
+
+// includesLanguage checks if files of the given language should be collected
+func (o Options) includesLanguage(language string) bool {
+	if len(o.Languages) == 0 {
+		return true
+	}
+	for _, lang := range o.Languages {
+		if strings.EqualFold(lang, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded returns the exclude pattern that matches the given file, if any
+func (o Options) excluded(filename string) (string, bool) {
+	for _, pattern := range o.Exclude {
+		if matched, _ := path.Match(pattern, filename); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// included checks if the given file matches the include patterns and extensions, if any.
+// The reason is returned for files that are not included.
+func (o Options) included(filename string) (string, bool) {
+	if len(o.Extensions) > 0 {
+		ext := strings.ToLower(path.Ext(filename))
+		found := false
+		for _, e := range o.Extensions {
+			if ext == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "the extension is not included", false
+		}
+	}
+	if len(o.Include) == 0 {
+		return "", true
+	}
+	for _, pattern := range o.Include {
+		if matched, _ := path.Match(pattern, filename); matched {
+			return "", true
+		}
+	}
+	return "no include pattern matches", false
+}
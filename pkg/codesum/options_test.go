@@ -0,0 +1,67 @@
+package codesum
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{"defaults", nil, ""},
+		{"include, language and size", []Option{WithInclude("cmd/*/*.go"), WithLanguages("go"), WithMaxFileSize(1 << 20)}, ""},
+		{"extension languages", []Option{WithExtensionLanguages(map[string]string{".rb": "Ruby"}), WithOnlyExtensionLanguages(true), WithLanguages("ruby")}, ""},
+		{"without contents, with hashes", []Option{WithoutContents(true), WithHashes(true), WithMaxContentSize(1024)}, ""},
+		{"list with the outline turned off", []Option{WithListOnly(true, true), WithOutline(false)}, ""},
+		{"relative paths", []Option{WithRelativeTo(".."), WithAbsolutePaths(false)}, ""},
+		{"only extension languages without any", []Option{WithOnlyExtensionLanguages(true)}, "none are given"},
+		{"unknown language", []Option{WithLanguages("cobol")}, `unknown language "cobol"`},
+		{"unknown extension", []Option{WithExtensions(".cob")}, `unknown extension ".cob"`},
+		{"custom language for the language filter", []Option{WithLanguages("ruby"), WithExtensionLanguages(map[string]string{"rb": "Ruby"})}, ""},
+		{"negative size", []Option{WithMaxFileSize(-1)}, "maximum file size"},
+		{"no concurrency", []Option{WithConcurrency(0)}, "concurrency"},
+		{"outline without contents", []Option{WithoutContents(true), WithOutline(true)}, "outline"},
+		{"summaries without contents", []Option{WithoutContents(true), WithSummaries(true)}, "summaries"},
+		{"signatures without contents", []Option{WithoutContents(true), WithSignatures(true)}, "signatures"},
+		{"imports without contents", []Option{WithoutContents(true), WithImports(true)}, "imports"},
+		{"readmes without contents", []Option{WithoutContents(true), WithDirReadmes(true)}, "README"},
+		{"license headers without contents", []Option{WithoutContents(true), WithStripLicenseHeaders(true)}, "license headers"},
+		{"near duplicates without contents", []Option{WithoutContents(true), WithNearDuplicates(90)}, "near duplicates"},
+		{"outline when listing", []Option{WithListOnly(true, false), WithOutline(true)}, "outline"},
+		{"relative and absolute paths", []Option{WithRelativeTo(".."), WithAbsolutePaths(true)}, "both relative"},
+		{"unknown sort order", []Option{WithSort("name")}, `unknown sort order "name"`},
+		{"unknown author mode", []Option{WithAuthorMode("first", 5)}, "unknown author mode"},
+		{"no recent commits", []Option{WithAuthorMode(AuthorAnyRecent, 0)}, "at least 1"},
+		{"drop all files", []Option{WithDropLargestPercent(100)}, "less than 100"},
+		{"type threshold", []Option{WithTypeThreshold(101)}, "type threshold"},
+		{"unknown role", []Option{WithRoles("documentation", "nonsense")}, `unknown role "nonsense"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOptions(tt.opts...)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("got the error %v, want none", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("got no error, want one with %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("got the error %v, want one with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOptionCombination(t *testing.T) {
+	// Only main.go is a Go file in the root that is smaller than 100 bytes
+	project, err := CollectFS(context.Background(), fixtureFS(), WithInclude("*.go", "lib/*"), WithLanguages("go"), WithMaxFileSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Files) != 1 || project.Files[0].Path != "main.go" {
+		t.Errorf("got the files %v, want main.go", project.Files)
+	}
+}
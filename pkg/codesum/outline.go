@@ -0,0 +1,128 @@
+package codesum
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// Declaration is a top-level declaration in a source file
+type Declaration struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Line int    `json:"line"`
+}
+
+// outlineEnricher sets FileInfo.Outline for Go and Python files
+type outlineEnricher struct{}
+
+func (outlineEnricher) Enrich(ctx context.Context, f *FileInfo) error {
+	var (
+		outline []Declaration
+		err     error
+	)
+	switch f.Language {
+	case "Go":
+		outline, err = goOutline(f.Path, f.Contents)
+	case "Python":
+		outline = pythonOutline(f.Contents)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f.Outline = outline
+	return nil
+}
+
+// goOutline returns the functions, methods and types that are declared in Go source code
+func goOutline(filename, src string) ([]Declaration, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var outline []Declaration
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			d := Declaration{Name: decl.Name.Name, Kind: "func", Line: fset.Position(decl.Pos()).Line}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				d.Name = fmt.Sprintf("(%s) %s", receiverType(decl.Recv.List[0].Type), decl.Name.Name)
+				d.Kind = "method"
+			}
+			outline = append(outline, d)
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				outline = append(outline, Declaration{Name: typeSpec.Name.Name, Kind: "type", Line: fset.Position(typeSpec.Pos()).Line})
+			}
+		}
+	}
+	return outline, nil
+}
+
+// receiverType returns the type name of a method receiver, like "*Options"
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return "?"
+}
+
+var pythonDeclaration = regexp.MustCompile(`^(\s*)(?:async\s+)?(def|class)\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// pythonOutline returns the top-level functions and classes, and the methods of top-level classes, like goOutline
+// returns the methods of the types, by looking at the indentation of def and class statements
+func pythonOutline(src string) []Declaration {
+	var (
+		outline      []Declaration
+		currentClass string
+		methodIndent string // the indentation of the body of currentClass, once known
+	)
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if indent == "" {
+			currentClass, methodIndent = "", ""
+		} else if currentClass != "" && methodIndent == "" {
+			methodIndent = indent
+		}
+		m := pythonDeclaration.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch {
+		case m[1] == "":
+			outline = append(outline, Declaration{Name: m[3], Kind: m[2], Line: lineNumber})
+			if m[2] == "class" {
+				currentClass = m[3]
+			}
+		case currentClass != "" && m[1] == methodIndent && m[2] == "def":
+			outline = append(outline, Declaration{Name: currentClass + "." + m[3], Kind: "method", Line: lineNumber})
+		}
+	}
+	return outline
+}
@@ -0,0 +1,39 @@
+package codesum
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// formatOutline returns one line per declaration, with the line number, the kind and the name
+func formatOutline(outline []Declaration) []byte {
+	var sb strings.Builder
+	for _, decl := range outline {
+		fmt.Fprintf(&sb, "%d\t%s\t%s\n", decl.Line, decl.Kind, decl.Name)
+	}
+	return []byte(sb.String())
+}
+
+func TestOutlineGolden(t *testing.T) {
+	for _, tt := range []struct{ filename, language string }{
+		{"sample.go", "Go"},
+		{"sample.py", "Python"},
+	} {
+		t.Run(tt.filename, func(t *testing.T) {
+			name := filepath.Join("outline", tt.filename)
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := FileInfo{Path: tt.filename, Language: tt.language, Contents: string(data)}
+			if err := (outlineEnricher{}).Enrich(context.Background(), &file); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".golden", formatOutline(file.Outline))
+		})
+	}
+}
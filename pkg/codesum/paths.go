@@ -0,0 +1,136 @@
+package codesum
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// pathRebaser turns paths relative to the root into paths relative to base, into absolute paths
+// or into paths with a prefix
+type pathRebaser struct {
+	root     string
+	base     string
+	absolute bool
+	prefix   string
+	outside  int
+}
+
+// newPathRebaser returns a rebaser for the given root directory, see WithRelativeTo and WithAbsolutePaths
+func newPathRebaser(root, base string, absolute bool) (*pathRebaser, error) {
+	absRoot, err := realPath(root)
+	if err != nil {
+		return nil, err
+	}
+	r := &pathRebaser{root: absRoot, absolute: absolute}
+	if base != "" {
+		if r.base, err = realPath(base); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// realPath returns the absolute path of dir with the symlinks resolved, so that
+// the same directory reached through different symlinks gives the same path
+func realPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
+
+// rebase returns the given slash separated path, relative to the root, as reported.
+// Paths outside of the base directory are returned as absolute paths.
+func (r *pathRebaser) rebase(name string) string {
+	if r.prefix != "" {
+		return path.Join(r.prefix, name)
+	}
+	abs := filepath.Join(r.root, filepath.FromSlash(name))
+	if r.absolute {
+		return filepath.ToSlash(abs)
+	}
+	rel, err := filepath.Rel(r.base, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		r.outside++
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// rebaseProject rebases all paths in the given project. A warning is returned if any paths were outside of the base directory.
+func (r *pathRebaser) rebaseProject(project *ProjectInfo) []string {
+	for i := range project.Files {
+		project.Files[i].Path = r.rebase(project.Files[i].Path)
+		if project.Files[i].SameFileAs != "" {
+			project.Files[i].SameFileAs = r.rebase(project.Files[i].SameFileAs)
+		}
+		if by, ok := strings.CutPrefix(project.Files[i].Reason, embeddedByPrefix); ok {
+			project.Files[i].Reason = embeddedByPrefix + r.rebase(by)
+		}
+	}
+	for _, fileErrors := range [][]FileError{project.Errors, project.EnrichErrors} {
+		for i := range fileErrors {
+			fileErrors[i].Path = r.rebase(fileErrors[i].Path)
+		}
+	}
+	for i := range project.DroppedLargest {
+		project.DroppedLargest[i] = r.rebase(project.DroppedLargest[i])
+	}
+	for i := range project.Recent {
+		project.Recent[i].Path = r.rebase(project.Recent[i].Path)
+	}
+	for i := range project.Assets {
+		project.Assets[i].Path = r.rebase(project.Assets[i].Path)
+	}
+	for i := range project.EmbeddedAssets {
+		project.EmbeddedAssets[i].Path = r.rebase(project.EmbeddedAssets[i].Path)
+		project.EmbeddedAssets[i].EmbeddedBy = r.rebase(project.EmbeddedAssets[i].EmbeddedBy)
+	}
+	for _, paths := range project.CaseCollisions {
+		for i := range paths {
+			paths[i] = r.rebase(paths[i])
+		}
+	}
+	for _, duplicate := range project.NearDuplicates {
+		for i := range duplicate.Files {
+			duplicate.Files[i] = r.rebase(duplicate.Files[i])
+		}
+	}
+	for _, dependency := range project.ExternalDependencies {
+		for i := range dependency.Files {
+			dependency.Files[i] = r.rebase(dependency.Files[i])
+		}
+	}
+	for i := range project.Directories {
+		project.Directories[i].Path = r.rebase(project.Directories[i].Path)
+		project.Directories[i].Readme = r.rebase(project.Directories[i].Readme)
+	}
+	if project.Manifest != nil {
+		project.Manifest.Path = r.rebase(project.Manifest.Path)
+	}
+	for i := range project.Removed {
+		project.Removed[i] = r.rebase(project.Removed[i])
+	}
+	for i := range project.Changelog {
+		for j := range project.Changelog[i].Files {
+			project.Changelog[i].Files[j] = r.rebase(project.Changelog[i].Files[j])
+		}
+	}
+	if project.OmittedByDirectory != nil {
+		omitted := make(map[string]int, len(project.OmittedByDirectory))
+		for dir, n := range project.OmittedByDirectory {
+			omitted[r.rebase(dir)] = n
+		}
+		project.OmittedByDirectory = omitted
+	}
+	if r.outside > 0 {
+		return []string{fmt.Sprintf("%d paths are outside of %s, so they are absolute", r.outside, r.base)}
+	}
+	return nil
+}
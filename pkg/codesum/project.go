@@ -0,0 +1,94 @@
+package codesum
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// buildSystemMarkers are the files in the root directory that show which build systems are used, in the order they are listed
+var buildSystemMarkers = []struct {
+	filenames []string
+	name      string
+}{
+	{[]string{"Makefile", "GNUmakefile", "makefile"}, "Make"},
+	{[]string{"CMakeLists.txt"}, "CMake"},
+	{[]string{"build.gradle", "build.gradle.kts"}, "Gradle"},
+	{[]string{"Cargo.toml"}, "Cargo"},
+	{[]string{"package.json"}, "npm"},
+	{[]string{"pyproject.toml"}, "pyproject.toml"},
+}
+
+func readProjectName(fsys fs.FS, modFilePath string) (string, error) {
+	file, err := fsys.Open(modFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "module ") {
+			parts := strings.Fields(scanner.Text())
+			if len(parts) > 1 {
+				return parts[1], nil // Return the module name
+			}
+		}
+	}
+	return "", fmt.Errorf("no module declaration found in %s", modFilePath)
+}
+
+func readGitConfig(fsys fs.FS, configFilePath string) (string, error) {
+	file, err := fsys.Open(configFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	inRemoteSection := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "[remote \"origin\"]") {
+			inRemoteSection = true
+		} else if inRemoteSection && strings.Contains(line, "url =") {
+			return strings.TrimSpace(strings.Split(line, "=")[1]), nil
+		} else if inRemoteSection && line == "" {
+			break // Exit if we reach the end of the section
+		}
+	}
+	return "", fmt.Errorf("no URL found in %s", configFilePath)
+}
+
+// detectBuildSystems returns the build systems that have marker files in the root directory, separated by commas.
+// A package.json file only counts if it has scripts.
+func detectBuildSystems(fsys fs.FS) string {
+	var names []string
+	for _, marker := range buildSystemMarkers {
+		for _, filename := range marker.filenames {
+			if _, err := fs.Stat(fsys, filename); err != nil {
+				continue
+			}
+			if filename == "package.json" && !hasPackageScripts(fsys, filename) {
+				continue
+			}
+			names = append(names, marker.name)
+			break
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// hasPackageScripts checks if the given package.json file has any scripts
+func hasPackageScripts(fsys fs.FS, filename string) bool {
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	return json.Unmarshal(data, &pkg) == nil && len(pkg.Scripts) > 0
+}
@@ -0,0 +1,36 @@
+package codesum
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectBuildSystems(t *testing.T) {
+	tests := []struct {
+		name    string
+		markers map[string]string
+		want    string
+	}{
+		{"none", nil, ""},
+		{"make", map[string]string{"GNUmakefile": "all:\n"}, "Make"},
+		{"make and cmake", map[string]string{"Makefile": "all:\n", "CMakeLists.txt": "project(x)\n"}, "Make, CMake"},
+		{"both makefiles count once", map[string]string{"Makefile": "all:\n", "makefile": "all:\n"}, "Make"},
+		{"gradle and cargo", map[string]string{"build.gradle.kts": "", "Cargo.toml": "[package]\n"}, "Gradle, Cargo"},
+		{"npm with scripts", map[string]string{"package.json": `{"scripts": {"build": "tsc"}}`, "pyproject.toml": ""}, "npm, pyproject.toml"},
+		{"npm without scripts", map[string]string{"package.json": `{"name": "x"}`, "Makefile": ""}, "Make"},
+		{"all of them", map[string]string{
+			"pyproject.toml": "", "Cargo.toml": "", "build.gradle": "", "CMakeLists.txt": "", "Makefile": "",
+			"package.json": `{"scripts": {"test": "jest"}}`,
+		}, "Make, CMake, Gradle, Cargo, npm, pyproject.toml"},
+		{"only in the root", map[string]string{"sub/Makefile": "", "Cargo.toml": ""}, "Cargo"},
+	}
+	for _, tt := range tests {
+		fsys := fstest.MapFS{"main.go": {Data: []byte("package main\n")}}
+		for filename, contents := range tt.markers {
+			fsys[filename] = &fstest.MapFile{Data: []byte(contents)}
+		}
+		if got := detectBuildSystems(fsys); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
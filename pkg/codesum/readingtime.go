@@ -0,0 +1,61 @@
+package codesum
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The reading rates that ReadingTime assumes
+const (
+	CodeLinesPerMinute  = 100 // lines of code that are read per minute
+	ProseWordsPerMinute = 200 // words of prose that are read per minute
+)
+
+// proseLanguages are read by the word instead of by the line
+var proseLanguages = map[string]bool{
+	"Markdown":         true,
+	"ASCIIDoc":         true,
+	"reStructuredText": true,
+	"Plain text":       true,
+}
+
+// ReadingTime estimates how long it takes a person to read the file, at CodeLinesPerMinute for code and
+// ProseWordsPerMinute for prose. Prose is counted by the line when the contents are not available.
+func ReadingTime(file FileInfo) time.Duration {
+	if proseLanguages[file.Language] && file.Contents != "" {
+		words := len(strings.Fields(file.Contents))
+		return time.Duration(words) * time.Minute / ProseWordsPerMinute
+	}
+	lines := file.LineCount
+	if lines == 0 && file.Contents != "" {
+		lines = strings.Count(file.Contents, "\n")
+		if !strings.HasSuffix(file.Contents, "\n") {
+			lines++
+		}
+	}
+	return time.Duration(lines) * time.Minute / CodeLinesPerMinute
+}
+
+// totalReadingTime is the sum of the reading times of the files
+func totalReadingTime(files []FileInfo) time.Duration {
+	var total time.Duration
+	for _, file := range files {
+		total += ReadingTime(file)
+	}
+	return total
+}
+
+// formatReadingTime rounds a reading time up to whole minutes, like "3 min" or "1 h 20 min"
+func formatReadingTime(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	switch {
+	case minutes < 1:
+		return "less than a minute"
+	case minutes < 60:
+		return fmt.Sprintf("%d min", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d h", minutes/60)
+	}
+	return fmt.Sprintf("%d h %d min", minutes/60, minutes%60)
+}
@@ -0,0 +1,85 @@
+package codesum
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// MaxDirReadmeSize is the size limit of the contents of each README file that WithDirReadmes includes.
+// Longer contents are cut off at the end of a line.
+const MaxDirReadmeSize = 4 * 1024
+
+// Directory is a directory with a README.md file, see WithDirReadmes
+type Directory struct {
+	Path string `json:"path"`
+	// Readme is the path of the README.md file
+	Readme         string `json:"readme"`
+	ReadmeContents string `json:"readme_contents"`
+	// ReadmeTruncated is true if the contents were cut off at MaxDirReadmeSize
+	ReadmeTruncated bool `json:"readme_truncated,omitempty"`
+}
+
+// isReadme checks if the file is a README.md file, in any case
+func isReadme(filename string) bool {
+	return strings.EqualFold(path.Base(filename), "README.md")
+}
+
+// collectDirReadmes returns the directories that have a README.md file among the collected files, sorted by path
+func collectDirReadmes(files []FileInfo) []Directory {
+	var directories []Directory
+	for _, file := range files {
+		if !isReadme(file.Path) {
+			continue
+		}
+		contents, truncated := truncateAtLine(file.Contents, MaxDirReadmeSize)
+		directories = append(directories, Directory{
+			Path:            path.Dir(file.Path),
+			Readme:          file.Path,
+			ReadmeContents:  contents,
+			ReadmeTruncated: truncated,
+		})
+	}
+	sort.Slice(directories, func(i, j int) bool { return directories[i].Path < directories[j].Path })
+	return directories
+}
+
+// truncateAtLine cuts s off at the end of the last whole line that fits in max bytes, if it is longer
+func truncateAtLine(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	if i := strings.LastIndexByte(s[:max], '\n'); i >= 0 {
+		return s[:i+1], true
+	}
+	return s[:max], true
+}
+
+// demoteHeadings adds levels to the ATX headings of a Markdown document, like "# Auth" to "#### Auth" for three levels,
+// so that it can be placed below a heading. Headings can not go below level 6. Lines in code blocks are left as
+// they are, and a code block that is not closed, like at the end of truncated contents, is closed.
+func demoteHeadings(markdown string, levels int) string {
+	var sb strings.Builder
+	fence := "" // the fence of the code block that the line is in, if any
+	for _, line := range splitLines(markdown) {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" ") == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		case strings.HasPrefix(trimmed, "#"):
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			if rest := trimmed[level:]; level <= 6 && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+				line = strings.Repeat("#", min(level+levels, 6)) + rest
+			}
+		}
+		sb.WriteString(line + "\n")
+	}
+	if fence != "" {
+		sb.WriteString(fence + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,80 @@
+package codesum
+
+import (
+	"sort"
+	"time"
+)
+
+// The sources of the times of the recently modified files
+const (
+	RecentSourceGit     = "git"
+	RecentSourceModTime = "mtime"
+)
+
+// RecentFile is one of the most recently modified files, see WithRecent
+type RecentFile struct {
+	Path         string `json:"path"`
+	LastModified string `json:"last_modified"`
+	// Source is RecentSourceGit for the date of the last commit, or RecentSourceModTime for the modification time
+	Source string    `json:"source"`
+	Time   time.Time `json:"-"`
+}
+
+// recentFiles returns the n most recently modified files, newest first, with the dates of the last commits
+// for the files that are in commits, and else the modification times
+func recentFiles(files []FileInfo, n int, commits map[string]GitInfo, o Options) []RecentFile {
+	recent := make([]RecentFile, 0, len(files))
+	for _, file := range files {
+		r := RecentFile{Path: file.Path, Source: RecentSourceModTime, Time: file.ModTime}
+		if info, ok := commits[file.Path]; ok {
+			r.Source, r.Time = RecentSourceGit, info.Time
+		}
+		r.LastModified = o.formatTimestamp(r.Time)
+		recent = append(recent, r)
+	}
+	sortRecent(recent)
+	return recent[:min(n, len(recent))]
+}
+
+// sortRecent sorts the files newest first, and by path when the times are the same
+func sortRecent(recent []RecentFile) {
+	sort.SliceStable(recent, func(i, j int) bool {
+		if !recent[i].Time.Equal(recent[j].Time) {
+			return recent[i].Time.After(recent[j].Time)
+		}
+		return recent[i].Path < recent[j].Path
+	})
+}
+
+// modTimeTies checks if any two files have the same modification time, which happens when the
+// files were written at the same time, like by git clone
+func modTimeTies(files []FileInfo) bool {
+	seen := make(map[time.Time]bool, len(files))
+	for _, file := range files {
+		t := file.ModTime.Truncate(time.Second)
+		if seen[t] {
+			return true
+		}
+		seen[t] = true
+	}
+	return false
+}
+
+// recentSourceNote says which times the recently modified files are listed by, so that checkout times are not over-trusted
+func recentSourceNote(recent []RecentFile) string {
+	var git, modTime int
+	for _, r := range recent {
+		if r.Source == RecentSourceGit {
+			git++
+		} else {
+			modTime++
+		}
+	}
+	switch {
+	case modTime == 0:
+		return "The times are the dates of the last commits."
+	case git == 0:
+		return "The times are the modification times of the files, which may be when they were checked out."
+	}
+	return "The times are the dates of the last commits, or the modification times for the files without commits, which may be when they were checked out."
+}
@@ -0,0 +1,561 @@
+package codesum
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RenderOptions control how a project is rendered
+type RenderOptions struct {
+	// Template is the text/template used by WriteTemplate
+	Template string
+	// OutlineOnly leaves out the file contents in the Markdown output, for an index of the declarations
+	OutlineOnly bool
+	// GroupByLanguage lists the files under a heading per language in the Markdown output.
+	// Interface definitions, like .proto files, are listed first, under one heading.
+	GroupByLanguage bool
+	// GroupByDirectory lists the files in a section per directory in the Markdown and reStructuredText output,
+	// where the first GroupDepth elements of the path decide the directory. Files in the root are in "(root)".
+	GroupByDirectory bool
+	// GroupDepth is the number of path elements that decide the group for GroupByDirectory. The default, 0, is the same as 1.
+	GroupDepth int
+	// Tight leaves out the blank lines between the sections of the Markdown output, to save tokens.
+	// The file contents are not changed.
+	Tight bool
+	// TimeFormat adds the generation time and the modification time of each file to the Markdown and
+	// reStructuredText output, formatted as one of TimeFormats or as a Go time layout. The default is to leave them out.
+	TimeFormat string
+	// UTC formats the times for TimeFormat in UTC instead of in the local time zone
+	UTC bool
+	// ContentsAsLines writes the contents of each file as an array of lines in the JSON output, instead of as
+	// one string. Joining the lines with "\n" gives the contents back.
+	ContentsAsLines bool
+	// MetricsLine adds a line with the line count, size, modification time and estimated tokens of each file
+	// below its heading, instead of the "Last modified:" line. The modification time needs TimeFormat.
+	MetricsLine bool
+	// ReadingTime adds an estimate of how long it takes to read each file, and the whole project, to the Markdown
+	// and reStructuredText output, see ReadingTime
+	ReadingTime bool
+	// Tree adds the directory tree of the files, with the number of files and lines of each directory, as a
+	// section of the Markdown and reStructuredText output, and as ProjectInfo.Tree in the JSON output
+	Tree bool
+}
+
+// blankLine returns the line that separates the sections of the Markdown output
+func (opts RenderOptions) blankLine() string {
+	if opts.Tight {
+		return ""
+	}
+	return "\n"
+}
+
+// metricsLine returns the metrics of the file on one line, like "lines: 120 · size: 3.4KB · modified: 2 days ago · tokens: ~800".
+// Metrics that were not computed are left out.
+func (opts RenderOptions) metricsLine(file FileInfo, now time.Time) string {
+	var metrics []string
+	if file.Role != "" {
+		metrics = append(metrics, "role: "+file.Role)
+	}
+	if file.LineCount > 0 {
+		metrics = append(metrics, fmt.Sprintf("lines: %d", file.LineCount))
+	}
+	if file.Size > 0 {
+		metrics = append(metrics, "size: "+formatSize(file.Size))
+	}
+	if opts.TimeFormat != "" && !file.ModTime.IsZero() {
+		metrics = append(metrics, "modified: "+opts.formatTime(file.ModTime, now))
+	}
+	if file.Contents != "" {
+		metrics = append(metrics, fmt.Sprintf("tokens: ~%d", EstimateTokens(int64(len(file.Contents)))))
+	}
+	if opts.ReadingTime {
+		metrics = append(metrics, "reading time: "+formatReadingTime(ReadingTime(file)))
+	}
+	return strings.Join(metrics, " · ")
+}
+
+// WriteMarkdown writes the project as a Markdown document
+func WriteMarkdown(w io.Writer, project ProjectInfo, opts RenderOptions) error {
+	bw := bufio.NewWriter(w)
+	blank := opts.blankLine()
+
+	fmt.Fprintf(bw, "# %s\n%s", project.Name, blank)
+	fmt.Fprintf(bw, "* Main language: %s\n", describeType(project))
+	if project.OtherLanguageFiles > 0 {
+		fmt.Fprintf(bw, "* Only the %s files are included, leaving out %s in other languages\n", project.Type, countFiles(project.OtherLanguageFiles))
+	}
+	if len(project.ExcludedLanguages) > 0 {
+		fmt.Fprintf(bw, "* Only the most common languages are included, leaving out %s\n", describeExcludedLanguages(project.ExcludedLanguages))
+	}
+	if project.BuildSystem != "" {
+		fmt.Fprintf(bw, "* Build system: %s\n", project.BuildSystem)
+	}
+	if roles := describeRoles(project.Files); roles != "" {
+		fmt.Fprintf(bw, "* Roles: %s\n", roles)
+	}
+	if manifest := project.Manifest; manifest != nil {
+		fmt.Fprintf(bw, "* Manifest file: %s\n", manifest.Path)
+		if manifest.Version != "" {
+			fmt.Fprintf(bw, "* Version: %s\n", manifest.Version)
+		}
+		if len(manifest.Dependencies) > 0 {
+			fmt.Fprintf(bw, "* Dependencies: %s\n", strings.Join(manifest.Dependencies, ", "))
+		}
+	}
+	fmt.Fprintf(bw, "* Package name: %s\n", project.Repository)
+	if project.ContentHash != "" {
+		fmt.Fprintf(bw, "* Content hash: %s\n", project.ContentHash)
+	}
+	now := documentTime(project)
+	if opts.TimeFormat != "" && !project.GeneratedTime.IsZero() {
+		fmt.Fprintf(bw, "* Generated at: %s\n", opts.formatTime(project.GeneratedTime, now))
+	}
+	if opts.ReadingTime {
+		fmt.Fprintf(bw, "* Reading time: %s\n", formatReadingTime(totalReadingTime(project.Files)))
+	}
+	bw.WriteString(blank)
+	if len(project.Totals.Languages) > 0 {
+		writeLanguageTable(bw, project.Totals)
+		bw.WriteString(blank)
+	}
+
+	if len(project.Changelog) > 0 {
+		bw.WriteString("## Changelog\n" + blank)
+		for _, entry := range project.Changelog {
+			fmt.Fprintf(bw, "* `%.7s` %s (%s, %s)\n", entry.Commit, entry.Subject, entry.Author, entry.Date)
+			for _, path := range entry.Files {
+				fmt.Fprintf(bw, "  * %s\n", path)
+			}
+		}
+		bw.WriteString(blank)
+	}
+
+	if d := project.Totals.Distribution; d != nil {
+		bw.WriteString("## Line counts\n" + blank)
+		var histogram strings.Builder
+		hw := bufio.NewWriter(&histogram)
+		writeHistogram(hw, d, "")
+		hw.Flush()
+		writeCodeBlock(bw, "text", histogram.String())
+		bw.WriteString(blank)
+		fmt.Fprintf(bw, "* All files: median %d lines, 90th percentile %d lines\n", d.Median, d.P90)
+		for _, lang := range sortedKeys(d.Languages) {
+			fmt.Fprintf(bw, "* %s: median %d lines, 90th percentile %d lines\n", lang, d.Languages[lang].Median, d.Languages[lang].P90)
+		}
+		bw.WriteString(blank)
+	}
+
+	if len(project.Recent) > 0 {
+		bw.WriteString("## Recently modified\n" + blank)
+		fmt.Fprintf(bw, "%s\n%s", recentSourceNote(project.Recent), blank)
+		for _, r := range project.Recent {
+			fmt.Fprintf(bw, "* %s (%s)\n", r.Path, opts.recentTime(r, now))
+		}
+		bw.WriteString(blank)
+	}
+
+	if opts.Tree && len(project.Files) > 0 {
+		bw.WriteString("## Directory tree\n" + blank)
+		writeCodeBlock(bw, "text", strings.Join(treeLines(BuildTree(project.Files)), "\n"))
+		bw.WriteString(blank)
+	}
+
+	if len(project.ExternalDependencies) > 0 {
+		bw.WriteString("## External dependencies used\n" + blank)
+		for _, dependency := range project.ExternalDependencies {
+			fmt.Fprintf(bw, "* `%s`, imported by:\n", dependency.Module)
+			for _, path := range dependency.Files {
+				fmt.Fprintf(bw, "  * %s\n", path)
+			}
+		}
+		bw.WriteString(blank)
+	}
+
+	switch {
+	case opts.GroupByDirectory:
+		// The sections per directory replace the source code section
+		names, groups := groupByDirectory(project.Files, opts.GroupDepth)
+		readmes := readmesByGroup(project.Directories)
+		for _, name := range names {
+			fmt.Fprintf(bw, "## %s\n%s%s\n%s", name, blank, groupStats(groups[name]), blank)
+			readme, hasReadme := readmes[name]
+			if hasReadme && strings.TrimSpace(readme.ReadmeContents) != "" {
+				// The README introduces the section, with its headings below those of the files
+				fmt.Fprintf(bw, "%s%s", demoteHeadings(readme.ReadmeContents, 3), blank)
+			}
+			for _, file := range groups[name] {
+				if !hasReadme || file.Path != readme.Readme {
+					writeMarkdownFile(bw, file, opts, "###", now)
+				}
+			}
+		}
+	case opts.GroupByLanguage:
+		bw.WriteString("## Source code\n" + blank)
+		names, groups := groupByLanguage(project.Files)
+		for _, name := range names {
+			fmt.Fprintf(bw, "### %s\n%s", name, blank)
+			for _, file := range groups[name] {
+				writeMarkdownFile(bw, file, opts, "####", now)
+			}
+		}
+	default:
+		bw.WriteString("## Source code\n" + blank)
+		for _, file := range project.Files {
+			writeMarkdownFile(bw, file, opts, "###", now)
+		}
+	}
+
+	if len(project.Assets) > 0 {
+		bw.WriteString("## Other files\n" + blank)
+		for _, group := range groupAssets(project.Assets) {
+			fmt.Fprintf(bw, "* %s: %s\n", group.extension, group.describe())
+		}
+		bw.WriteString(blank)
+	}
+
+	if len(project.EmbeddedAssets) > 0 {
+		bw.WriteString("## Embedded assets\n" + blank)
+		for _, asset := range project.EmbeddedAssets {
+			fmt.Fprintf(bw, "* %s (%s, %s), embedded by %s\n", asset.Path, asset.Type, formatSize(asset.Size), asset.EmbeddedBy)
+		}
+		bw.WriteString(blank)
+	}
+
+	if len(project.Removed) > 0 {
+		bw.WriteString("## Removed files\n" + blank)
+		for _, path := range project.Removed {
+			fmt.Fprintf(bw, "* %s\n", path)
+		}
+		bw.WriteString(blank)
+	}
+
+	if len(project.NearDuplicates) > 0 {
+		bw.WriteString("## Possible duplicates\n" + blank)
+		for _, duplicate := range project.NearDuplicates {
+			fmt.Fprintf(bw, "* %s and %s: %d%% similar\n", duplicate.Files[0], duplicate.Files[1], duplicate.Similarity)
+		}
+		bw.WriteString(blank)
+	}
+
+	if len(project.Omitted) > 0 || len(project.OmittedByDirectory) > 0 || len(project.DroppedLargest) > 0 {
+		bw.WriteString("## Omitted files\n" + blank)
+		for _, lang := range sortedKeys(project.Omitted) {
+			fmt.Fprintf(bw, "* %s: %d files\n", lang, project.Omitted[lang])
+		}
+		for _, dir := range sortedKeys(project.OmittedByDirectory) {
+			label := dir + "/"
+			if dir == "." {
+				label = "(root)"
+			}
+			fmt.Fprintf(bw, "* %s: %d files over the directory budget\n", label, project.OmittedByDirectory[dir])
+		}
+		for _, path := range project.DroppedLargest {
+			fmt.Fprintf(bw, "* %s: one of the largest files\n", path)
+		}
+		bw.WriteString(blank)
+	}
+
+	// bufio.Writer keeps the first write error, so checking Flush is enough
+	return bw.Flush()
+}
+
+// groupByLanguage groups the files by languageGroup and returns the sorted group names,
+// with the interface definitions first
+func groupByLanguage(files []FileInfo) ([]string, map[string][]FileInfo) {
+	groups := make(map[string][]FileInfo)
+	for _, file := range files {
+		group := languageGroup(file.Language)
+		groups[group] = append(groups[group], file)
+	}
+	names := sortedKeys(groups)
+	if _, ok := groups[interfaceGroup]; ok {
+		// The interface definitions are the contract between the other parts, so they come first
+		sort.SliceStable(names, func(i, j int) bool { return names[i] == interfaceGroup && names[j] != interfaceGroup })
+	}
+	return names, groups
+}
+
+// rootGroup is the group of the files in the root directory, for RenderOptions.GroupByDirectory
+const rootGroup = "(root)"
+
+// groupByDirectory groups the files by the first depth elements of their directories, and returns the
+// sorted group names, with the root group first. The files are sorted by path within each group.
+func groupByDirectory(files []FileInfo, depth int) ([]string, map[string][]FileInfo) {
+	depth = max(depth, 1)
+	groups := make(map[string][]FileInfo)
+	for _, file := range files {
+		group := rootGroup
+		if dir := path.Dir(file.Path); dir != "." && dir != "/" {
+			elements := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+			group = strings.Join(elements[:min(depth, len(elements))], "/") + "/"
+			if strings.HasPrefix(dir, "/") {
+				group = "/" + group
+			}
+		}
+		groups[group] = append(groups[group], file)
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Path < group[j].Path })
+	}
+	names := sortedKeys(groups)
+	sort.SliceStable(names, func(i, j int) bool { return names[i] == rootGroup && names[j] != rootGroup })
+	return names, groups
+}
+
+// readmesByGroup returns the directories with README files by the group names of groupByDirectory, like "internal/"
+func readmesByGroup(directories []Directory) map[string]Directory {
+	readmes := make(map[string]Directory, len(directories))
+	for _, dir := range directories {
+		group := rootGroup
+		if dir.Path != "." && dir.Path != "/" {
+			group = dir.Path + "/"
+		}
+		readmes[group] = dir
+	}
+	return readmes
+}
+
+// groupStats describes a group of files on one line, like "12 files, 1043 lines, mostly Go"
+func groupStats(files []FileInfo) string {
+	totals := computeTotals(files)
+	dominant, most := "", 0
+	for _, lang := range sortedKeys(totals.Languages) {
+		if n := totals.Languages[lang].Files; n > most {
+			dominant, most = lang, n
+		}
+	}
+	return fmt.Sprintf("%s, %s, mostly %s", countFiles(totals.Files), pluralize(totals.Lines, "line"), dominant)
+}
+
+// countFiles returns the number of files, like "1 file" or "12 files"
+func countFiles(n int) string {
+	return pluralize(n, "file")
+}
+
+// pluralize returns the number with the unit, like "1 line" or "12 lines"
+func pluralize[T int | int64](n T, unit string) string {
+	if n == 1 || n == -1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// pluralizeDelta returns the change with its sign and the unit, like "+1 line" or "-12 lines"
+func pluralizeDelta[T int | int64](n T, unit string) string {
+	if n >= 0 {
+		return "+" + pluralize(n, unit)
+	}
+	return pluralize(n, unit)
+}
+
+// writeLanguageTable writes the number of files, lines and bytes per language, with the most files first, followed
+// by the totals
+func writeLanguageTable(bw *bufio.Writer, totals Totals) {
+	bw.WriteString("| Language | Files | Lines | Size |\n|---|--:|--:|--:|\n")
+	for _, lang := range totals.rankedLanguages() {
+		t := totals.Languages[lang]
+		fmt.Fprintf(bw, "| %s | %d | %d | %s |\n", strings.ReplaceAll(lang, "|", `\|`), t.Files, t.Lines, formatSize(t.Bytes))
+	}
+	fmt.Fprintf(bw, "| Total | %d | %d | %s |\n", totals.Files, totals.Lines, formatSize(totals.Bytes))
+}
+
+// writeMarkdownFile writes the heading, modification time, outline and contents of one file
+func writeMarkdownFile(bw *bufio.Writer, file FileInfo, opts RenderOptions, heading string, now time.Time) {
+	blank := opts.blankLine()
+	title := file.Path
+	if file.Status != "" {
+		title += " (" + file.Status + ")"
+	}
+	if file.SignaturesOnly {
+		title += " (signatures only)"
+	}
+	if file.Reason != "" {
+		title += " (" + file.Reason + ")"
+	}
+	if file.Summary != "" {
+		title += " - " + file.Summary
+	}
+	fmt.Fprintf(bw, "%s %s\n%s", heading, title, blank)
+	if opts.MetricsLine {
+		if metrics := opts.metricsLine(file, now); metrics != "" {
+			fmt.Fprintf(bw, "%s\n%s", metrics, blank)
+		}
+	} else if opts.TimeFormat != "" && !file.ModTime.IsZero() {
+		fmt.Fprintf(bw, "Last modified: %s\n%s", opts.formatTime(file.ModTime, now), blank)
+	}
+	if opts.ReadingTime && !opts.MetricsLine {
+		fmt.Fprintf(bw, "Reading time: %s\n%s", formatReadingTime(ReadingTime(file)), blank)
+	}
+	if len(file.Outline) > 0 {
+		for _, decl := range file.Outline {
+			fmt.Fprintf(bw, "* %s `%s` (line %d)\n", decl.Kind, decl.Name, decl.Line)
+		}
+		bw.WriteString(blank)
+	}
+	if opts.OutlineOnly {
+		return
+	}
+	if file.Diff != "" {
+		writeCodeBlock(bw, "diff", file.Diff)
+		bw.WriteString(blank)
+		return
+	}
+	if file.SameFileAs != "" {
+		fmt.Fprintf(bw, "The same file as %s\n%s", file.SameFileAs, blank)
+		return
+	}
+	if file.Truncated {
+		fmt.Fprintf(bw, "The contents are left out, since the file is %s\n%s", formatSize(file.Size), blank)
+		return
+	}
+	if file.ContentEncoding != "" {
+		fmt.Fprintf(bw, "%s\n%s", binaryNote(file), blank)
+	}
+	writeCodeBlock(bw, fileFence(file), file.Contents)
+	bw.WriteString(blank)
+}
+
+// fileFence returns the language of the code fence for the contents of the file, which is the encoding for binary files
+func fileFence(file FileInfo) string {
+	if file.ContentEncoding != "" {
+		return file.ContentEncoding
+	}
+	return fenceLanguage(file.Language)
+}
+
+// writeCodeBlock writes the contents in a fenced code block, where the fence is one backtick longer than the longest
+// run of backticks in the contents, and at least three, so that fences in the contents do not end the block early.
+// The closing fence is on its own line, also when the contents do not end with a newline.
+func writeCodeBlock(bw *bufio.Writer, language, contents string) {
+	longest, run := 0, 0
+	for i := 0; i < len(contents); i++ {
+		if contents[i] != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(bw, "%s%s\n%s", fence, language, contents)
+	if contents != "" && !strings.HasSuffix(contents, "\n") {
+		bw.WriteByte('\n')
+	}
+	bw.WriteString(fence + "\n")
+}
+
+// codeBlock returns the contents in a fenced code block, see writeCodeBlock
+func codeBlock(language, contents string) string {
+	var sb strings.Builder
+	bw := bufio.NewWriter(&sb)
+	writeCodeBlock(bw, language, contents)
+	bw.Flush()
+	return sb.String()
+}
+
+// describeType describes the type of the project, with the largest shares of the languages if it is mixed,
+// like "Mixed (Go 52%, TypeScript 44%)"
+func describeType(project ProjectInfo) string {
+	if project.Type == MixedType {
+		return fmt.Sprintf("%s (%s)", project.Type, describeShares(project.Totals))
+	}
+	return project.Type
+}
+
+// describeExcludedLanguages lists the languages that were left out, with their number of files, like "Python (3 files)"
+func describeExcludedLanguages(excluded map[string]int) string {
+	var descriptions []string
+	for _, language := range sortedKeys(excluded) {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", language, countFiles(excluded[language])))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// binaryNote points out that the contents of an embedded binary file are encoded, with the original size and hash
+func binaryNote(file FileInfo) string {
+	return fmt.Sprintf("Binary file, encoded as %s. The original is %d bytes, with the SHA-256 hash %s.", file.ContentEncoding, file.Size, file.Hash)
+}
+
+// WriteJSON writes the project as an indented JSON document
+func WriteJSON(w io.Writer, project ProjectInfo, opts RenderOptions) error {
+	if opts.Tree {
+		project.Tree = BuildTree(project.Files)
+	}
+	if opts.ContentsAsLines {
+		return writeIndentedJSON(w, withContentLines(project))
+	}
+	return writeIndentedJSON(w, project)
+}
+
+// projectWithLines is a ProjectInfo with the contents of the files as arrays of lines, see RenderOptions.ContentsAsLines
+type projectWithLines struct {
+	ProjectInfo
+	Files []fileWithLines `json:"files"`
+}
+
+// fileWithLines is a FileInfo with the contents as an array of lines
+type fileWithLines struct {
+	FileInfo
+	Contents []string `json:"contents,omitempty"`
+}
+
+// withContentLines splits the contents of the files on "\n". The fields of the embedded types with the same
+// JSON names are hidden by the new fields.
+func withContentLines(project ProjectInfo) projectWithLines {
+	files := make([]fileWithLines, len(project.Files))
+	for i, file := range project.Files {
+		files[i].FileInfo = file
+		if file.Contents != "" {
+			files[i].Contents = strings.Split(file.Contents, "\n")
+		}
+	}
+	return projectWithLines{ProjectInfo: project, Files: files}
+}
+
+// templateFuncs are the functions that are available to templates. codeblock writes the contents of a file in a
+// code block like the Markdown output does, and fence returns the code fence language of a language name.
+var templateFuncs = template.FuncMap{
+	"codeblock": func(file FileInfo) string {
+		return codeBlock(fileFence(file), file.Contents)
+	},
+	"fence": fenceLanguage,
+}
+
+// WriteTemplate writes the project rendered with the text/template in opts.Template
+func WriteTemplate(w io.Writer, project ProjectInfo, opts RenderOptions) error {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(opts.Template)
+	if err != nil {
+		return fmt.Errorf("could not parse template: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	if err := tmpl.Execute(bw, project); err != nil {
+		return fmt.Errorf("could not execute template: %w", err)
+	}
+	return bw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal JSON: %w", err)
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,276 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// fixtureTime is the modification time of the fixture files and the time of the summaries, so that the output is the same on every run
+var fixtureTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fixtureFS is a small project with a few languages, a file without a trailing newline and a file with a fenced example
+func fixtureFS() fstest.MapFS {
+	file := func(contents string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(contents), Mode: 0o644, ModTime: fixtureTime}
+	}
+	return fstest.MapFS{
+		"go.mod":            file("module example.com/fixture\n\ngo 1.22\n"),
+		"main.go":           file("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(greeting())\n}\n"),
+		"greeting.go":       file("package main\n\n// greeting returns the greeting, for example:\n//\n//\t```\n//\thello\n//\t```\nfunc greeting() string {\n\treturn \"hello\"\n}"),
+		"lib/util.h":        file("#pragma once\n\nint add(int a, int b);\n"),
+		"scripts/build.sh":  file("#!/bin/sh\r\ngo build\r\n"),
+		"vendor/dep/dep.go": file("package dep\n"),
+		".gitignore":        file("*.log\n"),
+		"debug.log":         file("ignored\n"),
+	}
+}
+
+// collectFixture collects the fixture project at the fixture time
+func collectFixture(t testing.TB, opts ...Option) ProjectInfo {
+	t.Helper()
+	opts = append([]Option{WithTime(fixtureTime), WithName("fixture")}, opts...)
+	project, err := CollectFS(context.Background(), fixtureFS(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+// checkGolden compares the output with the golden file in testdata, or writes the golden file with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("the output differs from %s, run go test -update to update it\ngot:\n%s", golden, got)
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	project := collectFixture(t)
+	writers := map[string]func(io.Writer, ProjectInfo, RenderOptions) error{
+		"fixture.md":   WriteMarkdown,
+		"fixture.rst":  WriteRST,
+		"fixture.json": WriteJSON,
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf, project, RenderOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name, buf.Bytes())
+		})
+	}
+}
+
+func TestJSONNoContentsGolden(t *testing.T) {
+	project := collectFixture(t, WithoutContents(true))
+	for _, file := range project.Files {
+		if file.Contents != "" {
+			t.Errorf("%s has contents", file.Path)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "fixture-no-contents.json", buf.Bytes())
+}
+
+func TestJSONReproducible(t *testing.T) {
+	// The fixture is written to a directory, so that the files are read from disk in parallel, like by the CLI
+	root := t.TempDir()
+	for name, file := range fixtureFS() {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, file.Data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, fixtureTime, fixtureTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var first []byte
+	for i := 0; i < 10; i++ {
+		project, err := Collect(context.Background(), root, WithTime(fixtureTime), WithConcurrency(8), WithHashes(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := WriteJSON(&buf, project, RenderOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = buf.Bytes()
+		} else if !bytes.Equal(buf.Bytes(), first) {
+			t.Fatalf("run %d differs from the first run:\n%s\nfirst run:\n%s", i+1, buf.Bytes(), first)
+		}
+	}
+}
+
+func TestMarkdownTreeFence(t *testing.T) {
+	fsys := fstest.MapFS{
+		"```/main.go": &fstest.MapFile{Data: []byte("package main\n"), Mode: 0o644, ModTime: fixtureTime},
+	}
+	project, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime), WithName("fence"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, project, RenderOptions{Tree: true, OutlineOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("````text\n")) {
+		t.Errorf("the fence of the tree is not longer than the backticks in the tree:\n%s", buf.Bytes())
+	}
+}
+
+func TestCodeFences(t *testing.T) {
+	fences := map[string]string{
+		".go":     "go",
+		".cpp":    "cpp",
+		".cc":     "cpp",
+		".hpp":    "cpp",
+		".h":      "cpp",
+		".rs":     "rust",
+		".c":      "c",
+		".py":     "python",
+		".md":     "markdown",
+		".java":   "java",
+		".js":     "javascript",
+		".jsx":    "javascript",
+		".ts":     "typescript",
+		".tsx":    "typescript",
+		".kt":     "kotlin",
+		".proto":  "protobuf",
+		".thrift": "thrift",
+		".capnp":  "capnp",
+	}
+	for ext := range extensionLanguages {
+		if _, ok := fences[ext]; !ok {
+			t.Errorf("the fence of %s is not tested", ext)
+		}
+	}
+	for ext, fence := range fences {
+		t.Run(ext, func(t *testing.T) {
+			fsys := fstest.MapFS{"file" + ext: {Data: []byte("contents\n"), ModTime: fixtureTime}}
+			project, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := WriteMarkdown(&buf, project, RenderOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if want := "\n```" + fence + "\ncontents\n```\n"; !bytes.Contains(buf.Bytes(), []byte(want)) {
+				t.Errorf("the output does not contain %q:\n%s", want, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestCodeBlock(t *testing.T) {
+	tests := []struct {
+		name, language, contents, want string
+	}{
+		{"plain", "go", "package main\n", "```go\npackage main\n```\n"},
+		{"no trailing newline", "go", "package main", "```go\npackage main\n```\n"},
+		{"empty", "text", "", "```text\n```\n"},
+		{"fence in the contents", "markdown", "```go\nx\n```\n", "````markdown\n```go\nx\n```\n````\n"},
+		{"longer backtick run", "text", "a ````` b\n", "``````text\na ````` b\n``````\n"},
+		{"short backtick run", "text", "`x` and ``y``\n", "```text\n`x` and ``y``\n```\n"},
+	}
+	for _, tt := range tests {
+		if got := codeBlock(tt.language, tt.contents); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJSONContentsAsLines(t *testing.T) {
+	fsys := fixtureFS()
+	fsys["crlf.go"] = &fstest.MapFile{Data: []byte("package main\r\n\r\nvar x = 1\r\n"), ModTime: fixtureTime}
+	fsys["empty.go"] = &fstest.MapFile{ModTime: fixtureTime}
+	project, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, project, RenderOptions{ContentsAsLines: true}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Files []struct {
+			Path     string          `json:"path"`
+			Contents json.RawMessage `json:"contents"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Files) != len(project.Files) {
+		t.Fatalf("got %d files, want %d", len(decoded.Files), len(project.Files))
+	}
+	for i, file := range decoded.Files {
+		want := project.Files[i]
+		if file.Path != want.Path {
+			t.Fatalf("file %d is %s, want %s", i, file.Path, want.Path)
+		}
+		if want.Contents == "" {
+			if file.Contents != nil {
+				t.Errorf("%s has the contents %s, want none", file.Path, file.Contents)
+			}
+			continue
+		}
+		var lines []string
+		if err := json.Unmarshal(file.Contents, &lines); err != nil {
+			t.Errorf("the contents of %s are not an array of strings: %v", file.Path, err)
+			continue
+		}
+		if got := strings.Join(lines, "\n"); got != want.Contents {
+			t.Errorf("the lines of %s join to %q, want %q", file.Path, got, want.Contents)
+		}
+	}
+	// greeting.go has no trailing newline, so its last line is not empty
+	for _, file := range decoded.Files {
+		var lines []string
+		json.Unmarshal(file.Contents, &lines)
+		switch file.Path {
+		case "greeting.go":
+			if last := lines[len(lines)-1]; last != "}" {
+				t.Errorf("the last line of greeting.go is %q, want }", last)
+			}
+		case "main.go":
+			if last := lines[len(lines)-1]; last != "" {
+				t.Errorf("the last line of main.go is %q, want an empty line after the trailing newline", last)
+			}
+		case "crlf.go":
+			if lines[0] != "package main\r" {
+				t.Errorf("the first line of crlf.go is %q, want the carriage return kept", lines[0])
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package codesum
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultRoleDirs are the conventional directory names and the roles they give the files below them,
+// see WithRoleDirs. Other classifications of files by directory should use this table too, so that they agree.
+var DefaultRoleDirs = map[string]string{
+	"cmd":        "entrypoint",
+	"internal":   "private",
+	"pkg":        "public",
+	"api":        "contract",
+	"testdata":   "fixture",
+	"migrations": "migration",
+	"examples":   "example",
+	"docs":       "documentation",
+	"scripts":    "script",
+}
+
+// roleDirs returns the table of directory names and roles, which is DefaultRoleDirs unless WithRoleDirs is used
+func (o Options) roleDirs() map[string]string {
+	if o.RoleDirs != nil {
+		return o.RoleDirs
+	}
+	return DefaultRoleDirs
+}
+
+// role returns the role of the slash separated path, from the first directory in it that has a role, if any
+func (o Options) role(filename string) string {
+	dirs := strings.Split(filename, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if role, ok := o.roleDirs()[dir]; ok {
+			return role
+		}
+	}
+	return ""
+}
+
+// includesRole checks if files with the given role are included, see WithRoles
+func (o Options) includesRole(role string) bool {
+	if len(o.Roles) == 0 {
+		return true
+	}
+	for _, r := range o.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// knownRoles returns the roles in the table, sorted
+func (o Options) knownRoles() []string {
+	roles := make(map[string]bool)
+	for _, role := range o.roleDirs() {
+		roles[role] = true
+	}
+	return sortedKeys(roles)
+}
+
+// describeRoles lists the number of files per role, like "entrypoint (3 files), private (12 files)".
+// Files without a role are left out.
+func describeRoles(files []FileInfo) string {
+	counts := make(map[string]int)
+	for _, file := range files {
+		if file.Role != "" {
+			counts[file.Role]++
+		}
+	}
+	var descriptions []string
+	for _, role := range sortedKeys(counts) {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", role, countFiles(counts[role])))
+	}
+	return strings.Join(descriptions, ", ")
+}
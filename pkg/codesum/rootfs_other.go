@@ -0,0 +1,19 @@
+//go:build !windows
+
+package codesum
+
+import (
+	"io/fs"
+	"os"
+)
+
+// rootFS returns the file system of the directory that Collect walks
+func rootFS(root string) fs.FS {
+	return os.DirFS(root)
+}
+
+// reservedName returns the reason why the file or directory at the slash separated path can not be used on this
+// platform, if any. Only Windows has reserved names.
+func reservedName(string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,67 @@
+//go:build windows
+
+package codesum
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the length from which native paths need the \\?\ prefix, which is MAX_PATH minus room for the
+// 8.3 file names that directories need
+const maxPath = 248
+
+// longPathFS is like os.DirFS, but opens the files with the \\?\ prefix when the native path is too long
+// for the Windows API, like in deep node_modules trees. The paths within the file system are unchanged.
+type longPathFS string
+
+// rootFS returns the file system of the directory that Collect walks
+func rootFS(root string) fs.FS {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return longPathFS(root)
+}
+
+func (root longPathFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) || strings.ContainsAny(name, `\:`) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	native := filepath.Join(string(root), filepath.FromSlash(name))
+	if len(native) >= maxPath && !strings.HasPrefix(native, `\\`) {
+		native = `\\?\` + native
+	} else if len(native) >= maxPath && !strings.HasPrefix(native, `\\?\`) {
+		// A UNC path, like \\server\share\dir
+		native = `\\?\UNC\` + native[2:]
+	}
+	f, err := os.Open(native)
+	if err != nil {
+		// The error refers to the path within the file system, like os.DirFS does
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Path = name
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// reservedNames are the device names that Windows reserves in every directory, with any extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// reservedName returns the reason why the file or directory at the slash separated path can not be used on this
+// platform, if any. On Windows, names like aux.h open the AUX device instead of the file.
+func reservedName(name string) (string, bool) {
+	base := path.Base(name)
+	stem, _, _ := strings.Cut(base, ".")
+	if !reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		return "", false
+	}
+	return stem + " is a reserved device name on Windows", true
+}
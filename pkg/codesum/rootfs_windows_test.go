@@ -0,0 +1,97 @@
+//go:build windows
+
+package codesum
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// longPath returns the native path with the \\?\ prefix, so that it can be created even if it is too long
+// or has a reserved name
+func longPath(t *testing.T, native string) string {
+	t.Helper()
+	abs, err := filepath.Abs(native)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return `\\?\` + abs
+}
+
+func TestCollectLongPaths(t *testing.T) {
+	root := t.TempDir()
+	// 40 levels of 10 characters are far beyond MAX_PATH
+	dir := strings.Repeat("directory/", 40)
+	native := filepath.Join(root, filepath.FromSlash(dir))
+	if err := os.MkdirAll(longPath(t, native), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(longPath(t, filepath.Join(native, "deep.go")), []byte("package deep\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := Collect(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Errors) > 0 {
+		t.Errorf("got the errors %v", project.Errors)
+	}
+	if len(project.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(project.Files))
+	}
+	file := project.Files[0]
+	if want := dir + "deep.go"; file.Path != want {
+		t.Errorf("the path is %s, want %s", file.Path, want)
+	}
+	if file.Contents != "package deep\n" {
+		t.Errorf("the contents are %q", file.Contents)
+	}
+}
+
+func TestCollectReservedNames(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"aux.h", "main.c"} {
+		if err := os.WriteFile(longPath(t, filepath.Join(root, name)), []byte("int x;\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(longPath(t, filepath.Join(root, "con")), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	project, err := Collect(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Files) != 1 || project.Files[0].Path != "main.c" {
+		t.Errorf("got the files %v, want only main.c", project.Files)
+	}
+	omitted := make(map[string]string)
+	for _, fileError := range project.Errors {
+		omitted[fileError.Path] = fileError.Error
+	}
+	for _, name := range []string{"aux.h", "con"} {
+		if reason := omitted[name]; !strings.Contains(reason, "reserved device name") {
+			t.Errorf("%s is omitted with the reason %q, want a reserved device name", name, reason)
+		}
+	}
+}
+
+func TestReservedName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"aux.h":        true,
+		"src/CON":      true,
+		"lib/com1.txt": true,
+		"nul.tar.gz":   true,
+		"auxiliary.h":  false,
+		"con/file.go":  false,
+		"com10.c":      false,
+		"main.go":      false,
+	} {
+		if _, got := reservedName(name); got != want {
+			t.Errorf("reservedName(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
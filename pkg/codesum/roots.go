@@ -0,0 +1,216 @@
+package codesum
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CollectRoots collects each of the given directories as a separate project. The paths are prefixed with
+// the directories, as given, unless WithRelativeTo or WithAbsolutePaths is used, so that the paths of
+// different roots never collide. Roots that are inside other roots are rejected for the same reason.
+// Each root uses its own ignore files, and limits like WithMaxPerLanguage apply to each root.
+func CollectRoots(ctx context.Context, roots []string, opts ...Option) ([]ProjectInfo, error) {
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRoots(roots); err != nil {
+		return nil, err
+	}
+	if o.Time.IsZero() {
+		// All roots get the same generation time
+		opts = append(opts[:len(opts):len(opts)], WithTime(time.Now()))
+	}
+	projects := make([]ProjectInfo, 0, len(roots))
+	for _, root := range roots {
+		rootOpts := opts
+		if o.RelativeTo == "" && !o.AbsolutePaths {
+			prefix := filepath.ToSlash(filepath.Clean(root))
+			rootOpts = append(opts[:len(opts):len(opts)], func(o *Options) error {
+				o.prefix = prefix
+				return nil
+			})
+		}
+		project, err := Collect(ctx, root, rootOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", root, err)
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// CollectMerged collects the given directories like CollectRoots, and merges them into one project.
+// The name, unless given with WithName, the repository and the build system of the project list those of the roots,
+// separated by commas.
+func CollectMerged(ctx context.Context, roots []string, opts ...Option) (ProjectInfo, error) {
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	opts = append(opts[:len(opts):len(opts)], func(o *Options) error {
+		o.keepHashes = true
+		return nil
+	})
+	projects, err := CollectRoots(ctx, roots, opts...)
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+
+	merged := ProjectInfo{SchemaVersion: SchemaVersion, Totals: Totals{Languages: make(map[string]LanguageTotals)}}
+	var names, repositories, buildSystems []string
+	changelog := make(map[string]*ChangelogEntry)
+	importers := make(map[string][]string)
+	for i, project := range projects {
+		if i == 0 {
+			merged.GeneratedAt = project.GeneratedAt
+			merged.GeneratedTime = project.GeneratedTime
+		}
+		names = appendUnique(names, project.Name)
+		repositories = appendUnique(repositories, project.Repository)
+		for _, buildSystem := range strings.Split(project.BuildSystem, ", ") {
+			buildSystems = appendUnique(buildSystems, buildSystem)
+		}
+		merged.Files = append(merged.Files, project.Files...)
+		merged.Totals.addTotals(project.Totals)
+		for lang, n := range project.Omitted {
+			if merged.Omitted == nil {
+				merged.Omitted = make(map[string]int)
+			}
+			merged.Omitted[lang] += n
+		}
+		for dir, n := range project.OmittedByDirectory {
+			if merged.OmittedByDirectory == nil {
+				merged.OmittedByDirectory = make(map[string]int)
+			}
+			merged.OmittedByDirectory[dir] += n
+		}
+		// Roots in the same repository have the same commits, with the files of each root
+		for _, entry := range project.Changelog {
+			if existing, ok := changelog[entry.Commit]; ok {
+				existing.Files = append(existing.Files, entry.Files...)
+				continue
+			}
+			entry := entry
+			changelog[entry.Commit] = &entry
+		}
+		merged.DroppedLargest = append(merged.DroppedLargest, project.DroppedLargest...)
+		merged.Recent = append(merged.Recent, project.Recent...)
+		merged.Assets = append(merged.Assets, project.Assets...)
+		merged.EmbeddedAssets = append(merged.EmbeddedAssets, project.EmbeddedAssets...)
+		merged.CaseCollisions = append(merged.CaseCollisions, project.CaseCollisions...)
+		merged.Directories = append(merged.Directories, project.Directories...)
+		merged.OtherLanguageFiles += project.OtherLanguageFiles
+		for language, n := range project.ExcludedLanguages {
+			if merged.ExcludedLanguages == nil {
+				merged.ExcludedLanguages = make(map[string]int)
+			}
+			merged.ExcludedLanguages[language] += n
+		}
+		if merged.Manifest == nil {
+			// The manifest file is read in each root, and the first one describes the merged project
+			merged.Manifest = project.Manifest
+		}
+		for _, dependency := range project.ExternalDependencies {
+			importers[dependency.Module] = append(importers[dependency.Module], dependency.Files...)
+		}
+		merged.Warnings = append(merged.Warnings, project.Warnings...)
+		merged.Errors = append(merged.Errors, project.Errors...)
+		merged.EnrichErrors = append(merged.EnrichErrors, project.EnrichErrors...)
+	}
+	switch o.Sort {
+	case SortPath, SortSize, SortLines, SortModified:
+		// The files of all roots are sorted together, while the files of each root are kept together, in the order
+		// of the roots, by default and for the commit order
+		sortFiles(merged.Files, o.Sort)
+		if o.Reverse {
+			slices.Reverse(merged.Files)
+		}
+	}
+	merged.Name = strings.Join(names, ", ")
+	if o.Name != "" {
+		merged.Name = o.Name
+	}
+	merged.Repository = strings.Join(repositories, ", ")
+	merged.BuildSystem = strings.Join(buildSystems, ", ")
+	merged.Type = merged.Totals.projectType(o.TypeThreshold)
+	if o.Distribution {
+		merged.Totals.Distribution = computeDistribution(merged.Files)
+	}
+	if o.NearDuplicates > 0 {
+		// The files of different roots are compared too
+		merged.NearDuplicates = findNearDuplicates(merged.Files, o.NearDuplicates)
+	}
+	for _, entry := range changelog {
+		merged.Changelog = append(merged.Changelog, *entry)
+	}
+	sort.Slice(merged.Changelog, func(i, j int) bool { return merged.Changelog[i].Time.After(merged.Changelog[j].Time) })
+	if len(merged.Changelog) > o.Changelog {
+		merged.Changelog = merged.Changelog[:o.Changelog]
+	}
+	for _, module := range sortedKeys(importers) {
+		merged.ExternalDependencies = append(merged.ExternalDependencies, ExternalDependency{Module: module, Files: importers[module]})
+	}
+	sortRecent(merged.Recent)
+	if len(merged.Recent) > o.Recent {
+		merged.Recent = merged.Recent[:o.Recent]
+	}
+
+	if merged.RunID, err = newRunID(o.RunID, merged.Files, merged.GeneratedTime); err != nil {
+		return ProjectInfo{}, fmt.Errorf("could not create a run ID: %w", err)
+	}
+	merged.ContentHash = ContentHash(merged.Files)
+	if !o.Hashes {
+		for i := range merged.Files {
+			if merged.Files[i].ContentEncoding == "" {
+				merged.Files[i].Hash = ""
+			}
+		}
+	}
+	return merged, nil
+}
+
+// checkRoots returns an error if a root is given twice or is inside another root
+func checkRoots(roots []string) error {
+	resolved := make([]string, len(roots))
+	for i, root := range roots {
+		var err error
+		if resolved[i], err = realPath(root); err != nil {
+			return err
+		}
+	}
+	for i := range roots {
+		for j := range roots {
+			if i == j {
+				continue
+			}
+			rel, err := filepath.Rel(resolved[j], resolved[i])
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			if rel == "." {
+				return fmt.Errorf("%s and %s are the same directory", roots[j], roots[i])
+			}
+			return fmt.Errorf("%s is inside %s, which would make the paths ambiguous", roots[i], roots[j])
+		}
+	}
+	return nil
+}
+
+// appendUnique appends s to list, unless it is empty or already in the list
+func appendUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
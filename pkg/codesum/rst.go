@@ -0,0 +1,314 @@
+package codesum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// rstLexers are the Pygments lexers of the languages in fenceLanguages that Pygments names differently, or does
+// not have a lexer for
+var rstLexers = map[string]string{
+	"ASCIIDoc": "text",
+}
+
+// rstLexer returns the Pygments lexer that is used for the code blocks of the given language. Languages that are
+// not in fenceLanguages, like those of WithExtensionLanguages, use the "text" lexer, since Sphinx warns about
+// lexers that Pygments does not have.
+func rstLexer(language string) string {
+	if lexer, ok := rstLexers[language]; ok {
+		return lexer
+	}
+	if lexer, ok := fenceLanguages[language]; ok {
+		return lexer
+	}
+	return "text"
+}
+
+// rstEscaper escapes the characters that start inline markup in reStructuredText
+var rstEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "`", "\\`", "_", `\_`, "|", `\|`)
+
+// rstHeading writes a section title, underlined with the given character
+func rstHeading(bw *bufio.Writer, title string, underline byte) {
+	title = rstEscaper.Replace(title)
+	fmt.Fprintf(bw, "%s\n%s\n\n", title, strings.Repeat(string(underline), utf8.RuneCountInString(title)))
+}
+
+// WriteRST writes the project as a reStructuredText document, for Sphinx
+func WriteRST(w io.Writer, project ProjectInfo, opts RenderOptions) error {
+	bw := bufio.NewWriter(w)
+
+	rstHeading(bw, project.Name, '=')
+	fmt.Fprintf(bw, "* Main language: %s\n", rstEscaper.Replace(describeType(project)))
+	if project.OtherLanguageFiles > 0 {
+		fmt.Fprintf(bw, "* Only the %s files are included, leaving out %s in other languages\n", rstEscaper.Replace(project.Type), countFiles(project.OtherLanguageFiles))
+	}
+	if len(project.ExcludedLanguages) > 0 {
+		fmt.Fprintf(bw, "* Only the most common languages are included, leaving out %s\n", rstEscaper.Replace(describeExcludedLanguages(project.ExcludedLanguages)))
+	}
+	if project.BuildSystem != "" {
+		fmt.Fprintf(bw, "* Build system: %s\n", rstEscaper.Replace(project.BuildSystem))
+	}
+	if roles := describeRoles(project.Files); roles != "" {
+		fmt.Fprintf(bw, "* Roles: %s\n", rstEscaper.Replace(roles))
+	}
+	if manifest := project.Manifest; manifest != nil {
+		fmt.Fprintf(bw, "* Manifest file: %s\n", rstEscaper.Replace(manifest.Path))
+		if manifest.Version != "" {
+			fmt.Fprintf(bw, "* Version: %s\n", rstEscaper.Replace(manifest.Version))
+		}
+		if len(manifest.Dependencies) > 0 {
+			fmt.Fprintf(bw, "* Dependencies: %s\n", rstEscaper.Replace(strings.Join(manifest.Dependencies, ", ")))
+		}
+	}
+	fmt.Fprintf(bw, "* Package name: %s\n", rstEscaper.Replace(project.Repository))
+	if project.ContentHash != "" {
+		fmt.Fprintf(bw, "* Content hash: %s\n", project.ContentHash)
+	}
+	now := documentTime(project)
+	if opts.TimeFormat != "" && !project.GeneratedTime.IsZero() {
+		fmt.Fprintf(bw, "* Generated at: %s\n", rstEscaper.Replace(opts.formatTime(project.GeneratedTime, now)))
+	}
+	if opts.ReadingTime {
+		fmt.Fprintf(bw, "* Reading time: %s\n", formatReadingTime(totalReadingTime(project.Files)))
+	}
+	bw.WriteString("\n")
+	if len(project.Totals.Languages) > 0 {
+		writeRSTLanguageTable(bw, project.Totals)
+	}
+
+	if len(project.Changelog) > 0 {
+		rstHeading(bw, "Changelog", '-')
+		for _, entry := range project.Changelog {
+			fmt.Fprintf(bw, "* ``%.7s`` %s (%s, %s)\n", entry.Commit, rstEscaper.Replace(entry.Subject), rstEscaper.Replace(entry.Author), entry.Date)
+			if len(entry.Files) > 0 {
+				bw.WriteString("\n")
+				for _, path := range entry.Files {
+					fmt.Fprintf(bw, "  * %s\n", rstEscaper.Replace(path))
+				}
+				bw.WriteString("\n")
+			}
+		}
+		bw.WriteString("\n")
+	}
+
+	if d := project.Totals.Distribution; d != nil {
+		rstHeading(bw, "Line counts", '-')
+		bw.WriteString("::\n\n")
+		writeHistogram(bw, d, "   ")
+		bw.WriteString("\n")
+		fmt.Fprintf(bw, "* All files: median %d lines, 90th percentile %d lines\n", d.Median, d.P90)
+		for _, lang := range sortedKeys(d.Languages) {
+			fmt.Fprintf(bw, "* %s: median %d lines, 90th percentile %d lines\n", rstEscaper.Replace(lang), d.Languages[lang].Median, d.Languages[lang].P90)
+		}
+		bw.WriteString("\n")
+	}
+
+	if len(project.Recent) > 0 {
+		rstHeading(bw, "Recently modified", '-')
+		fmt.Fprintf(bw, "%s\n\n", recentSourceNote(project.Recent))
+		for _, r := range project.Recent {
+			fmt.Fprintf(bw, "* %s (%s)\n", rstEscaper.Replace(r.Path), rstEscaper.Replace(opts.recentTime(r, now)))
+		}
+		bw.WriteString("\n")
+	}
+
+	if opts.Tree && len(project.Files) > 0 {
+		rstHeading(bw, "Directory tree", '-')
+		writeRSTCodeBlock(bw, "text", strings.Join(treeLines(BuildTree(project.Files)), "\n"))
+	}
+
+	if len(project.ExternalDependencies) > 0 {
+		rstHeading(bw, "External dependencies used", '-')
+		for _, dependency := range project.ExternalDependencies {
+			fmt.Fprintf(bw, "* ``%s``, imported by:\n\n", dependency.Module)
+			for _, path := range dependency.Files {
+				fmt.Fprintf(bw, "  * %s\n", rstEscaper.Replace(path))
+			}
+			bw.WriteString("\n")
+		}
+		bw.WriteString("\n")
+	}
+
+	switch {
+	case opts.GroupByDirectory:
+		names, groups := groupByDirectory(project.Files, opts.GroupDepth)
+		readmes := readmesByGroup(project.Directories)
+		for _, name := range names {
+			rstHeading(bw, name, '-')
+			fmt.Fprintf(bw, "%s\n\n", rstEscaper.Replace(groupStats(groups[name])))
+			readme, hasReadme := readmes[name]
+			if hasReadme && strings.TrimSpace(readme.ReadmeContents) != "" {
+				writeRSTCodeBlock(bw, "markdown", readme.ReadmeContents)
+			}
+			for _, file := range groups[name] {
+				if !hasReadme || file.Path != readme.Readme {
+					writeRSTFile(bw, file, opts, '~', now)
+				}
+			}
+		}
+	case opts.GroupByLanguage:
+		rstHeading(bw, "Source code", '-')
+		names, groups := groupByLanguage(project.Files)
+		for _, name := range names {
+			rstHeading(bw, name, '~')
+			for _, file := range groups[name] {
+				writeRSTFile(bw, file, opts, '^', now)
+			}
+		}
+	default:
+		rstHeading(bw, "Source code", '-')
+		for _, file := range project.Files {
+			writeRSTFile(bw, file, opts, '~', now)
+		}
+	}
+
+	if len(project.Assets) > 0 {
+		rstHeading(bw, "Other files", '-')
+		for _, group := range groupAssets(project.Assets) {
+			fmt.Fprintf(bw, "* %s: %s\n", rstEscaper.Replace(group.extension), group.describe())
+		}
+		bw.WriteString("\n")
+	}
+
+	if len(project.EmbeddedAssets) > 0 {
+		rstHeading(bw, "Embedded assets", '-')
+		for _, asset := range project.EmbeddedAssets {
+			fmt.Fprintf(bw, "* %s (%s, %s), embedded by %s\n", rstEscaper.Replace(asset.Path), asset.Type, formatSize(asset.Size), rstEscaper.Replace(asset.EmbeddedBy))
+		}
+		bw.WriteString("\n")
+	}
+
+	if len(project.Removed) > 0 {
+		rstHeading(bw, "Removed files", '-')
+		for _, path := range project.Removed {
+			fmt.Fprintf(bw, "* %s\n", rstEscaper.Replace(path))
+		}
+		bw.WriteString("\n")
+	}
+
+	if len(project.NearDuplicates) > 0 {
+		rstHeading(bw, "Possible duplicates", '-')
+		for _, duplicate := range project.NearDuplicates {
+			fmt.Fprintf(bw, "* %s and %s: %d%% similar\n", rstEscaper.Replace(duplicate.Files[0]), rstEscaper.Replace(duplicate.Files[1]), duplicate.Similarity)
+		}
+		bw.WriteString("\n")
+	}
+
+	if len(project.Omitted) > 0 || len(project.OmittedByDirectory) > 0 || len(project.DroppedLargest) > 0 {
+		rstHeading(bw, "Omitted files", '-')
+		for _, lang := range sortedKeys(project.Omitted) {
+			fmt.Fprintf(bw, "* %s: %d files\n", rstEscaper.Replace(lang), project.Omitted[lang])
+		}
+		for _, dir := range sortedKeys(project.OmittedByDirectory) {
+			label := dir + "/"
+			if dir == "." {
+				label = "(root)"
+			}
+			fmt.Fprintf(bw, "* %s: %d files over the directory budget\n", rstEscaper.Replace(label), project.OmittedByDirectory[dir])
+		}
+		for _, path := range project.DroppedLargest {
+			fmt.Fprintf(bw, "* %s: one of the largest files\n", rstEscaper.Replace(path))
+		}
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+// writeRSTLanguageTable writes the table of writeLanguageTable as a list table
+func writeRSTLanguageTable(bw *bufio.Writer, totals Totals) {
+	bw.WriteString(".. list-table::\n   :header-rows: 1\n\n")
+	row := func(cells ...string) {
+		for i, cell := range cells {
+			prefix := "     - "
+			if i == 0 {
+				prefix = "   * - "
+			}
+			fmt.Fprintf(bw, "%s%s\n", prefix, cell)
+		}
+	}
+	row("Language", "Files", "Lines", "Size")
+	for _, lang := range totals.rankedLanguages() {
+		t := totals.Languages[lang]
+		row(rstEscaper.Replace(lang), strconv.Itoa(t.Files), strconv.Itoa(t.Lines), formatSize(t.Bytes))
+	}
+	row("Total", strconv.Itoa(totals.Files), strconv.Itoa(totals.Lines), formatSize(totals.Bytes))
+	bw.WriteString("\n")
+}
+
+// writeRSTFile writes the title, modification time, outline and contents of one file
+func writeRSTFile(bw *bufio.Writer, file FileInfo, opts RenderOptions, underline byte, now time.Time) {
+	title := file.Path
+	if file.Status != "" {
+		title += " (" + file.Status + ")"
+	}
+	if file.SignaturesOnly {
+		title += " (signatures only)"
+	}
+	if file.Reason != "" {
+		title += " (" + file.Reason + ")"
+	}
+	if file.Summary != "" {
+		title += " - " + file.Summary
+	}
+	rstHeading(bw, title, underline)
+	if opts.MetricsLine {
+		if metrics := opts.metricsLine(file, now); metrics != "" {
+			fmt.Fprintf(bw, "%s\n\n", rstEscaper.Replace(metrics))
+		}
+	} else if opts.TimeFormat != "" && !file.ModTime.IsZero() {
+		fmt.Fprintf(bw, "Last modified: %s\n\n", rstEscaper.Replace(opts.formatTime(file.ModTime, now)))
+	}
+	if opts.ReadingTime && !opts.MetricsLine {
+		fmt.Fprintf(bw, "Reading time: %s\n\n", formatReadingTime(ReadingTime(file)))
+	}
+	if len(file.Outline) > 0 {
+		for _, decl := range file.Outline {
+			fmt.Fprintf(bw, "* %s ``%s`` (line %d)\n", decl.Kind, decl.Name, decl.Line)
+		}
+		bw.WriteString("\n")
+	}
+	if opts.OutlineOnly {
+		return
+	}
+	if file.Diff != "" {
+		writeRSTCodeBlock(bw, "diff", file.Diff)
+		return
+	}
+	if file.SameFileAs != "" {
+		fmt.Fprintf(bw, "The same file as %s\n\n", rstEscaper.Replace(file.SameFileAs))
+		return
+	}
+	if file.Truncated {
+		fmt.Fprintf(bw, "The contents are left out, since the file is %s\n\n", formatSize(file.Size))
+		return
+	}
+	// A code block must have contents
+	if strings.TrimSpace(file.Contents) == "" {
+		bw.WriteString("*Empty file*\n\n")
+		return
+	}
+	lexer := rstLexer(file.Language)
+	if file.ContentEncoding != "" {
+		fmt.Fprintf(bw, "%s\n\n", binaryNote(file))
+	}
+	writeRSTCodeBlock(bw, lexer, file.Contents)
+}
+
+// writeRSTCodeBlock writes the contents as a code block, which must not be empty, highlighted with the given lexer
+func writeRSTCodeBlock(bw *bufio.Writer, lexer, contents string) {
+	fmt.Fprintf(bw, ".. code-block:: %s\n\n", lexer)
+	for _, line := range splitLines(contents) {
+		if strings.TrimSpace(line) == "" {
+			bw.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(bw, "   %s\n", line)
+	}
+	bw.WriteString("\n")
+}
@@ -0,0 +1,26 @@
+package codesum
+
+import "testing"
+
+func TestRSTLexer(t *testing.T) {
+	for language, fence := range fenceLanguages {
+		want := fence
+		if lexer, ok := rstLexers[language]; ok {
+			want = lexer
+		}
+		if got := rstLexer(language); got != want {
+			t.Errorf("rstLexer(%q) = %q, want %q", language, got, want)
+		}
+	}
+	tests := map[string]string{
+		"Go":           "go",
+		"C/C++ Header": "cpp",
+		"ASCIIDoc":     "text",
+		"Ruby":         "text",
+	}
+	for language, want := range tests {
+		if got := rstLexer(language); got != want {
+			t.Errorf("rstLexer(%q) = %q, want %q", language, got, want)
+		}
+	}
+}
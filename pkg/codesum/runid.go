@@ -0,0 +1,103 @@
+package codesum
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunIDMode selects how ProjectInfo.RunID is created
+type RunIDMode string
+
+const (
+	// RunIDNone leaves ProjectInfo.RunID empty
+	RunIDNone RunIDMode = ""
+	// RunIDHash derives a UUID from the project hash, so that identical trees get identical IDs.
+	// The project hash is the SHA-256 hash of one "path\x00sha256\n" line per file, in path order,
+	// where sha256 is the hex encoded SHA-256 hash of the file. The first 16 bytes of the project hash
+	// are used for a version 8 UUID, as described in RFC 9562.
+	RunIDHash RunIDMode = "hash"
+	// RunIDHashTime is a version 7 UUID, as described in RFC 9562, with the generation time in milliseconds
+	// in the first 48 bits and the project hash in the rest, so that the IDs of one tree sort by time
+	RunIDHashTime RunIDMode = "hash-time"
+	// RunIDRandom is a random version 4 UUID
+	RunIDRandom RunIDMode = "random"
+)
+
+// RunIDModes are the valid modes, except for RunIDNone
+var RunIDModes = []RunIDMode{RunIDHash, RunIDHashTime, RunIDRandom}
+
+// valid checks if the mode is one of RunIDModes
+func (mode RunIDMode) valid() bool {
+	for _, m := range RunIDModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// joinRunIDModes returns the valid modes, separated by sep
+func joinRunIDModes(sep string) string {
+	names := make([]string, len(RunIDModes))
+	for i, mode := range RunIDModes {
+		names[i] = string(mode)
+	}
+	return strings.Join(names, sep)
+}
+
+// ContentHash returns the hex encoded project hash of the files, which is the SHA-256 hash of one
+// "path\x00sha256\n" line per file, in byte order of the paths, where the path is relative to the root, with
+// forward slashes, and sha256 is the hex encoded SHA-256 hash of the contents of the file. It changes when a file
+// is added, removed or edited, but not when only the modification times change. The files must have their Hash set.
+func ContentHash(files []FileInfo) string {
+	sum := projectHash(files)
+	return hex.EncodeToString(sum[:])
+}
+
+// projectHash returns the SHA-256 hash of the paths and hashes of the given files, which must have their Hash set
+func projectHash(files []FileInfo) [sha256.Size]byte {
+	sorted := make([]FileInfo, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	h := sha256.New()
+	for _, file := range sorted {
+		fmt.Fprintf(h, "%s\x00%s\n", file.Path, file.Hash)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// newRunID returns a run ID for the given files and time, in the given mode
+func newRunID(mode RunIDMode, files []FileInfo, t time.Time) (string, error) {
+	var id [16]byte
+	switch mode {
+	case RunIDHash:
+		sum := projectHash(files)
+		copy(id[:], sum[:])
+		id[6] = id[6]&0x0f | 0x80 // version 8
+	case RunIDHashTime:
+		sum := projectHash(files)
+		copy(id[6:], sum[:])
+		var ms [8]byte
+		binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+		copy(id[:6], ms[2:])
+		id[6] = id[6]&0x0f | 0x70 // version 7
+	case RunIDRandom:
+		if _, err := io.ReadFull(rand.Reader, id[:]); err != nil {
+			return "", err
+		}
+		id[6] = id[6]&0x0f | 0x40 // version 4
+	default:
+		return "", nil
+	}
+	id[8] = id[8]&0x3f | 0x80 // RFC 9562 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]), nil
+}
@@ -0,0 +1,103 @@
+package codesum
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDialect is the JSON Schema version that JSONSchema follows
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema of the JSON output, as written by WriteJSON. It is generated from the
+// ProjectInfo struct and the types it contains, so that it is always in sync with them. Fields with omitempty
+// are optional, and the named struct types are in "$defs".
+func JSONSchema() map[string]any {
+	g := schemaGenerator{defs: make(map[string]any)}
+	schema := g.structSchema(reflect.TypeOf(ProjectInfo{}))
+	schema["$schema"] = JSONSchemaDialect
+	schema["title"] = "codesum project summary"
+	schema["properties"].(map[string]any)["schema_version"] = map[string]any{"const": SchemaVersion}
+	// The contents are an array of lines with RenderOptions.ContentsAsLines
+	g.defs["FileInfo"].(map[string]any)["properties"].(map[string]any)["contents"] = map[string]any{
+		"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+	schema["$defs"] = g.defs
+	return schema
+}
+
+// schemaGenerator collects the schemas of the named struct types, so that each is only described once
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema returns the schema of a value of type t, as encoding/json marshals it
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		return g.typeSchema(t.Elem())
+	case t.Kind() == reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // marks the type as being described, in case it refers to itself
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": g.typeSchema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	// Interfaces, like the values of FileInfo.Extra, can be anything
+	return map[string]any{}
+}
+
+// structSchema returns the schema of a struct, where the fields of embedded structs are promoted like
+// encoding/json does
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" || !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			name, flags, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				addFields(field.Type)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = g.typeSchema(field.Type)
+			if !strings.Contains(","+flags+",", ",omitempty,") {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
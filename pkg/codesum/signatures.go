@@ -0,0 +1,194 @@
+package codesum
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// signatureLanguages are the C-family languages that WithSignatures condenses
+var signatureLanguages = map[string]bool{
+	"C":            true,
+	"C++":          true,
+	"C/C++ Header": true,
+}
+
+// signaturesEnricher replaces the contents of C-family files with their declarations, see WithSignatures
+type signaturesEnricher struct{}
+
+func (signaturesEnricher) Enrich(ctx context.Context, f *FileInfo) error {
+	if !signatureLanguages[f.Language] {
+		return nil
+	}
+	f.Contents = cSignatures(f.Contents)
+	f.SignaturesOnly = true
+	return nil
+}
+
+// The kinds of blocks that cSignatures keeps track of
+const (
+	blockTransparent = iota // a namespace or an extern "C" block, where the declarations are kept
+	blockType               // a struct, class, union or enum, where the members are kept
+	blockBody               // a function body or an initializer, which is left out
+)
+
+var (
+	typeHeader        = regexp.MustCompile(`\b(struct|class|union|enum)\b`)
+	transparentHeader = regexp.MustCompile(`^(namespace\b|extern\s*"C(\+\+)?")`)
+	accessLabel       = regexp.MustCompile(`^(public|protected|private):$`)
+	keptDirective     = regexp.MustCompile(`^#\s*(include|define)\b`)
+	whitespace        = regexp.MustCompile(`\s+`)
+)
+
+// cSignatures returns the top-level declarations of C or C++ source code: the function signatures up to the
+// opening brace, as prototypes, the other declarations that end with a semicolon, the struct, class, union and
+// enum declarations with their members, where the bodies of inline methods are left out, and the #include and
+// #define directives. Declarations within namespaces and extern "C" blocks are kept too. Comments are left out.
+// This is a heuristic and not a parser, so macros that expand to declarations or braces, and constructor
+// initializer lists that use braces, can give odd results.
+func cSignatures(src string) string {
+	var (
+		out    strings.Builder
+		stmt   strings.Builder // the statement so far, at the current level
+		blocks []int
+		indent int // the number of blocks that are not skipped
+	)
+	skipping := func() bool {
+		return len(blocks) > 0 && blocks[len(blocks)-1] == blockBody
+	}
+	emit := func(line string) {
+		out.WriteString(strings.Repeat("    ", indent) + line + "\n")
+	}
+	header := func() string {
+		return strings.TrimSpace(whitespace.ReplaceAllString(stmt.String(), " "))
+	}
+
+	atLineStart := true
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			atLineStart = true
+			stmt.WriteByte(' ')
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			stmt.WriteByte(c)
+			continue
+		case c == '#' && atLineStart:
+			// A preprocessor directive, which may be continued with backslashes
+			end := i
+			for end < len(src) && (src[end] != '\n' || src[end-1] == '\\') {
+				end++
+			}
+			if directive := strings.TrimSpace(src[i:end]); !skipping() && keptDirective.MatchString(directive) {
+				emit(directive)
+			}
+			i = end - 1
+			continue
+		}
+		atLineStart = false
+
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return out.String()
+			}
+			i += end + 3
+			stmt.WriteByte(' ')
+		case c == '"' || c == '\'':
+			// Braces and semicolons within literals are not structure
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if !skipping() {
+				stmt.WriteString(src[i:min(j+1, len(src))])
+			}
+			i = j
+		case skipping():
+			switch c {
+			case '{':
+				blocks = append(blocks, blockBody)
+			case '}':
+				blocks = blocks[:len(blocks)-1]
+				if !skipping() {
+					// The end of a function body or an initializer
+					if strings.HasSuffix(header(), "=") {
+						stmt.WriteString(" {...}")
+					} else {
+						stmt.Reset()
+					}
+				}
+			}
+		case c == '{':
+			h := header()
+			stmt.Reset()
+			switch {
+			case transparentHeader.MatchString(h):
+				emit(h + " {")
+				blocks = append(blocks, blockTransparent)
+				indent++
+			case strings.HasSuffix(h, "="):
+				// An initializer, which is left out, but the declaration is kept
+				stmt.WriteString(h)
+				blocks = append(blocks, blockBody)
+			case typeHeader.MatchString(h) && !strings.Contains(h, "("):
+				emit(h + " {")
+				blocks = append(blocks, blockType)
+				indent++
+			default:
+				// A function, where the signature is kept as a prototype
+				if h != "" {
+					emit(h + ";")
+				}
+				blocks = append(blocks, blockBody)
+			}
+		case c == '}':
+			if len(blocks) == 0 {
+				continue
+			}
+			kind := blocks[len(blocks)-1]
+			blocks = blocks[:len(blocks)-1]
+			if h := header(); h != "" {
+				// The members of an enum, or the last declaration if it has no semicolon
+				emit(h)
+			}
+			indent--
+			if kind == blockType {
+				// The declaration continues until the semicolon, like "} point_t;"
+				stmt.Reset()
+				stmt.WriteString("}")
+			} else {
+				emit("}")
+			}
+		case c == ';':
+			stmt.WriteByte(';')
+			if h := header(); h != ";" {
+				emit(h)
+			}
+			stmt.Reset()
+		default:
+			stmt.WriteByte(c)
+			if c != ':' {
+				continue
+			}
+			if h := header(); accessLabel.MatchString(h) {
+				// An access label, which is outdented like in most code styles
+				indent--
+				emit(h)
+				indent++
+				stmt.Reset()
+			}
+		}
+	}
+	return out.String()
+}
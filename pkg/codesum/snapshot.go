@@ -0,0 +1,121 @@
+package codesum
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Snapshot records the state of the files of a project, for finding out what changed between two runs
+type Snapshot struct {
+	Files map[string]SnapshotEntry `json:"files"`
+	// ContentHash is the ProjectInfo.ContentHash of the project, which is empty in snapshots from older versions
+	ContentHash string `json:"content_hash,omitempty"`
+}
+
+type SnapshotEntry struct {
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"sha256,omitempty"`
+}
+
+// NewSnapshot records the paths, modification times, sizes and hashes of the files in the project
+func NewSnapshot(project ProjectInfo) Snapshot {
+	snapshot := Snapshot{Files: make(map[string]SnapshotEntry, len(project.Files)), ContentHash: project.ContentHash}
+	for _, file := range project.Files {
+		snapshot.Files[file.Path] = SnapshotEntry{ModTime: file.ModTime.UTC(), Size: file.Size, Hash: file.Hash}
+	}
+	return snapshot
+}
+
+// LoadSnapshot reads a snapshot that was saved with Save. A missing file gives an empty snapshot.
+func LoadSnapshot(filename string) (Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Snapshot{Files: make(map[string]SnapshotEntry)}, nil
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	if snapshot.Files == nil {
+		snapshot.Files = make(map[string]SnapshotEntry)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to the given file, creating the directory if needed
+func (s Snapshot) Save(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// changed checks if a file differs from the snapshot entry. The hash is compared when both sides have one,
+// so that only touching a file does not count as a change.
+func (e SnapshotEntry) changed(file FileInfo) bool {
+	if e.Hash != "" && file.Hash != "" {
+		return e.Hash != file.Hash
+	}
+	return e.Size != file.Size || !e.ModTime.Equal(file.ModTime)
+}
+
+// ChangedSince returns the project with only the files that were added or modified since the snapshot was taken.
+// FileInfo.Status is set for each remaining file, ProjectInfo.Removed lists the removed files,
+// and the totals are recomputed. When the content hashes are the same, nothing changed. ProjectInfo.ContentHash
+// is left as it is, since it describes the whole project.
+func ChangedSince(project ProjectInfo, snapshot Snapshot) ProjectInfo {
+	if project.ContentHash != "" && snapshot.ContentHash == project.ContentHash {
+		// Nothing was added, removed or edited, so there is no need to compare the files
+		project.Files, project.Removed = nil, nil
+		return withTotals(project)
+	}
+	var files []FileInfo
+	current := make(map[string]bool, len(project.Files))
+	for _, file := range project.Files {
+		current[file.Path] = true
+		entry, existed := snapshot.Files[file.Path]
+		switch {
+		case !existed:
+			file.Status = "added"
+		case entry.changed(file):
+			file.Status = "modified"
+		default:
+			continue
+		}
+		files = append(files, file)
+	}
+	var removed []string
+	for path := range snapshot.Files {
+		if !current[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+
+	project.Files = files
+	project.Removed = removed
+	return withTotals(project)
+}
+
+// withTotals recomputes the totals of the project, and the distribution if there was one
+func withTotals(project ProjectInfo) ProjectInfo {
+	distribution := project.Totals.Distribution
+	project.Totals = computeTotals(project.Files)
+	if distribution != nil {
+		project.Totals.Distribution = computeDistribution(project.Files)
+	}
+	return project
+}
@@ -0,0 +1,66 @@
+package codesum
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestChangedSince(t *testing.T) {
+	fsys := fixtureFS()
+	before, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime), WithHashes(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "cache", "snapshot.json")
+	if err := NewSnapshot(before).Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := LoadSnapshot(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if unchanged := ChangedSince(before, snapshot); len(unchanged.Files) != 0 || len(unchanged.Removed) != 0 {
+		t.Errorf("got the files %v and the removed files %q for the same tree, want none", unchanged.Files, unchanged.Removed)
+	}
+
+	later := fixtureTime.Add(time.Hour)
+	// main.go is only touched, greeting.go is edited, lib/util.h is removed and extra.go is added
+	fsys["main.go"] = &fstest.MapFile{Data: fsys["main.go"].Data, Mode: 0o644, ModTime: later}
+	fsys["greeting.go"] = &fstest.MapFile{Data: []byte("package main\n\nfunc greeting() string { return \"hi\" }\n"), Mode: 0o644, ModTime: later}
+	delete(fsys, "lib/util.h")
+	fsys["extra.go"] = &fstest.MapFile{Data: []byte("package main\n"), Mode: 0o644, ModTime: later}
+	after, err := CollectFS(context.Background(), fsys, WithTime(later), WithHashes(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := ChangedSince(after, snapshot)
+	statuses := make(map[string]string)
+	for _, file := range changed.Files {
+		statuses[file.Path] = file.Status
+	}
+	want := map[string]string{"extra.go": "added", "greeting.go": "modified"}
+	if len(statuses) != len(want) || statuses["extra.go"] != want["extra.go"] || statuses["greeting.go"] != want["greeting.go"] {
+		t.Errorf("got the statuses %v, want %v", statuses, want)
+	}
+	if !slices.Equal(changed.Removed, []string{"lib/util.h"}) {
+		t.Errorf("got the removed files %q, want lib/util.h", changed.Removed)
+	}
+	if changed.Totals.Files != 2 {
+		t.Errorf("got %d files in the totals, want 2", changed.Totals.Files)
+	}
+}
+
+func TestLoadMissingSnapshot(t *testing.T) {
+	snapshot, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Files == nil || len(snapshot.Files) != 0 {
+		t.Errorf("got the files %v, want an empty map", snapshot.Files)
+	}
+}
@@ -0,0 +1,84 @@
+package codesum
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortOrder selects the order of ProjectInfo.Files
+type SortOrder string
+
+const (
+	// SortDefault sorts the files by path, like SortPath
+	SortDefault SortOrder = ""
+	// SortGitRecency puts the files with the most recent commits first. Files without commits, and all files
+	// when not collecting from a git repository, are sorted by their modification time instead.
+	SortGitRecency SortOrder = "git-recency"
+	// SortPath sorts the files by path, byte by byte, so that a/b.go comes before a/b/c.go, unlike in the order
+	// of the directory walk
+	SortPath SortOrder = "path"
+	// SortSize puts the largest files first
+	SortSize SortOrder = "size"
+	// SortLines puts the files with the most lines first
+	SortLines SortOrder = "lines"
+	// SortModified puts the most recently modified files first, by their modification times
+	SortModified SortOrder = "modified"
+)
+
+// SortOrders are the valid sort orders, except for SortDefault
+var SortOrders = []SortOrder{SortGitRecency, SortPath, SortSize, SortLines, SortModified}
+
+// valid checks if the order is one of SortOrders
+func (order SortOrder) valid() bool {
+	for _, o := range SortOrders {
+		if order == o {
+			return true
+		}
+	}
+	return false
+}
+
+// joinSortOrders returns the valid sort orders, separated by sep
+func joinSortOrders(sep string) string {
+	names := make([]string, len(SortOrders))
+	for i, order := range SortOrders {
+		names[i] = string(order)
+	}
+	return strings.Join(names, sep)
+}
+
+// sortFiles sorts the files in the given order, other than SortGitRecency. Files that are the same in that order
+// are sorted by path.
+func sortFiles(files []FileInfo, order SortOrder) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		switch {
+		case order == SortSize && a.Size != b.Size:
+			return a.Size > b.Size
+		case order == SortLines && a.LineCount != b.LineCount:
+			return a.LineCount > b.LineCount
+		case order == SortModified && !a.ModTime.Equal(b.ModTime):
+			return a.ModTime.After(b.ModTime)
+		}
+		return a.Path < b.Path
+	})
+}
+
+// sortByRecency sorts the files by the time of their last commit, newest first, or by their modification time
+// if they have no commit. Files with the same time are sorted by path.
+func sortByRecency(files []FileInfo, lastCommits map[string]GitInfo) {
+	recency := func(file FileInfo) time.Time {
+		if info, ok := lastCommits[file.Path]; ok {
+			return info.Time
+		}
+		return file.ModTime
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		ti, tj := recency(files[i]), recency(files[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return files[i].Path < files[j].Path
+	})
+}
@@ -0,0 +1,373 @@
+package codesum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// limitPerLanguage keeps at most max files of each language, in the given order.
+// The number of omitted files per language is also returned.
+func limitPerLanguage(files []FileInfo, max int) ([]FileInfo, map[string]int) {
+	if max <= 0 {
+		return files, nil
+	}
+	var kept []FileInfo
+	seen := make(map[string]int)
+	omitted := make(map[string]int)
+	for _, file := range files {
+		if seen[file.Language] >= max {
+			omitted[file.Language]++
+			continue
+		}
+		seen[file.Language]++
+		kept = append(kept, file)
+	}
+	if len(omitted) == 0 {
+		return kept, nil
+	}
+	return kept, omitted
+}
+
+// dropLargest drops the largest percent of the files by size, rounded down, and keeps the order of the other files.
+// The paths of the dropped files are also returned, largest first.
+func dropLargest(files []FileInfo, percent float64) ([]FileInfo, []string) {
+	n := int(float64(len(files)) * percent / 100)
+	if n <= 0 {
+		return files, nil
+	}
+	bySize := make([]FileInfo, len(files))
+	copy(bySize, files)
+	sort.SliceStable(bySize, func(i, j int) bool { return bySize[i].Size > bySize[j].Size })
+	dropped := make(map[string]bool, n)
+	paths := make([]string, n)
+	for i, file := range bySize[:n] {
+		dropped[file.Path] = true
+		paths[i] = file.Path
+	}
+	kept := make([]FileInfo, 0, len(files)-n)
+	for _, file := range files {
+		if !dropped[file.Path] {
+			kept = append(kept, file)
+		}
+	}
+	return kept, paths
+}
+
+// topLevelDir returns the first path element of a file path, or "." for files in the root directory
+func topLevelDir(path string) string {
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return "."
+}
+
+// limitPerDirectory keeps the files of each top-level directory, in the given order,
+// as long as their total size stays within budget bytes.
+// The number of omitted files per top-level directory is also returned.
+func limitPerDirectory(files []FileInfo, budget int64) ([]FileInfo, map[string]int) {
+	if budget <= 0 {
+		return files, nil
+	}
+	var kept []FileInfo
+	used := make(map[string]int64)
+	omitted := make(map[string]int)
+	for _, file := range files {
+		dir := topLevelDir(file.Path)
+		if used[dir]+file.Size > budget {
+			omitted[dir]++
+			continue
+		}
+		used[dir] += file.Size
+		kept = append(kept, file)
+	}
+	if len(omitted) == 0 {
+		return kept, nil
+	}
+	return kept, omitted
+}
+
+func computeTotals(files []FileInfo) Totals {
+	totals := Totals{Languages: make(map[string]LanguageTotals)}
+	for _, file := range files {
+		totals.add(file, 1)
+	}
+	return totals
+}
+
+// add adds the file to the totals, or subtracts it if sign is -1. Languages without files are removed.
+func (t *Totals) add(file FileInfo, sign int) {
+	t.Files += sign
+	t.Lines += sign * file.LineCount
+	t.Bytes += int64(sign) * file.Size
+
+	lang := t.Languages[file.Language]
+	lang.Files += sign
+	lang.Lines += sign * file.LineCount
+	lang.Bytes += int64(sign) * file.Size
+	if lang.Files == 0 {
+		delete(t.Languages, file.Language)
+		return
+	}
+	t.Languages[file.Language] = lang
+}
+
+// addTotals adds the totals of another set of files, like those of another root
+func (t *Totals) addTotals(other Totals) {
+	t.Files += other.Files
+	t.Lines += other.Lines
+	t.Bytes += other.Bytes
+	for language, totals := range other.Languages {
+		lang := t.Languages[language]
+		lang.Files += totals.Files
+		lang.Lines += totals.Lines
+		lang.Bytes += totals.Bytes
+		t.Languages[language] = lang
+	}
+}
+
+// rankedLanguages returns the languages with the most files first. Ties are broken by the number of lines,
+// and then by name, so that the order does not vary between runs.
+func (t Totals) rankedLanguages() []string {
+	languages := sortedKeys(t.Languages)
+	sort.SliceStable(languages, func(i, j int) bool {
+		a, b := t.Languages[languages[i]], t.Languages[languages[j]]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Lines > b.Lines
+	})
+	return languages
+}
+
+// mainLanguage returns the language with the most files, or "Unknown" if there are no files, see rankedLanguages
+func (t Totals) mainLanguage() string {
+	if ranked := t.rankedLanguages(); len(ranked) > 0 {
+		return ranked[0]
+	}
+	return "Unknown"
+}
+
+// MixedType is the ProjectInfo.Type of a project where no language has the share of the lines that
+// WithTypeThreshold asks for
+const MixedType = "Mixed"
+
+// DefaultTypeThreshold is the share of the lines, in percent, that a language needs to be the type of the project
+const DefaultTypeThreshold = 60
+
+// share returns the share of the language, in percent, of the lines, or of the files if no lines were counted
+func (t Totals) share(language string) float64 {
+	switch {
+	case t.Lines > 0:
+		return float64(t.Languages[language].Lines) * 100 / float64(t.Lines)
+	case t.Files > 0:
+		return float64(t.Languages[language].Files) * 100 / float64(t.Files)
+	}
+	return 0
+}
+
+// byShare returns the languages with the largest share first, with ties broken by name
+func (t Totals) byShare() []string {
+	languages := sortedKeys(t.Languages)
+	sort.SliceStable(languages, func(i, j int) bool { return t.share(languages[i]) > t.share(languages[j]) })
+	return languages
+}
+
+// dominantLanguage returns the language with the largest share, or with a threshold of 0, the language with the
+// most files, see mainLanguage. This is the language of projectType when the project is not mixed.
+func (t Totals) dominantLanguage(threshold float64) string {
+	if languages := t.byShare(); threshold > 0 && len(languages) > 0 {
+		return languages[0]
+	}
+	return t.mainLanguage()
+}
+
+// projectType returns the language with the largest share, if it is at least threshold percent, or else MixedType.
+// With a threshold of 0, the language with the most files wins, see dominantLanguage.
+func (t Totals) projectType(threshold float64) string {
+	dominant := t.dominantLanguage(threshold)
+	if threshold > 0 && len(t.Languages) > 0 && t.share(dominant) < threshold {
+		return MixedType
+	}
+	return dominant
+}
+
+// describeShares describes the largest shares of the languages, like "Go 52%, TypeScript 44%"
+func describeShares(t Totals) string {
+	const shown = 3
+	var descriptions []string
+	for _, language := range t.byShare() {
+		if len(descriptions) == shown {
+			break
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s %.0f%%", language, t.share(language)))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// totalsAccumulator adds up the totals of the files while they are read, so that the totals are ready without
+// a second pass over the files. It is safe for concurrent use.
+type totalsAccumulator struct {
+	mu     sync.Mutex
+	totals Totals
+}
+
+func newTotalsAccumulator() *totalsAccumulator {
+	return &totalsAccumulator{totals: Totals{Languages: make(map[string]LanguageTotals)}}
+}
+
+// add adds a file that was read to the totals
+func (a *totalsAccumulator) add(file FileInfo) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.totals.add(file, 1)
+}
+
+// omit subtracts each file that is in files, but not in kept, from the totals. The omitted files are logged at the
+// debug level with the given message, unless it is empty, for the filters that log the files themselves.
+func (a *totalsAccumulator) omit(o Options, files, kept []FileInfo, message string) {
+	if len(files) == len(kept) {
+		return
+	}
+	keptPaths := make(map[string]bool, len(kept))
+	for _, file := range kept {
+		keptPaths[file.Path] = true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, file := range files {
+		if keptPaths[file.Path] {
+			continue
+		}
+		a.totals.add(file, -1)
+		if message != "" {
+			o.Logger.Debug(message, "path", file.Path)
+		}
+	}
+}
+
+// result returns a copy of the totals so far
+func (a *totalsAccumulator) result() Totals {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	totals := a.totals
+	totals.Languages = make(map[string]LanguageTotals, len(a.totals.Languages))
+	for language, lang := range a.totals.Languages {
+		totals.Languages[language] = lang
+	}
+	return totals
+}
+
+// countLines returns the number of lines in the file at the given path, where a last line without a newline is
+// counted too
+func countLines(fsys fs.FS, path string) (int, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return countLinesFrom(f)
+}
+
+// countLinesFrom returns the number of lines that r reads, where a last line without a newline is counted too.
+// Lines of any length are counted, unlike with bufio.Scanner. CRLF line endings count once, since only the "\n"
+// is counted, and an empty file has no lines.
+func countLinesFrom(r io.Reader) (int, error) {
+	buf := make([]byte, 32*1024)
+	lineCount := 0
+	var last byte = '\n'
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			lineCount += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lineCount++
+	}
+	return lineCount, nil
+}
+
+// trimBlankLines removes the leading and trailing lines that only contain whitespace.
+// The result ends with a newline, unless it is empty.
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	start, end := 0, len(lines)
+	for start < end && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	if start == end {
+		return ""
+	}
+	return strings.Join(lines[start:end], "\n") + "\n"
+}
+
+// hashBytes returns the hex encoded SHA-256 hash of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sniffLength is the number of bytes that http.DetectContentType looks at
+const sniffLength = 512
+
+// hashFile returns the hex encoded SHA-256 hash of a file, without reading all of it into memory, and the first
+// sniffLength bytes of it, for sniffMimeType
+func hashFile(fsys fs.FS, path string) (string, []byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+	h := sha256.New()
+	h.Write(head)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", nil, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), head, nil
+}
+
+// BytesPerToken is the average number of bytes per token that EstimateTokens assumes
+const BytesPerToken = 4
+
+// EstimateTokens returns a rough estimate of how many LLM tokens the given number of bytes of text is
+func EstimateTokens(size int64) int64 {
+	return (size + BytesPerToken - 1) / BytesPerToken
+}
+
+// formatSize formats a number of bytes for people, like "512B", "3.4KB" or "1.2MB", with 1KB being 1024 bytes
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value, prefixes := float64(size)/unit, "KMGT"
+	i := 0
+	for value >= unit && i < len(prefixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f%cB", value, prefixes[i])
+}
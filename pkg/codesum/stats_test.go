@@ -0,0 +1,275 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+)
+
+func TestDominantOnlyMatchesType(t *testing.T) {
+	// Markdown has the most files, while Go has the most lines, but not 60% of them
+	fsys := fstest.MapFS{
+		"main.go":   {Data: []byte(strings.Repeat("// line\n", 50))},
+		"script.py": {Data: []byte(strings.Repeat("# line\n", 40))},
+		"a.md":      {Data: []byte("a\n")},
+		"b.md":      {Data: []byte("b\n")},
+		"c.md":      {Data: []byte("c\n")},
+	}
+	all, err := CollectFS(context.Background(), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Type != MixedType {
+		t.Fatalf("the type is %s, want %s", all.Type, MixedType)
+	}
+	for _, tt := range []struct {
+		threshold float64
+		want      string
+	}{
+		{DefaultTypeThreshold, "Go"},
+		{0, "Markdown"},
+	} {
+		project, err := CollectFS(context.Background(), fsys, WithDominantOnly(true), WithTypeThreshold(tt.threshold))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.Type != tt.want {
+			t.Errorf("with the threshold %g, the type is %s, want %s", tt.threshold, project.Type, tt.want)
+		}
+		for _, file := range project.Files {
+			if file.Language != tt.want {
+				t.Errorf("with the threshold %g, %s in %s is included", tt.threshold, file.Path, file.Language)
+			}
+		}
+		if want := len(all.Files) - len(project.Files); project.OtherLanguageFiles != want {
+			t.Errorf("with the threshold %g, %d files in other languages are counted, want %d", tt.threshold, project.OtherLanguageFiles, want)
+		}
+	}
+}
+
+func TestLanguageTotals(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go": {Data: []byte("package main\n\nfunc main() {}\n")},
+		"util.go": {Data: []byte("package main\n")},
+		"tool.py": {Data: []byte("import sys\nprint(sys.argv)\n")},
+	}
+	project, err := CollectFS(context.Background(), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Totals{
+		Files: 3,
+		Lines: 6,
+		Bytes: 69,
+		Languages: map[string]LanguageTotals{
+			"Go":     {Files: 2, Lines: 4, Bytes: 42},
+			"Python": {Files: 1, Lines: 2, Bytes: 27},
+		},
+	}
+	got := project.Totals
+	if got.Files != want.Files || got.Lines != want.Lines || got.Bytes != want.Bytes {
+		t.Errorf("the totals are %d files, %d lines and %d bytes, want %d, %d and %d", got.Files, got.Lines, got.Bytes, want.Files, want.Lines, want.Bytes)
+	}
+	if len(got.Languages) != len(want.Languages) {
+		t.Errorf("got the languages %v, want %v", got.Languages, want.Languages)
+	}
+	for lang, w := range want.Languages {
+		if got.Languages[lang] != w {
+			t.Errorf("%s has the totals %+v, want %+v", lang, got.Languages[lang], w)
+		}
+	}
+	// The totals must match the line counts of the files
+	lines := make(map[string]int)
+	for _, file := range project.Files {
+		lines[file.Language] += file.LineCount
+	}
+	for lang, n := range lines {
+		if got.Languages[lang].Lines != n {
+			t.Errorf("%s has %d lines in the totals, but %d in the files", lang, got.Languages[lang].Lines, n)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Totals Totals `json:"totals"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for lang, w := range want.Languages {
+		if decoded.Totals.Languages[lang] != w {
+			t.Errorf("%s has the totals %+v in the JSON output, want %+v", lang, decoded.Totals.Languages[lang], w)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteMarkdown(&buf, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	table := "| Language | Files | Lines | Size |\n" +
+		"|---|--:|--:|--:|\n" +
+		"| Go | 2 | 4 | 42B |\n" +
+		"| Python | 1 | 2 | 27B |\n" +
+		"| Total | 3 | 6 | 69B |\n"
+	if !strings.Contains(buf.String(), table) {
+		t.Errorf("the Markdown output does not have the table\n%s\ngot:\n%s", table, buf.String())
+	}
+}
+
+// lineCountFixtures are files with a known number of lines
+var lineCountFixtures = map[string]struct {
+	contents string
+	lines    int
+}{
+	"ten.go":         {strings.Repeat("// line\n", 10), 10},
+	"no_newline.go":  {"package main\n\nfunc main() {}", 3},
+	"one_line.go":    {"package main", 1},
+	"blank_lines.go": {"\n\n\n", 3},
+}
+
+func TestCollectLineCounts(t *testing.T) {
+	fsys := make(fstest.MapFS)
+	for name, fixture := range lineCountFixtures {
+		fsys[name] = &fstest.MapFile{Data: []byte(fixture.contents)}
+	}
+	// The lines are counted in the contents that were read, or in the file when the contents are left out
+	for _, opts := range [][]Option{nil, {WithoutContents(true)}, {WithListOnly(true, true)}} {
+		project, err := CollectFS(context.Background(), fsys, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(project.Files) != len(lineCountFixtures) {
+			t.Fatalf("got %d files, want %d", len(project.Files), len(lineCountFixtures))
+		}
+		total := 0
+		for _, file := range project.Files {
+			want := lineCountFixtures[file.Path].lines
+			if file.LineCount != want {
+				t.Errorf("%s has %d lines, want %d", file.Path, file.LineCount, want)
+			}
+			total += want
+		}
+		if project.Totals.Lines != total {
+			t.Errorf("the totals have %d lines, want %d", project.Totals.Lines, total)
+		}
+	}
+}
+
+func TestCountLinesFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     int
+	}{
+		{"empty", "", 0},
+		{"one line without a newline", "package main", 1},
+		{"one line", "package main\n", 1},
+		{"no trailing newline", "a\nb", 2},
+		{"only a newline", "\n", 1},
+		{"CRLF", "a\r\nb\r\n", 2},
+		{"CRLF without a trailing newline", "a\r\nb", 2},
+		{"a lone CR", "a\rb\n", 1},
+		{"a line longer than the buffer", strings.Repeat("x", 100*1024), 1},
+		{"a newline at the end of the buffer", strings.Repeat("x", 32*1024-1) + "\n" + "y", 2},
+	}
+	for _, tt := range tests {
+		got, err := countLinesFrom(strings.NewReader(tt.contents))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %d lines, want %d", tt.name, got, tt.want)
+		}
+		// Reading one byte at a time must give the same count
+		if got, err := countLinesFrom(iotest.OneByteReader(strings.NewReader(tt.contents))); err != nil || got != tt.want {
+			t.Errorf("%s, one byte at a time: got %d lines and the error %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := countLinesFrom(iotest.ErrReader(iotest.ErrTimeout)); err == nil {
+		t.Error("the error of the reader is not returned")
+	}
+}
+
+// TestRenderedLineCounts checks that the line counts of a CRLF file, an empty file and a file without a trailing
+// newline are the real counts in the JSON and the Markdown output
+func TestRenderedLineCounts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"crlf.go":       {Data: []byte("package main\r\n\r\nfunc main() {}\r\n")},
+		"empty.go":      {Data: []byte("")},
+		"no_newline.go": {Data: []byte("package main\n\nvar x = 1")},
+	}
+	project, err := CollectFS(context.Background(), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonOutput, markdown bytes.Buffer
+	if err := WriteJSON(&jsonOutput, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteMarkdown(&markdown, project, RenderOptions{MetricsLine: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"path": "crlf.go",`, `"line_count": 3,`, `"path": "no_newline.go",`} {
+		if !strings.Contains(jsonOutput.String(), want) {
+			t.Errorf("the JSON output does not have %s:\n%s", want, jsonOutput.String())
+		}
+	}
+	for _, want := range []string{"lines: 3 · size: 32B", "lines: 3 · size: 23B", "| Go | 3 | 6 |"} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("the Markdown output does not have %q:\n%s", want, markdown.String())
+		}
+	}
+	for _, file := range project.Files {
+		if file.Path == "empty.go" && file.LineCount != 0 {
+			t.Errorf("empty.go has %d lines, want 0", file.LineCount)
+		}
+	}
+}
+
+func TestTrimEdges(t *testing.T) {
+	padded := "\n  \n\npackage main\n\n\nfunc main() {}\n\n\t\n\n"
+	fsys := fstest.MapFS{"main.go": {Data: []byte(padded)}}
+	for _, trim := range []bool{false, true} {
+		project, err := CollectFS(context.Background(), fsys, WithTrimEdges(trim))
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := project.Files[0]
+		want := padded
+		if trim {
+			// The blank lines inside of the contents are kept
+			want = "package main\n\n\nfunc main() {}\n"
+		}
+		if file.Contents != want {
+			t.Errorf("got the contents %q with trimming %t, want %q", file.Contents, trim, want)
+		}
+		// The line count is that of the file on disk
+		if file.LineCount != 10 {
+			t.Errorf("got %d lines with trimming %t, want 10", file.LineCount, trim)
+		}
+	}
+}
+
+func TestTrimBlankLines(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"\n\n \n":             "",
+		"x":                   "x\n",
+		"x\n":                 "x\n",
+		"\r\n\r\nx\r\n\r\n":   "x\r\n",
+		"\n\tx\n\n  y\n\n":    "\tx\n\n  y\n",
+		"   \nx\n   \ny\n   ": "x\n   \ny\n",
+	}
+	for s, want := range tests {
+		if got := trimBlankLines(s); got != want {
+			t.Errorf("trimBlankLines(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package codesum
+
+import (
+	"bufio"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// readGitModules returns the submodule names in a .gitmodules file, by path.
+// A missing .gitmodules file gives an empty map.
+func readGitModules(fsys fs.FS) map[string]string {
+	submodules := make(map[string]string)
+	file, err := fsys.Open(".gitmodules")
+	if err != nil {
+		return submodules
+	}
+	defer file.Close()
+
+	var name string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[submodule ") && strings.HasSuffix(line, "]") {
+			name = strings.Trim(strings.TrimPrefix(strings.TrimSuffix(line, "]"), "[submodule "), `"`)
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name = ""
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if found && name != "" && strings.TrimSpace(key) == "path" {
+			submodules[path.Clean(strings.TrimSpace(value))] = name
+		}
+	}
+	return submodules
+}
+
+// submoduleInitialized checks if a submodule has been checked out, by looking for its .git file or directory
+func submoduleInitialized(fsys fs.FS, dir string) bool {
+	_, err := fs.Stat(fsys, path.Join(dir, ".git"))
+	return err == nil
+}
+
+// submoduleOf returns the name of the submodule that contains the given path, if any
+func submoduleOf(filePath string, submodules map[string]string) string {
+	for dir, name := range submodules {
+		if strings.HasPrefix(filePath, dir+"/") {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package codesum
+
+import (
+	"context"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// submoduleFS is a project with an initialized submodule, which has a .git file that points to the git directory of
+// the superproject, and a submodule that is not initialized
+func submoduleFS() fstest.MapFS {
+	file := func(contents string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(contents), Mode: 0o644}
+	}
+	return fstest.MapFS{
+		".gitmodules": file("[submodule \"core\"]\n\tpath = libs/core\n\turl = https://example.com/core.git\n" +
+			"[submodule \"other\"]\n\tpath = libs/other\n\turl = https://example.com/other.git\n"),
+		"main.go":           file("package main\n"),
+		"libs/core/.git":    file("gitdir: ../../.git/modules/core\n"),
+		"libs/core/core.go": file("package core\n"),
+		"libs/other":        &fstest.MapFile{Mode: fs.ModeDir | 0o755},
+	}
+}
+
+func TestSubmodules(t *testing.T) {
+	project, err := CollectFS(context.Background(), submoduleFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Files) != 1 || project.Files[0].Path != "main.go" {
+		t.Errorf("got the files %v without WithSubmodules, want only main.go", project.Files)
+	}
+
+	project, err = CollectFS(context.Background(), submoduleFS(), WithSubmodules(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	submodules := make(map[string]string)
+	for _, file := range project.Files {
+		submodules[file.Path] = file.Submodule
+	}
+	want := map[string]string{"main.go": "", "libs/core/core.go": "core"}
+	if len(submodules) != len(want) {
+		t.Errorf("got the files %v, want %v", submodules, want)
+	}
+	for path, submodule := range want {
+		if got, ok := submodules[path]; !ok || got != submodule {
+			t.Errorf("%s is in the submodule %q, want %q", path, got, submodule)
+		}
+	}
+	notInitialized := func(warning string) bool {
+		return strings.Contains(warning, "submodule other in libs/other, since it is not initialized")
+	}
+	if !slices.ContainsFunc(project.Warnings, notInitialized) {
+		t.Errorf("got the warnings %q, want one for the submodule that is not initialized", project.Warnings)
+	}
+}
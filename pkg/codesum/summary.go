@@ -0,0 +1,133 @@
+package codesum
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// summaryEnricher sets FileInfo.Summary from the leading doc comment of Go, Python and Rust files
+type summaryEnricher struct{}
+
+func (summaryEnricher) Enrich(ctx context.Context, f *FileInfo) error {
+	var (
+		doc string
+		err error
+	)
+	switch f.Language {
+	case "Go":
+		doc, err = goFileDoc(f.Path, f.Contents)
+	case "Python":
+		doc = pythonDocstring(f.Contents)
+	case "Rust":
+		doc = rustFileDoc(f.Contents)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f.Summary = firstSentence(doc)
+	return nil
+}
+
+// goFileDoc returns the package comment, or else the first comment above the package clause that is
+// not a build constraint or a license header
+func goFileDoc(filename, src string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	if file.Doc != nil {
+		return file.Doc.Text(), nil
+	}
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		// Text leaves out directives like //go:build, but not the old +build lines
+		text := group.Text()
+		if text == "" || strings.HasPrefix(text, "+build") || isLicenseHeader(text) {
+			continue
+		}
+		return text, nil
+	}
+	return "", nil
+}
+
+// isLicenseHeader checks if a comment looks like a copyright or license notice
+func isLicenseHeader(text string) bool {
+	return strings.HasPrefix(text, "Copyright") || strings.Contains(text, "SPDX-License-Identifier")
+}
+
+var pythonDocstringStart = regexp.MustCompile(`^[rRuU]?("""|'''|"|')`)
+
+// pythonDocstring returns the module docstring, which is a string literal that is the first statement
+func pythonDocstring(src string) string {
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue // blank lines, comments, the shebang and the encoding declaration
+		}
+		m := pythonDocstringStart.FindStringSubmatch(trimmed)
+		if m == nil {
+			return ""
+		}
+		quote := m[1]
+		rest := trimmed[len(m[0]):]
+		if end := strings.Index(rest, quote); end >= 0 {
+			return rest[:end]
+		}
+		if len(quote) == 1 {
+			return "" // a single quoted string can not span lines
+		}
+		// Collect the lines until the closing triple quote
+		doc := []string{rest}
+		for _, line := range lines[i+1:] {
+			if end := strings.Index(line, quote); end >= 0 {
+				return strings.Join(append(doc, line[:end]), "\n")
+			}
+			doc = append(doc, line)
+		}
+		return ""
+	}
+	return ""
+}
+
+// rustFileDoc returns the //! inner doc comment at the top of the file, or else the /// doc comment of the first item
+func rustFileDoc(src string) string {
+	var doc []string
+	prefix := ""
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case prefix == "" && trimmed == "":
+			continue
+		case prefix == "" && strings.HasPrefix(trimmed, "//!"):
+			prefix = "//!"
+		case prefix == "" && strings.HasPrefix(trimmed, "///"):
+			prefix = "///"
+		case prefix == "":
+			return ""
+		}
+		if !strings.HasPrefix(trimmed, prefix) {
+			break
+		}
+		doc = append(doc, strings.TrimPrefix(strings.TrimPrefix(trimmed, prefix), " "))
+	}
+	return strings.Join(doc, "\n")
+}
+
+// firstSentence returns the first sentence of the first paragraph of a comment, on one line
+func firstSentence(doc string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(doc), "\n\n")
+	line := strings.Join(strings.Fields(paragraph), " ")
+	if i := strings.Index(line, ". "); i >= 0 {
+		return line[:i+1]
+	}
+	return line
+}
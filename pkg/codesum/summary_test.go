@@ -0,0 +1,97 @@
+package codesum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileSummaries(t *testing.T) {
+	tests := []struct {
+		name, language, contents, want string
+	}{
+		{"go package comment", "Go", "// Package server serves. It is fast.\npackage server\n", "Package server serves."},
+		{"go license header", "Go", "// Copyright 2024 The Authors\n\n// Command tool does things\npackage main\n", "Command tool does things"},
+		{"go license and package comment", "Go", "// SPDX-License-Identifier: MIT\n\n// Package x is small.\npackage x\n", "Package x is small."},
+		{"go build constraint", "Go", "//go:build linux\n// +build linux\n\npackage x\n", ""},
+		{"go without comment", "Go", "package x\n", ""},
+		{"python docstring", "Python", "#!/usr/bin/env python3\n\"\"\"Run the jobs.\n\nMore details.\n\"\"\"\n", "Run the jobs."},
+		{"python one line docstring", "Python", "# -*- coding: utf-8 -*-\n'Tools for dates'\nimport os\n", "Tools for dates"},
+		{"python multi-line first sentence", "Python", "'''Reads the\nconfiguration file. Then more.'''\n", "Reads the configuration file."},
+		{"python code first", "Python", "import os\n\"\"\"Not a docstring\"\"\"\n", ""},
+		{"rust inner doc", "Rust", "//! A parser for\n//! configuration files.\n\nfn main() {}\n", "A parser for configuration files."},
+		{"rust item doc", "Rust", "/// The entry point.\nfn main() {}\n", "The entry point."},
+		{"rust plain comment", "Rust", "// not a doc comment\nfn main() {}\n", ""},
+		{"other language", "C", "/* A C file. */\nint x;\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := FileInfo{Path: "file", Language: tt.language, Contents: tt.contents}
+			if err := (summaryEnricher{}).Enrich(context.Background(), &file); err != nil {
+				t.Fatal(err)
+			}
+			if file.Summary != tt.want {
+				t.Errorf("got the summary %q, want %q", file.Summary, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummariesOutput(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":  {Data: []byte("// Command hello greets.\npackage main\n\nfunc main() {}\n"), ModTime: fixtureTime},
+		"util.go":  {Data: []byte("package main\n"), ModTime: fixtureTime},
+		"tools.py": {Data: []byte("\"\"\"Build the docs.\"\"\"\n"), ModTime: fixtureTime},
+	}
+	project, err := CollectFS(context.Background(), fsys, WithTime(fixtureTime), WithSummaries(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var md bytes.Buffer
+	if err := WriteMarkdown(&md, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"main.go - Command hello greets.\n",
+		"tools.py - Build the docs.\n",
+		"| Go | 2 | 5 | 67B |\n",
+		"| Python | 1 | 1 | 22B |\n",
+		"| Total | 3 | 6 | 89B |\n",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("the Markdown output does not contain %q:\n%s", want, md.String())
+		}
+	}
+	if strings.Contains(md.String(), "util.go -") {
+		t.Errorf("util.go has a summary in the Markdown output, without a doc comment:\n%s", md.String())
+	}
+
+	var data bytes.Buffer
+	if err := WriteJSON(&data, project, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded ProjectInfo
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	summaries := make(map[string]string)
+	for _, file := range decoded.Files {
+		summaries[file.Path] = file.Summary
+	}
+	if summaries["main.go"] != "Command hello greets." || summaries["tools.py"] != "Build the docs." || summaries["util.go"] != "" {
+		t.Errorf("got the summaries %q in the JSON output", summaries)
+	}
+	wantTotals := map[string]LanguageTotals{"Go": {Files: 2, Lines: 5, Bytes: 67}, "Python": {Files: 1, Lines: 1, Bytes: 22}}
+	if len(decoded.Totals.Languages) != len(wantTotals) {
+		t.Errorf("got the language totals %v in the JSON output, want %v", decoded.Totals.Languages, wantTotals)
+	}
+	for language, want := range wantTotals {
+		if got := decoded.Totals.Languages[language]; got != want {
+			t.Errorf("got the totals %+v for %s in the JSON output, want %+v", got, language, want)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+/* Quoted  costs 5  */
+int x = 1;
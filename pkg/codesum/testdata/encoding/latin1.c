@@ -0,0 +1,2 @@
+/*  2001 Jos Mller, see  3 */
+int gre = 1;
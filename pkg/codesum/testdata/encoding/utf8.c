@@ -0,0 +1,2 @@
+/* Ünïcödé stays as it is */
+int z;
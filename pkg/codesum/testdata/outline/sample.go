@@ -0,0 +1,34 @@
+// Package sample has one declaration of each kind
+package sample
+
+import "fmt"
+
+const greeting = "hello"
+
+var count int
+
+// Greeter greets
+type Greeter struct {
+	name string
+}
+
+type (
+	Names             []string
+	Set[T comparable] map[T]struct{}
+)
+
+// New returns a Greeter
+func New(name string) *Greeter {
+	return &Greeter{name: name}
+}
+
+func (g *Greeter) Greet() string {
+	inner := func() string { return greeting }
+	return fmt.Sprintf("%s, %s", inner(), g.name)
+}
+
+func (s Set[T]) Add(value T) {
+	s[value] = struct{}{}
+}
+
+func main() {}
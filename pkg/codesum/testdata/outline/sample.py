@@ -0,0 +1,38 @@
+"""A module with one declaration of each kind"""
+
+import os
+
+
+def greet(name):
+    def inner():
+        return "hello"
+    return f"{inner()}, {name}"
+
+
+async def fetch(url):
+    pass
+
+
+class Greeter:
+    """Greets"""
+
+    greeting = "hello"
+
+    def __init__(self, name):
+        self.name = name
+
+    async def greet(self):
+        class Inner:
+            def hidden(self):
+                pass
+        return self.name
+
+    # def commented(self):
+
+
+class Empty: pass
+
+
+if os.name == "nt":
+    def windows_only():
+        pass
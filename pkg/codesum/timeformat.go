@@ -0,0 +1,81 @@
+package codesum
+
+import (
+	"fmt"
+	"time"
+)
+
+// Named values for RenderOptions.TimeFormat. Any other value is used as a Go time layout.
+const (
+	TimeFormatRFC3339  = "rfc3339"
+	TimeFormatDate     = "date"
+	TimeFormatRelative = "relative"
+)
+
+// TimeFormats are the named time formats
+var TimeFormats = []string{TimeFormatRFC3339, TimeFormatDate, TimeFormatRelative}
+
+// formatTime formats t for the Markdown and reStructuredText output, according to opts.TimeFormat.
+// Relative times are relative to now, which is the same for the whole document.
+func (opts RenderOptions) formatTime(t, now time.Time) string {
+	if opts.UTC {
+		t = t.UTC()
+	} else {
+		t = t.Local()
+	}
+	switch opts.TimeFormat {
+	case TimeFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeFormatDate:
+		return t.Format(time.DateOnly)
+	case TimeFormatRelative:
+		return relativeTime(t, now)
+	}
+	return t.Format(opts.TimeFormat)
+}
+
+// recentTime formats the time of a recently modified file, according to opts.TimeFormat if it is set
+func (opts RenderOptions) recentTime(r RecentFile, now time.Time) string {
+	if opts.TimeFormat == "" {
+		return r.LastModified
+	}
+	return opts.formatTime(r.Time, now)
+}
+
+// documentTime returns the time that relative times in the output are relative to
+func documentTime(project ProjectInfo) time.Time {
+	if project.GeneratedTime.IsZero() {
+		return time.Now()
+	}
+	return project.GeneratedTime
+}
+
+// relativeTime describes t relative to now, like "3 days ago"
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	format := "%d %s ago"
+	if d < 0 {
+		d, format = -d, "in %d %s"
+	}
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{365 * 24 * time.Hour, "year"},
+		{30 * 24 * time.Hour, "month"},
+		{7 * 24 * time.Hour, "week"},
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+	for _, unit := range units {
+		if n := int(d / unit.size); n >= 1 {
+			name := unit.name
+			if n > 1 {
+				name += "s"
+			}
+			return fmt.Sprintf(format, n, name)
+		}
+	}
+	return "just now"
+}
@@ -0,0 +1,67 @@
+package codesum
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := fixtureTime
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now, "just now"},
+		{now.Add(-59 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-90 * time.Minute), "1 hour ago"},
+		{now.Add(-3 * 24 * time.Hour), "3 days ago"},
+		{now.Add(-14 * 24 * time.Hour), "2 weeks ago"},
+		{now.Add(-60 * 24 * time.Hour), "2 months ago"},
+		{now.Add(-400 * 24 * time.Hour), "1 year ago"},
+		{now.Add(2 * time.Hour), "in 2 hours"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(tt.t, now); got != tt.want {
+			t.Errorf("relativeTime(now%+v) = %q, want %q", tt.t.Sub(now), got, tt.want)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	modified := time.Date(2024, 4, 28, 9, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{TimeFormatRFC3339, "2024-04-28T07:30:00Z"},
+		{TimeFormatDate, "2024-04-28"},
+		{TimeFormatRelative, "3 days ago"},
+		{"02 Jan 15:04", "28 Apr 07:30"},
+	}
+	for _, tt := range tests {
+		opts := RenderOptions{TimeFormat: tt.format, UTC: true}
+		if got := opts.formatTime(modified, fixtureTime); got != tt.want {
+			t.Errorf("the format %q gives %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+// TestMarkdownRelativeTimes checks that all relative times in a document are relative to the time of the
+// summary, which is frozen with WithTime, and not to the time when it is rendered
+func TestMarkdownRelativeTimes(t *testing.T) {
+	project := collectFixture(t, WithTime(fixtureTime.Add(3*24*time.Hour)))
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, project, RenderOptions{TimeFormat: TimeFormatRelative, UTC: true}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "* Generated at: just now\n") {
+		t.Errorf("the generation time is not just now:\n%s", output)
+	}
+	if got, want := strings.Count(output, "Last modified: 3 days ago\n"), len(project.Files); got != want {
+		t.Errorf("got %d files that were modified 3 days ago, want %d:\n%s", got, want, output)
+	}
+}
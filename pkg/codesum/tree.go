@@ -0,0 +1,98 @@
+package codesum
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TreeNode is a directory or a file in the directory tree of a project, see BuildTree
+type TreeNode struct {
+	Name string `json:"name"`
+	// File is the index of the file in ProjectInfo.Files, for files
+	File *int `json:"file,omitempty"`
+	// Files and Lines are the number of files and lines below a directory
+	Files int `json:"files,omitempty"`
+	Lines int `json:"lines,omitempty"`
+	// Children are the directories and files in a directory, sorted by name
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// BuildTree returns the directory tree of the given files, where the files refer to their index in files.
+// The root is named "." for relative paths and "/" for absolute paths.
+func BuildTree(files []FileInfo) *TreeNode {
+	root := &TreeNode{Name: "."}
+	dirs := map[string]*TreeNode{"": root}
+	for i, file := range files {
+		p := file.Path
+		if strings.HasPrefix(p, "/") {
+			root.Name = "/"
+			p = strings.TrimPrefix(p, "/")
+		}
+		elements := strings.Split(p, "/")
+		parent := root
+		for j, name := range elements[:len(elements)-1] {
+			key := strings.Join(elements[:j+1], "/")
+			dir, ok := dirs[key]
+			if !ok {
+				dir = &TreeNode{Name: name}
+				dirs[key] = dir
+				parent.Children = append(parent.Children, dir)
+			}
+			parent.Files++
+			parent.Lines += file.LineCount
+			parent = dir
+		}
+		index := i
+		parent.Children = append(parent.Children, &TreeNode{Name: elements[len(elements)-1], File: &index})
+		parent.Files++
+		parent.Lines += file.LineCount
+	}
+	for _, dir := range dirs {
+		sort.SliceStable(dir.Children, func(i, j int) bool { return dir.Children[i].Name < dir.Children[j].Name })
+	}
+	return root
+}
+
+// treeLines draws the tree as lines of text, like "├── cmd/ (1 file, 20 lines)", where directories are
+// followed by a slash and their number of files and lines
+func treeLines(root *TreeNode) []string {
+	// The nodes are drawn from a stack instead of recursively, so that very deep trees can not exhaust the stack
+	type entry struct {
+		node *TreeNode
+		// prefix is drawn before the label of the node, and indent before the labels of its children
+		prefix, indent string
+	}
+	var stack []entry
+	push := func(node *TreeNode, indent string) {
+		// The children are pushed in reverse order, so that the first child is popped first
+		for i := len(node.Children) - 1; i >= 0; i-- {
+			branch, next := "├── ", "│   "
+			if i == len(node.Children)-1 {
+				branch, next = "└── ", "    "
+			}
+			stack = append(stack, entry{node: node.Children[i], prefix: indent + branch, indent: indent + next})
+		}
+	}
+	lines := []string{root.label()}
+	push(root, "")
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		lines = append(lines, e.prefix+e.node.label())
+		push(e.node, e.indent)
+	}
+	return lines
+}
+
+// label returns the name of a file, or the name and the totals of a directory
+func (n *TreeNode) label() string {
+	if n.File != nil {
+		return n.Name
+	}
+	name := n.Name
+	if name != "/" {
+		name += "/"
+	}
+	return fmt.Sprintf("%s (%s, %s)", name, countFiles(n.Files), pluralize(n.Lines, "line"))
+}
@@ -0,0 +1,92 @@
+package codesum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTreeLines(t *testing.T) {
+	files := []FileInfo{
+		{Path: "main.go", LineCount: 10},
+		{Path: "cmd/tool/tool.go", LineCount: 5},
+		{Path: "cmd/README.md", LineCount: 2},
+	}
+	want := []string{
+		"./ (3 files, 17 lines)",
+		"├── cmd/ (2 files, 7 lines)",
+		"│   ├── README.md",
+		"│   └── tool/ (1 file, 5 lines)",
+		"│       └── tool.go",
+		"└── main.go",
+	}
+	got := treeLines(BuildTree(files))
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTreeLinesDeep(t *testing.T) {
+	const depth = 2000
+	path := strings.Repeat("d/", depth) + "deep.go"
+	lines := treeLines(BuildTree([]FileInfo{{Path: path, LineCount: 1}}))
+	if len(lines) != depth+2 {
+		t.Fatalf("got %d lines, want %d", len(lines), depth+2)
+	}
+	if last, want := lines[len(lines)-1], strings.Repeat("    ", depth)+"└── deep.go"; last != want {
+		t.Errorf("the last line is %q, want %q", last, want)
+	}
+}
+
+func TestMaxPerLanguageImbalanced(t *testing.T) {
+	// Many shallow Go files and a few Python scripts in one deep directory
+	fsys := fstest.MapFS{}
+	for i := 0; i < 50; i++ {
+		fsys[fmt.Sprintf("file%02d.go", i)] = &fstest.MapFile{Data: []byte("package main\n")}
+	}
+	deep := strings.Repeat("scripts/", 8)
+	for _, name := range []string{"build.py", "deploy.py"} {
+		fsys[deep+name] = &fstest.MapFile{Data: []byte("print('hello')\n")}
+	}
+	project, err := CollectFS(context.Background(), fsys, WithMaxPerLanguage(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range project.Files {
+		paths = append(paths, file.Path)
+	}
+	want := []string{"file00.go", "file01.go", "file02.go", deep + "build.py", deep + "deploy.py"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("got the files %q, want %q", paths, want)
+	}
+	if project.Omitted["Go"] != 47 || project.Omitted["Python"] != 0 {
+		t.Errorf("got the omitted files %v, want 47 Go files", project.Omitted)
+	}
+	lines := treeLines(BuildTree(project.Files))
+	if want := "./ (5 files, 5 lines)"; lines[0] != want {
+		t.Errorf("the first line of the tree is %q, want %q", lines[0], want)
+	}
+	if len(lines) != 1+3+8+2 {
+		t.Errorf("got %d lines in the tree, want %d:\n%s", len(lines), 1+3+8+2, strings.Join(lines, "\n"))
+	}
+}
+
+func TestCollectTooDeep(t *testing.T) {
+	fsys := fstest.MapFS{
+		strings.Repeat("d/", 20) + "deep.go": {Data: []byte("package deep\n")},
+	}
+	if _, err := CollectFS(context.Background(), fsys, WithMaxDepth(10)); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("got the error %v, want ErrTooDeep", err)
+	}
+	project, err := CollectFS(context.Background(), fsys, WithMaxDepth(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Files) != 1 {
+		t.Errorf("got %d files, want 1", len(project.Files))
+	}
+}
@@ -0,0 +1,159 @@
+package codesum
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around each change in a unified diff
+const diffContext = 3
+
+// lineEdit is one line of an edit script, where op is ' ' for unchanged, '-' for removed and '+' for added
+type lineEdit struct {
+	op   byte
+	text string
+}
+
+// splitLines splits text into lines, without the line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script from a to b, using the Myers diff algorithm
+func diffLines(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back through the trace, from the end of both texts
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, lineEdit{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, lineEdit{'+', b[y-1]})
+			y--
+		} else {
+			edits = append(edits, lineEdit{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, lineEdit{' ', a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// unifiedDiff returns a unified diff from oldText to newText, or an empty string if they are equal
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	edits := diffLines(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		// Find the next change, and then the end of the hunk, where there are enough unchanged lines in a row
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last, unchanged := first, 0
+		for i := first; i < len(edits) && unchanged <= 2*diffContext; i++ {
+			if edits[i].op == ' ' {
+				unchanged++
+			} else {
+				last, unchanged = i, 0
+			}
+		}
+		hunkStart := first - diffContext
+		if hunkStart < start {
+			hunkStart = start
+		}
+		hunkEnd := last + diffContext + 1
+		if hunkEnd > len(edits) {
+			hunkEnd = len(edits)
+		}
+
+		oldStart, newStart := 1, 1
+		for _, e := range edits[:hunkStart] {
+			if e.op != '+' {
+				oldStart++
+			}
+			if e.op != '-' {
+				newStart++
+			}
+		}
+		oldLen, newLen := 0, 0
+		for _, e := range edits[hunkStart:hunkEnd] {
+			if e.op != '+' {
+				oldLen++
+			}
+			if e.op != '-' {
+				newLen++
+			}
+		}
+		// An empty range refers to the line before it
+		if oldLen == 0 {
+			oldStart--
+		}
+		if newLen == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, e := range edits[hunkStart:hunkEnd] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.text)
+			sb.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return sb.String()
+}
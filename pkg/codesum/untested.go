@@ -0,0 +1,44 @@
+package codesum
+
+import (
+	"path"
+	"strings"
+)
+
+// untested returns the Go files that have no test file, see WithUntested.
+// rootName is the name of the root directory, for the package-level test file of the files in the root.
+func untested(files []FileInfo, rootName string, o Options) []FileInfo {
+	tests := make(map[string]bool)
+	for _, file := range files {
+		if strings.HasSuffix(file.Path, "_test.go") {
+			tests[file.Path] = true
+		}
+	}
+	var kept []FileInfo
+	for _, file := range files {
+		switch {
+		case file.Language != "Go":
+			o.Logger.Info("skipping file, since it is not a Go file", "path", file.Path)
+		case tests[file.Path]:
+			o.Logger.Info("skipping file, since it is a test file", "path", file.Path)
+		case hasTestFile(file.Path, rootName, tests):
+			o.Logger.Info("skipping file, since it has a test file", "path", file.Path)
+		default:
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// hasTestFile checks if there is a test file for the given Go file, or for its whole package
+func hasTestFile(filename, rootName string, tests map[string]bool) bool {
+	dir := path.Dir(filename)
+	if tests[strings.TrimSuffix(filename, ".go")+"_test.go"] {
+		return true
+	}
+	dirName := path.Base(dir)
+	if dir == "." {
+		dirName = rootName
+	}
+	return tests[path.Join(dir, dirName+"_test.go")]
+}
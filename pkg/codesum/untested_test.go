@@ -0,0 +1,63 @@
+package codesum
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUntested(t *testing.T) {
+	var files []FileInfo
+	for _, path := range []string{
+		"main.go",          // tested by tool_test.go, the package-level test file of the root
+		"flags.go",         // tested by tool_test.go too
+		"tool_test.go",     // a test file
+		"server/server.go", // tested by server_test.go
+		"server/server_test.go",
+		"server/handler.go", // tested by server_test.go, the package-level test file
+		"util/strings.go",   // tested by strings_test.go
+		"util/strings_test.go",
+		"util/math.go",    // no math_test.go or util_test.go
+		"util/README.md",  // not a Go file
+		"other/server.go", // server_test.go is in another directory
+	} {
+		language := "Go"
+		if path == "util/README.md" {
+			language = "Markdown"
+		}
+		files = append(files, FileInfo{Path: path, Language: language})
+	}
+	o, err := NewOptions(WithUntested(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, file := range untested(files, "tool", o) {
+		got = append(got, file.Path)
+	}
+	if want := []string{"util/math.go", "other/server.go"}; !slices.Equal(got, want) {
+		t.Errorf("got the untested files %q, want %q", got, want)
+	}
+}
+
+func TestCollectUntested(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/lib.go":      {Data: []byte("package lib\n")},
+		"lib/lib_test.go": {Data: []byte("package lib\n")},
+		"lib/extra.go":    {Data: []byte("package lib\n")},
+		"cmd/cmd.go":      {Data: []byte("package cmd\n")},
+		"cmd/cmd.py":      {Data: []byte("print('hi')\n")},
+	}
+	project, err := CollectFS(context.Background(), fsys, WithUntested(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, file := range project.Files {
+		got = append(got, file.Path)
+	}
+	if want := []string{"cmd/cmd.go"}; !slices.Equal(got, want) {
+		t.Errorf("got the files %q, want %q", got, want)
+	}
+}
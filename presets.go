@@ -0,0 +1,83 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+//go:embed presets/*.tmpl
+var presetTemplates embed.FS
+
+// preset is a named bundle of flag values and an output template
+type preset struct {
+	description string
+	options     map[string]string // flag name -> value
+	template    string            // filename in presets/, or empty for the default output
+}
+
+var presets = map[string]preset{
+	"llm": {
+		description: "Compact Markdown with the full source code, for pasting into an LLM frontend",
+		template:    "llm.tmpl",
+	},
+	"review": {
+		description: "Markdown with line counts and modification times per file, using the local time zone",
+		options:     map[string]string{"local-time": "true"},
+		template:    "review.tmpl",
+	},
+	"inventory": {
+		description: "A Markdown table of files, languages, line counts and modification times, without source code",
+		options:     map[string]string{"no-contents": "true"},
+		template:    "inventory.tmpl",
+	},
+}
+
+// presetNames returns the names of all available presets, sorted
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printPresets writes the name and the description of each preset, for -preset help
+func printPresets(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range presetNames() {
+		fmt.Fprintf(tw, "%s\t%s\n", name, presets[name].description)
+	}
+	return tw.Flush()
+}
+
+// applyPreset sets the flags of the given preset, unless they were already set on the command line
+// or in a configuration file. It returns the template text of the preset, if any.
+func applyPreset(name string) (string, error) {
+	p, ok := presets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(presetNames(), ", "))
+	}
+	for flagName, value := range p.options {
+		if _, set := flagSources[flagName]; set {
+			continue
+		}
+		if err := flag.Set(flagName, value); err != nil {
+			return "", fmt.Errorf("preset %s: %w", name, err)
+		}
+		flagSources[flagName] = "preset " + name
+	}
+	if p.template == "" {
+		return "", nil
+	}
+	data, err := presetTemplates.ReadFile("presets/" + p.template)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// scpLikeURL matches git URLs like git@github.com:org/repo.git
+var scpLikeURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:`)
+
+// isGitURL checks if the argument is the URL of a git repository, instead of a local path
+func isGitURL(arg string) bool {
+	for _, scheme := range []string{"https://", "http://", "ssh://", "git://", "file://"} {
+		if strings.HasPrefix(arg, scheme) {
+			return true
+		}
+	}
+	return scpLikeURL.MatchString(arg)
+}
+
+// commitHash matches full SHA-1 and SHA-256 commit hashes, which can be given to -ref
+var commitHash = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// repositoryName returns the last element of a git URL, without .git, like "repo" for https://github.com/org/repo.git
+func repositoryName(url string) string {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	if url == "" || url == "." || url == ".." {
+		return "repository"
+	}
+	return url
+}
+
+// cloneRepository makes a shallow clone of the repository at url, at the given branch, tag or full commit hash if ref
+// is not empty. The clone is made in keepDir if it is given, or else in a temporary directory that is removed by the
+// returned function. The progress is written to stderr, and git handles any authentication.
+func cloneRepository(ctx context.Context, url, ref, keepDir string, quiet bool) (string, func(), error) {
+	dir, cleanup := keepDir, func() {}
+	if dir == "" {
+		parent, err := os.MkdirTemp("", "codesum-clone-")
+		if err != nil {
+			return "", cleanup, err
+		}
+		// The directory is named after the repository, since the project name may come from it
+		dir = filepath.Join(parent, repositoryName(url))
+		cleanup = func() { os.RemoveAll(parent) }
+	}
+
+	progress := "--progress"
+	if quiet {
+		progress = "--quiet"
+	}
+	var err error
+	if commitHash.MatchString(ref) {
+		// git clone --branch only takes branches and tags, so a commit is fetched on its own
+		err = runGit(ctx, "", "init", "--quiet", "--", dir)
+		if err == nil {
+			err = runGit(ctx, dir, "remote", "add", "origin", url)
+		}
+		if err == nil {
+			if err = runGit(ctx, dir, "fetch", "--depth", "1", progress, "origin", ref); err != nil {
+				err = fmt.Errorf("could not fetch commit %s, which the server may not allow: %w", ref, err)
+			}
+		}
+		if err == nil {
+			err = runGit(ctx, dir, "checkout", "--quiet", "--detach", "FETCH_HEAD")
+		}
+	} else {
+		args := []string{"clone", "--depth", "1"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		args = append(args, progress, "--", url, dir)
+		err = runGit(ctx, "", args...)
+	}
+	if err != nil {
+		cleanup()
+		if ctx.Err() != nil {
+			return "", func() {}, errInterrupted
+		}
+		return "", func() {}, fmt.Errorf("could not clone %s: %w", url, err)
+	}
+	return dir, cleanup, nil
+}
+
+// runGit runs git with the given arguments in dir, or in the current directory if dir is empty,
+// with the output written to stderr
+func runGit(ctx context.Context, dir string, args ...string) error {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+	"golang.org/x/sync/singleflight"
+)
+
+// shutdownTimeout is how long requests that are in progress may take when the server is stopped
+const shutdownTimeout = 10 * time.Second
+
+// summaryServer serves summaries of one directory. Summaries are cached for ttl, and concurrent
+// requests for the same summary share one collection.
+type summaryServer struct {
+	root   string
+	ttl    time.Duration
+	logger *slog.Logger
+
+	// ctx is used for collecting, so that a canceled request does not cancel the requests that share its collection
+	ctx   context.Context
+	group singleflight.Group
+
+	mut   sync.Mutex
+	cache map[string]cachedSummary
+}
+
+// cachedSummary is a collected summary and when it was collected
+type cachedSummary struct {
+	project codesum.ProjectInfo
+	time    time.Time
+}
+
+// summaryQuery is the safe subset of the options that can be given as query parameters.
+// There are no parameters for paths, so requests can not read anything outside of the root directory.
+type summaryQuery struct {
+	languages []string
+	statsOnly bool
+	maxTokens int64
+}
+
+// runServe serves summaries of a directory over HTTP until it is interrupted
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("codesum serve", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "Listen on the given address")
+	root := fs.String("root", ".", "Serve summaries of the given directory")
+	ttl := fs.Duration("ttl", 30*time.Second, "Cache each summary for this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *ttl < 0 {
+		return errors.New("-ttl can not be negative")
+	}
+	absRoot, err := filepath.Abs(*root)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(absRoot); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", absRoot)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &summaryServer{
+		root:   absRoot,
+		ttl:    *ttl,
+		logger: newLogger(os.Stderr, slog.LevelWarn),
+		ctx:    ctx,
+		cache:  make(map[string]cachedSummary),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/summary.json", s.handle(codesum.WriteJSON))
+	mux.HandleFunc("/summary.md", s.handle(codesum.WriteMarkdown))
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "Serving summaries of %s on %s\n", absRoot, *addr)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// handle returns a handler that writes the summary with the given renderer
+func (s *summaryServer) handle(write func(io.Writer, codesum.ProjectInfo, codesum.RenderOptions) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query, err := parseSummaryQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if query.statsOnly && strings.HasSuffix(r.URL.Path, ".md") {
+			http.Error(w, "stats-only is only available for /summary.json, since the Markdown output is made of file contents", http.StatusBadRequest)
+			return
+		}
+		project, err := s.summary(query)
+		if err != nil {
+			s.logger.Error(err.Error())
+			http.Error(w, "could not collect the summary", http.StatusInternalServerError)
+			return
+		}
+
+		etag := query.etag(path.Ext(r.URL.Path), project.ContentHash)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := write(&buf, project, codesum.RenderOptions{}); err != nil {
+			s.logger.Error(err.Error())
+			http.Error(w, "could not render the summary", http.StatusInternalServerError)
+			return
+		}
+		if err := checkOutputSize(int64(buf.Len()), 0, query.maxTokens); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".json") {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		buf.WriteTo(w)
+	}
+}
+
+// summary returns the cached summary for the given query, or collects it if it is missing or too old
+func (s *summaryServer) summary(query summaryQuery) (codesum.ProjectInfo, error) {
+	key := query.cacheKey()
+	s.mut.Lock()
+	cached, ok := s.cache[key]
+	s.mut.Unlock()
+	if ok && time.Since(cached.time) < s.ttl {
+		return cached.project, nil
+	}
+
+	v, err, _ := s.group.Do(key, func() (any, error) {
+		project, err := codesum.Collect(s.ctx, s.root,
+			codesum.WithLanguages(query.languages...),
+			codesum.WithoutContents(query.statsOnly),
+			codesum.WithLogger(s.logger))
+		if err != nil {
+			return nil, err
+		}
+		project.Generator = readBuildInfo().String()
+		s.mut.Lock()
+		s.cache[key] = cachedSummary{project: project, time: time.Now()}
+		s.mut.Unlock()
+		return project, nil
+	})
+	if err != nil {
+		return codesum.ProjectInfo{}, err
+	}
+	return v.(codesum.ProjectInfo), nil
+}
+
+// parseSummaryQuery parses and validates the query parameters. Unknown parameters are rejected.
+func parseSummaryQuery(values map[string][]string) (summaryQuery, error) {
+	var (
+		query summaryQuery
+		err   error
+	)
+	for name, list := range values {
+		value := list[len(list)-1]
+		switch name {
+		case "lang":
+			query.languages = splitList(value)
+		case "stats-only":
+			if query.statsOnly, err = strconv.ParseBool(value); err != nil {
+				return summaryQuery{}, fmt.Errorf("invalid stats-only value %q", value)
+			}
+		case "max-tokens":
+			if query.maxTokens, err = strconv.ParseInt(value, 10, 64); err != nil || query.maxTokens < 0 {
+				return summaryQuery{}, fmt.Errorf("invalid max-tokens value %q", value)
+			}
+		default:
+			return summaryQuery{}, fmt.Errorf("unknown query parameter %q, the parameters are lang, stats-only and max-tokens", name)
+		}
+	}
+	if _, err := codesum.NewOptions(codesum.WithLanguages(query.languages...)); err != nil {
+		return summaryQuery{}, err
+	}
+	return query, nil
+}
+
+// etag returns the entity tag of a response in the given format, like ".json". The summary only changes when the
+// files do, as long as the same version of codesum is running, but the content hash is the same with and without the
+// contents, so the format and the query options, including max-tokens, which decides if there is a body, are part of
+// the tag too.
+func (q summaryQuery) etag(format, contentHash string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s max-tokens=%d\x00%s", format, q.cacheKey(), q.maxTokens, contentHash)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// cacheKey returns a key that is the same for queries that collect the same summary
+func (q summaryQuery) cacheKey() string {
+	languages := make([]string, len(q.languages))
+	for i, lang := range q.languages {
+		languages[i] = strings.ToLower(lang)
+	}
+	sort.Strings(languages)
+	return fmt.Sprintf("lang=%s stats-only=%t", strings.Join(languages, ","), q.statsOnly)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+func TestServeETag(t *testing.T) {
+	s := &summaryServer{
+		root:   writeProject(t),
+		ttl:    time.Minute,
+		logger: newLogger(io.Discard, slog.LevelWarn),
+		ctx:    context.Background(),
+		cache:  make(map[string]cachedSummary),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/summary.json", s.handle(codesum.WriteJSON))
+	mux.HandleFunc("/summary.md", s.handle(codesum.WriteMarkdown))
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	etags := make(map[string]string)
+	for _, target := range []string{"/summary.json", "/summary.json?stats-only=true", "/summary.json?lang=go", "/summary.json?max-tokens=100000", "/summary.md"} {
+		w := get(target, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got the status %d, want 200: %s", target, w.Code, w.Body.String())
+		}
+		etag := w.Header().Get("ETag")
+		for other, otherTag := range etags {
+			if etag == otherTag {
+				t.Errorf("%s and %s have the same ETag %s", target, other, etag)
+			}
+		}
+		etags[target] = etag
+
+		if w := get(target, etag); w.Code != http.StatusNotModified {
+			t.Errorf("%s: got the status %d with its own ETag, want 304", target, w.Code)
+		}
+	}
+	if w := get("/summary.json?stats-only=true", etags["/summary.json"]); w.Code != http.StatusOK {
+		t.Errorf("got the status %d for the stats with the ETag of the full summary, want 200", w.Code)
+	}
+}
@@ -0,0 +1,6 @@
+package hello
+
+// Hello returns a greeting
+func Hello() string {
+	return "hello"
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// version can be set when building, with: go build -ldflags "-X main.version=1.2.3"
+var version = ""
+
+const defaultVersion = "1.1.0"
+
+// buildInfo describes the build of the codesum executable
+type buildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitDate string `json:"commit_date,omitempty"`
+	Dirty      bool   `json:"dirty"`
+	GoVersion  string `json:"go_version"`
+}
+
+// readBuildInfo combines the version from -ldflags with the module and VCS information embedded by the Go toolchain
+func readBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		GoVersion: runtime.Version(),
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.CommitDate = setting.Value
+			case "vcs.modified":
+				info.Dirty = setting.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = defaultVersion
+	}
+	return info
+}
+
+// String returns the version, with the short commit hash if known, like "codesum 1.1.0 (0123abc)"
+func (info buildInfo) String() string {
+	s := "codesum " + info.Version
+	if len(info.Commit) >= 7 {
+		s += " (" + info.Commit[:7]
+		if info.Dirty {
+			s += ", dirty"
+		}
+		s += ")"
+	}
+	return s
+}
+
+// writeVersion writes the build information as text or as JSON
+func writeVersion(w io.Writer, info buildInfo, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "codesum %s\n", info.Version)
+	if err != nil {
+		return err
+	}
+	if info.Commit != "" {
+		fmt.Fprintf(w, "commit:      %s\n", info.Commit)
+	}
+	if info.CommitDate != "" {
+		fmt.Fprintf(w, "commit date: %s\n", info.CommitDate)
+	}
+	fmt.Fprintf(w, "dirty:       %t\n", info.Dirty)
+	_, err = fmt.Fprintf(w, "go version:  %s\n", info.GoVersion)
+	return err
+}
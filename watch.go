@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+// watch writes the output, and then writes it again whenever a file changes, until ctx is canceled
+func (c *cliFlags) watch(ctx context.Context, opts []codesum.Option, logger *slog.Logger) error {
+	ignorer, err := codesum.NewIgnorer(os.DirFS("."), opts...)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not watch the directory: %w", err)
+	}
+	defer watcher.Close()
+	if err := addWatches(watcher, ".", ignorer, logger); err != nil {
+		return err
+	}
+
+	// The output files are in the watched tree, so changes to them must not trigger a new summary
+	outputs := c.outputFiles()
+
+	c.regenerate(ctx, opts, logger)
+	var (
+		timer   *time.Timer
+		trigger <-chan time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if isOutputFile(event.Name, outputs) {
+				continue
+			}
+			path := filepath.ToSlash(filepath.Clean(event.Name))
+			// A removed or renamed path can not be checked, so it is matched as a file
+			info, err := os.Stat(event.Name)
+			isDir := err == nil && info.IsDir()
+			if isIgnoredChange(path, isDir, ignorer) {
+				logger.Debug("ignored file changed", "path", path, "op", event.Op.String())
+				continue
+			}
+			logger.Debug("file changed", "path", path, "op", event.Op.String())
+			if isDir && event.Has(fsnotify.Create) {
+				if err := addWatches(watcher, path, ignorer, logger); err != nil {
+					logger.Warn(err.Error())
+				}
+			}
+			// Wait until no files have changed for c.watchInterval
+			if timer == nil {
+				timer = time.NewTimer(c.watchInterval)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(c.watchInterval)
+			}
+			trigger = timer.C
+		case <-trigger:
+			trigger = nil
+			c.regenerate(ctx, opts, logger)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn(fmt.Sprintf("watching: %v", err))
+		}
+	}
+}
+
+// regenerate writes the output and prints a notice, or logs why it could not be written
+func (c *cliFlags) regenerate(ctx context.Context, opts []codesum.Option, logger *slog.Logger) {
+	if err := c.summarize(ctx, opts, logger); err != nil {
+		if ctx.Err() == nil {
+			logger.Error(err.Error())
+		}
+		return
+	}
+	if !c.quiet {
+		fmt.Fprintf(os.Stderr, "%s wrote %s\n", time.Now().Format("15:04:05"), c.outputFile)
+	}
+}
+
+// addWatches watches the given directory and all directories below it that are not ignored
+func addWatches(watcher *fsnotify.Watcher, root string, ignorer *codesum.Ignorer, logger *slog.Logger) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may have been removed again already
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		path = filepath.ToSlash(path)
+		if path != "." && (d.Name() == ".git" || ignorer.Ignored(path)) {
+			return filepath.SkipDir
+		}
+		logger.Debug("watching directory", "path", path)
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("could not watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// isIgnoredChange checks if a change to the given slash separated path, relative to the watched directory, is in
+// a file or directory that would not be collected, so that it does not need a new summary
+func isIgnoredChange(path string, isDir bool, ignorer *codesum.Ignorer) bool {
+	if isDir {
+		return ignorer.Ignored(path)
+	}
+	return ignorer.IgnoredFile(path)
+}
+
+// outputFiles returns the absolute paths of the files that are written by codesum
+func (c *cliFlags) outputFiles() []string {
+	var outputs []string
+	for _, filename := range []string{c.outputFile, c.alsoJSON, c.errorReport, c.metricsOut} {
+		if filename == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(filename); err == nil {
+			outputs = append(outputs, abs)
+		}
+	}
+	return outputs
+}
+
+// isOutputFile checks if path is one of the output files, or one of the temporary files that writeOutput uses for them
+func isOutputFile(path string, outputs []string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir, base := filepath.Split(abs)
+	for _, output := range outputs {
+		outputDir, outputBase := filepath.Split(output)
+		if abs == output || (dir == outputDir && strings.HasPrefix(base, "."+outputBase+".")) {
+			return true
+		}
+	}
+	return false
+}
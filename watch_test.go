@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/xyproto/codesum/pkg/codesum"
+)
+
+func TestIsIgnoredChange(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":     {Data: []byte("*.log\nbuild/\n")},
+		"src/.gitignore": {Data: []byte("*_gen.go\n")},
+	}
+	ignorer, err := codesum.NewIgnorer(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"main.go", false, false},
+		{"app.log", false, true},
+		{"src/app.log", false, true},
+		{"build", true, true},
+		{"build", false, false}, // a removed file, which build/ does not match
+		{"build/out.go", false, true},
+		{"src/types_gen.go", false, true}, // by the ignore file in src
+		{"types_gen.go", false, false},
+		{"node_modules/pkg/index.js", false, true}, // by the common ignores
+		{"node_modules", true, true},
+		{"src", true, false},
+		{".gitignore", false, false}, // a change to the ignore rules must give a new summary
+	}
+	for _, tt := range tests {
+		if got := isIgnoredChange(tt.path, tt.isDir, ignorer); got != tt.ignored {
+			t.Errorf("isIgnoredChange(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+}